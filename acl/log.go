@@ -0,0 +1,75 @@
+package acl
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	// denials of the same kind within the time are grouped in an entry, as in Redis
+	logGroupingTime = 60 * time.Second
+	logGroupingLen  = 10 // latest entries looked up for grouping
+)
+
+// the contexts of denials
+const (
+	ContextTopLevel = "toplevel"
+	ContextMulti    = "multi"
+	ContextLua      = "lua"
+)
+
+// LogEntry is a denial reported by ACL LOG, or a group of denials of the same reason,
+// context, object and user.
+type LogEntry struct {
+	ID         int64
+	Count      int64
+	Reason     Reason
+	Context    string // ContextTopLevel, ContextMulti or ContextLua
+	Object     string // the command, key or channel denied, "AUTH" for authentications
+	Username   string
+	ClientInfo string // of the latest client denied, as CLIENT INFO
+	Created    time.Time
+	Updated    time.Time
+}
+
+// Log keeps the latest denials, the newest first.
+type Log struct {
+	entries []*LogEntry
+	nextID  int64
+}
+
+// Add records the denial, grouping it with a recent entry of the same kind which becomes
+// the newest. The oldest entries are dropped over maxLen.
+func (l *Log) Add(entry LogEntry, maxLen int) {
+	now := time.Now()
+	for i, e := range l.entries[:min(len(l.entries), logGroupingLen)] {
+		if e.Reason == entry.Reason && e.Context == entry.Context && e.Object == entry.Object &&
+			e.Username == entry.Username && now.Sub(e.Updated) < logGroupingTime {
+			e.Count++
+			e.ClientInfo = entry.ClientInfo
+			e.Updated = now
+			l.entries = slices.Insert(slices.Delete(l.entries, i, i+1), 0, e)
+			return
+		}
+	}
+
+	entry.ID = l.nextID
+	entry.Count = 1
+	entry.Created, entry.Updated = now, now
+	l.nextID++
+
+	l.entries = slices.Insert(l.entries, 0, &entry)
+	if len(l.entries) > maxLen {
+		l.entries = l.entries[:maxLen]
+	}
+}
+
+// Entries returns the entries, the newest first.
+func (l *Log) Entries() []*LogEntry {
+	return l.entries
+}
+
+// Reset drops every entry.
+func (l *Log) Reset() {
+	l.entries = nil
+}
@@ -0,0 +1,615 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// DefaultUser is the user of new connections, with every permission and no password unless
+// it is changed.
+const DefaultUser = "default"
+
+// errors of rules, with the wording of Redis
+var (
+	ErrSyntax           = errors.New("Syntax error")
+	ErrUnknownCommand   = errors.New("Unknown command or category name in ACL")
+	ErrBadHash          = errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+	ErrNoSuchPassword   = errors.New("The password you are trying to remove from the user does not exist")
+	ErrKeysAfterAll     = errors.New("Adding a pattern after the * pattern (or the 'allkeys' flag) is not valid and does not have any effect. Try 'resetkeys' to start with an empty list of patterns")
+	ErrChannelsAfterAll = errors.New("Adding a pattern after the * pattern (or the 'allchannels' flag) is not valid and does not have any effect. Try 'resetchannels' to start with an empty list of channels")
+)
+
+// RuleError is a rule which could not be applied to a user.
+type RuleError struct {
+	Rule string
+	Err  error
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("Error in ACL SETUSER modifier '%s': %s", e.Rule, e.Err)
+}
+
+func (e *RuleError) Unwrap() error {
+	return e.Err
+}
+
+// User is a user of ACL, which clients authenticate as with its password.
+type User struct {
+	Name      string
+	enabled   bool
+	nopass    bool     // any password matches
+	sanitize  string   // "sanitize-payload" or "skip-sanitize-payload" when set
+	passwords []string // SHA-256 hashes in hex
+	root      *Selector
+	selectors []*Selector // allowing the commands not allowed by the root selector
+}
+
+// Selector is a set of permissions: the commands allowed, and the keys and channels they
+// can access.
+type Selector struct {
+	allCommands  bool            // +@all, so commands are allowed unless removed later
+	commandRules []string        // the rules applied after +@all or -@all
+	commands     map[string]bool // full names of the allowed commands
+	allKeys      bool
+	keys         []keyPattern
+	allChannels  bool
+	channels     []string
+}
+
+type keyPattern struct {
+	pattern string
+	flags   spec.KeyFlags
+}
+
+// NewUser returns a user without any permission, which is disabled until set "on".
+func NewUser(name string) *User {
+	return &User{
+		Name: name,
+		root: newSelector(),
+	}
+}
+
+// newDefaultUser returns the default user allowed to run any command without a password.
+func newDefaultUser() *User {
+	u := NewUser(DefaultUser)
+	u.enabled = true
+	u.nopass = true
+	u.sanitize = "sanitize-payload"
+	u.root.allKeys = true
+	u.root.allChannels = true
+	u.root.setAllCommands(true)
+
+	return u
+}
+
+func newSelector() *Selector {
+	return &Selector{commands: make(map[string]bool)}
+}
+
+// Enabled reports whether clients can authenticate as the user.
+func (u *User) Enabled() bool {
+	return u.enabled
+}
+
+// NoPass reports whether any password matches.
+func (u *User) NoPass() bool {
+	return u.nopass
+}
+
+// Authenticate reports whether the user is enabled and the password matches.
+func (u *User) Authenticate(password string) bool {
+	if !u.enabled {
+		return false
+	}
+	if u.nopass {
+		return true
+	}
+
+	// the comparison takes the same time wherever the hashes differ
+	hash := hashPassword(password)
+	for _, p := range u.passwords {
+		if subtle.ConstantTimeCompare([]byte(p), []byte(hash)) == 1 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *User) clone() *User {
+	c := *u
+	c.passwords = slices.Clone(u.passwords)
+	c.root = u.root.clone()
+	c.selectors = make([]*Selector, 0, len(u.selectors))
+	for _, s := range u.selectors {
+		c.selectors = append(c.selectors, s.clone())
+	}
+
+	return &c
+}
+
+func (s *Selector) clone() *Selector {
+	c := *s
+	c.commandRules = slices.Clone(s.commandRules)
+	c.commands = make(map[string]bool, len(s.commands))
+	for name, allowed := range s.commands {
+		c.commands[name] = allowed
+	}
+	c.keys = slices.Clone(s.keys)
+	c.channels = slices.Clone(s.channels)
+
+	return &c
+}
+
+// SetRules applies the rules in order. Selectors may be split across several rules, from
+// the one starting with "(" to the one ending with ")".
+func (u *User) SetRules(rules []string) error {
+	merged, err := mergeSelectorRules(rules)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range merged {
+		if err := u.SetRule(rule); err != nil {
+			return &RuleError{Rule: rule, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// mergeSelectorRules joins the rules of selectors split across several rules.
+func mergeSelectorRules(rules []string) ([]string, error) {
+	merged := make([]string, 0, len(rules))
+	for i := 0; i < len(rules); i++ {
+		rule := rules[i]
+		if !strings.HasPrefix(rule, "(") || strings.HasSuffix(rule, ")") {
+			merged = append(merged, rule)
+			continue
+		}
+
+		start := i
+		for i++; i < len(rules) && !strings.HasSuffix(rule, ")"); i++ {
+			rule += " " + rules[i]
+		}
+		i--
+		if !strings.HasSuffix(rule, ")") {
+			return nil, fmt.Errorf("Unmatched parenthesis in acl selector starting at '%s'.", rules[start])
+		}
+		merged = append(merged, rule)
+	}
+
+	return merged, nil
+}
+
+// SetRule applies a rule to the user, or to its root selector for the rules of selectors.
+func (u *User) SetRule(rule string) error {
+	switch lower := strings.ToLower(rule); {
+	case lower == "on":
+		u.enabled = true
+	case lower == "off":
+		u.enabled = false
+	case lower == "nopass":
+		u.nopass = true
+		u.passwords = nil
+	case lower == "resetpass":
+		u.nopass = false
+		u.passwords = nil
+	case lower == "sanitize-payload", lower == "skip-sanitize-payload":
+		u.sanitize = lower
+	case lower == "clearselectors":
+		u.selectors = nil
+	case lower == "reset":
+		for _, r := range []string{"resetpass", "resetkeys", "resetchannels", "off", "clearselectors", "-@all"} {
+			if err := u.SetRule(r); err != nil {
+				return err
+			}
+		}
+
+	case strings.HasPrefix(rule, ">"):
+		u.addPassword(hashPassword(rule[1:]))
+	case strings.HasPrefix(rule, "#"):
+		if !validHash(rule[1:]) {
+			return ErrBadHash
+		}
+		u.addPassword(rule[1:])
+	case strings.HasPrefix(rule, "<"):
+		return u.removePassword(hashPassword(rule[1:]))
+	case strings.HasPrefix(rule, "!"):
+		if !validHash(rule[1:]) {
+			return ErrBadHash
+		}
+		return u.removePassword(rule[1:])
+
+	case strings.HasPrefix(rule, "(") && strings.HasSuffix(rule, ")"):
+		s := newSelector()
+		for _, r := range strings.Fields(rule[1 : len(rule)-1]) {
+			if err := s.SetRule(r); err != nil {
+				return err
+			}
+		}
+		u.selectors = append(u.selectors, s)
+
+	default:
+		return u.root.SetRule(rule)
+	}
+
+	return nil
+}
+
+func (u *User) addPassword(hash string) {
+	u.nopass = false
+	if !slices.Contains(u.passwords, hash) {
+		u.passwords = append(u.passwords, hash)
+	}
+}
+
+func (u *User) removePassword(hash string) error {
+	i := slices.Index(u.passwords, hash)
+	if i < 0 {
+		return ErrNoSuchPassword
+	}
+
+	u.nopass = false
+	u.passwords = slices.Delete(u.passwords, i, i+1)
+	return nil
+}
+
+func hashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func validHash(hash string) bool {
+	return len(hash) == sha256.Size*2 && !strings.ContainsFunc(hash, func(r rune) bool {
+		return (r < '0' || r > '9') && (r < 'a' || r > 'f')
+	})
+}
+
+// SetRule applies a rule of commands, keys or channels to the selector.
+func (s *Selector) SetRule(rule string) error {
+	switch lower := strings.ToLower(rule); {
+	case lower == "allkeys", rule == "~*":
+		s.allKeys = true
+		s.keys = nil
+	case lower == "resetkeys":
+		s.allKeys = false
+		s.keys = nil
+	case lower == "allchannels", rule == "&*":
+		s.allChannels = true
+		s.channels = nil
+	case lower == "resetchannels":
+		s.allChannels = false
+		s.channels = nil
+	case lower == "allcommands", lower == "+@all":
+		s.setAllCommands(true)
+	case lower == "nocommands", lower == "-@all":
+		s.setAllCommands(false)
+
+	case strings.HasPrefix(rule, "~"), strings.HasPrefix(rule, "%"):
+		return s.addKeyPattern(rule)
+	case strings.HasPrefix(rule, "&"):
+		if s.allChannels {
+			return ErrChannelsAfterAll
+		}
+		if !slices.Contains(s.channels, rule[1:]) {
+			s.channels = append(s.channels, rule[1:])
+		}
+
+	case strings.HasPrefix(rule, "+@"), strings.HasPrefix(rule, "-@"):
+		category := lower[2:]
+		if !slices.Contains(spec.Categories, category) {
+			return ErrUnknownCommand
+		}
+		for _, c := range spec.CommandSpecs() {
+			if c.HasCategory(category) {
+				s.commands[c.Name] = rule[0] == '+'
+			}
+		}
+		s.updateCommandRules(lower)
+
+	case strings.HasPrefix(rule, "+"), strings.HasPrefix(rule, "-"):
+		commands := commandsNamed(lower[1:])
+		if len(commands) == 0 {
+			return ErrUnknownCommand
+		}
+		for _, c := range commands {
+			s.commands[c.Name] = rule[0] == '+'
+		}
+		s.updateCommandRules(lower)
+
+	default:
+		return ErrSyntax
+	}
+
+	return nil
+}
+
+// addKeyPattern adds a pattern of keys, such as "~cache:*", or "%R~cache:*" for the keys
+// only read and "%W~cache:*" for the keys only written.
+func (s *Selector) addKeyPattern(rule string) error {
+	flags := spec.KeyRead | spec.KeyWrite
+	pattern := rule[1:]
+	if rule[0] == '%' {
+		permissions, p, found := strings.Cut(rule[1:], "~")
+		if !found || permissions == "" {
+			return ErrSyntax
+		}
+
+		flags = 0
+		for _, c := range strings.ToUpper(permissions) {
+			switch c {
+			case 'R':
+				flags |= spec.KeyRead
+			case 'W':
+				flags |= spec.KeyWrite
+			default:
+				return ErrSyntax
+			}
+		}
+		pattern = p
+	}
+
+	if s.allKeys {
+		return ErrKeysAfterAll
+	}
+
+	kp := keyPattern{pattern: pattern, flags: flags}
+	if !slices.Contains(s.keys, kp) {
+		s.keys = append(s.keys, kp)
+	}
+	return nil
+}
+
+// setAllCommands allows or forbids every command, dropping the previous rules of commands.
+func (s *Selector) setAllCommands(allowed bool) {
+	s.allCommands = allowed
+	s.commandRules = nil
+	for _, c := range spec.CommandSpecs() {
+		s.commands[c.Name] = allowed
+	}
+}
+
+// updateCommandRules appends the rule of commands, dropping a previous rule of the same
+// command or category, which has no effect anymore.
+func (s *Selector) updateCommandRules(rule string) {
+	s.commandRules = slices.DeleteFunc(s.commandRules, func(r string) bool { return r[1:] == rule[1:] })
+	s.commandRules = append(s.commandRules, rule)
+}
+
+// commandsNamed returns the command of the name, or the subcommands of a container command.
+func commandsNamed(name string) []*spec.CommandSpec {
+	if c, found := spec.LookupCommand(name); found {
+		return []*spec.CommandSpec{c}
+	}
+
+	var subcommands []*spec.CommandSpec
+	for _, c := range spec.CommandSpecs() {
+		if strings.HasPrefix(c.Name, name+"|") {
+			subcommands = append(subcommands, c)
+		}
+	}
+
+	return subcommands
+}
+
+// Reason is the reason of a denial, ordered by relevance when selectors deny a command for
+// different reasons.
+type Reason int
+
+const (
+	ReasonCommand Reason = iota + 1
+	ReasonKey
+	ReasonAuth
+	ReasonChannel
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonCommand:
+		return "command"
+	case ReasonKey:
+		return "key"
+	case ReasonAuth:
+		return "auth"
+	default:
+		return "channel"
+	}
+}
+
+// Denial is the reason a user is not allowed to run a command, with the command, key or
+// channel denied.
+type Denial struct {
+	Reason Reason
+	Object string
+}
+
+// Message formats the denial for the user, as replied by Redis.
+func (d *Denial) Message(user string) string {
+	switch d.Reason {
+	case ReasonCommand:
+		return fmt.Sprintf("User %s has no permissions to run the '%s' command", user, d.Object)
+	case ReasonKey:
+		return fmt.Sprintf("User %s has no permissions to access the '%s' key", user, d.Object)
+	case ReasonChannel:
+		return fmt.Sprintf("User %s has no permissions to access the '%s' channel", user, d.Object)
+	default:
+		return fmt.Sprintf("User %s failed to authenticate", user)
+	}
+}
+
+// Check returns why the user is not allowed to run the command with the arguments, or nil
+// when a selector allows it.
+func (u *User) Check(cmd *spec.CommandSpec, args []string) *Denial {
+	denial := u.root.check(cmd, args)
+	if denial == nil {
+		return nil
+	}
+
+	for _, s := range u.selectors {
+		d := s.check(cmd, args)
+		if d == nil {
+			return nil
+		}
+		if d.Reason > denial.Reason {
+			denial = d
+		}
+	}
+
+	return denial
+}
+
+func (s *Selector) check(cmd *spec.CommandSpec, args []string) *Denial {
+	if !s.commands[cmd.Name] {
+		return &Denial{Reason: ReasonCommand, Object: cmd.Name}
+	}
+
+	if !s.allKeys {
+		for _, key := range cmd.KeysOf(args) {
+			if !slices.ContainsFunc(s.keys, func(kp keyPattern) bool {
+				return kp.flags&key.Flags == key.Flags && pkg.MatchGlob(kp.pattern, key.Name)
+			}) {
+				return &Denial{Reason: ReasonKey, Object: key.Name}
+			}
+		}
+	}
+
+	if !s.allChannels {
+		for _, channel := range cmd.ChannelsOf(args) {
+			// patterns are only allowed when they are allowed as they are
+			if !slices.ContainsFunc(s.channels, func(p string) bool {
+				return p == channel || (!cmd.Channels.Pattern && pkg.MatchGlob(p, channel))
+			}) {
+				return &Denial{Reason: ReasonChannel, Object: channel}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Describe formats the user as its rules, as listed by ACL LIST without the "user <name>"
+// prefix.
+func (u *User) Describe() string {
+	var parts []string
+	if u.enabled {
+		parts = append(parts, "on")
+	} else {
+		parts = append(parts, "off")
+	}
+	if u.nopass {
+		parts = append(parts, "nopass")
+	}
+	if u.sanitize != "" {
+		parts = append(parts, u.sanitize)
+	}
+	for _, p := range u.passwords {
+		parts = append(parts, "#"+p)
+	}
+	parts = append(parts, u.root.Describe())
+	for _, s := range u.selectors {
+		parts = append(parts, "("+s.Describe()+")")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// Flags returns the flags of the user reported by ACL GETUSER.
+func (u *User) Flags() []string {
+	flags := []string{"off"}
+	if u.enabled {
+		flags[0] = "on"
+	}
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+	if u.sanitize != "" {
+		flags = append(flags, u.sanitize)
+	}
+
+	return flags
+}
+
+// Passwords returns the hashes of the passwords of the user.
+func (u *User) Passwords() []string {
+	return u.passwords
+}
+
+// Root returns the root selector of the user.
+func (u *User) Root() *Selector {
+	return u.root
+}
+
+// Selectors returns the selectors of the user other than the root one.
+func (u *User) Selectors() []*Selector {
+	return u.selectors
+}
+
+// Describe formats the selector as its rules of keys, channels and commands.
+func (s *Selector) Describe() string {
+	var parts []string
+	if keys := s.DescribeKeys(); keys != "" {
+		parts = append(parts, keys)
+	}
+	if s.allChannels {
+		parts = append(parts, "&*")
+	} else {
+		parts = append(parts, strings.TrimSpace("resetchannels "+s.DescribeChannels()))
+	}
+	parts = append(parts, s.DescribeCommands())
+
+	return strings.Join(parts, " ")
+}
+
+// DescribeCommands formats the rules of commands, starting with +@all or -@all.
+func (s *Selector) DescribeCommands() string {
+	rules := []string{"-@all"}
+	if s.allCommands {
+		rules[0] = "+@all"
+	}
+
+	return strings.Join(append(rules, s.commandRules...), " ")
+}
+
+// DescribeKeys formats the patterns of keys, e.g. "~cache:* %R~config:*".
+func (s *Selector) DescribeKeys() string {
+	if s.allKeys {
+		return "~*"
+	}
+
+	patterns := make([]string, 0, len(s.keys))
+	for _, kp := range s.keys {
+		switch kp.flags {
+		case spec.KeyRead:
+			patterns = append(patterns, "%R~"+kp.pattern)
+		case spec.KeyWrite:
+			patterns = append(patterns, "%W~"+kp.pattern)
+		default:
+			patterns = append(patterns, "~"+kp.pattern)
+		}
+	}
+
+	return strings.Join(patterns, " ")
+}
+
+// DescribeChannels formats the patterns of channels, e.g. "&news:*".
+func (s *Selector) DescribeChannels() string {
+	if s.allChannels {
+		return "&*"
+	}
+
+	patterns := make([]string, 0, len(s.channels))
+	for _, p := range s.channels {
+		patterns = append(patterns, "&"+p)
+	}
+
+	return strings.Join(patterns, " ")
+}
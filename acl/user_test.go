@@ -0,0 +1,152 @@
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+func TestUserCheck(t *testing.T) {
+	tests := []struct {
+		rules []string
+		args  []string
+		want  *Denial
+	}{
+		// the later rule wins over a category
+		{rules: []string{"+@read", "-get", "~*"}, args: []string{"get", "k"}, want: &Denial{Reason: ReasonCommand, Object: "get"}},
+		{rules: []string{"-get", "+@read", "~*"}, args: []string{"get", "k"}, want: nil},
+		{rules: []string{"+@all", "-@write", "~*"}, args: []string{"set", "k", "v"}, want: &Denial{Reason: ReasonCommand, Object: "set"}},
+		{rules: []string{"-@all", "+set", "~*"}, args: []string{"set", "k", "v"}, want: nil},
+		{rules: []string{"+client", "-client|kill"}, args: []string{"client", "kill"}, want: &Denial{Reason: ReasonCommand, Object: "client|kill"}},
+		{rules: []string{"+client", "-client|kill"}, args: []string{"client", "list"}, want: nil},
+
+		// keys patterns limited to reads or writes
+		{rules: []string{"+get", "+set", "%R~r:*"}, args: []string{"get", "r:1"}, want: nil},
+		{rules: []string{"+get", "+set", "%R~r:*"}, args: []string{"set", "r:1", "v"}, want: &Denial{Reason: ReasonKey, Object: "r:1"}},
+		{rules: []string{"+get", "+set", "%W~w:*"}, args: []string{"set", "w:1", "v"}, want: nil},
+		{rules: []string{"+get", "+set", "%W~w:*"}, args: []string{"get", "w:1"}, want: &Denial{Reason: ReasonKey, Object: "w:1"}},
+		{rules: []string{"+get", "+set", "%RW~k"}, args: []string{"set", "k", "v"}, want: nil},
+		{rules: []string{"+get", "~a:*"}, args: []string{"get", "b:1"}, want: &Denial{Reason: ReasonKey, Object: "b:1"}},
+
+		// channels
+		{rules: []string{"+publish", "&news"}, args: []string{"publish", "news", "m"}, want: nil},
+		{rules: []string{"+publish", "&news"}, args: []string{"publish", "sports", "m"}, want: &Denial{Reason: ReasonChannel, Object: "sports"}},
+		{rules: []string{"+psubscribe", "&news:*"}, args: []string{"psubscribe", "news:*"}, want: nil},
+		{rules: []string{"+psubscribe", "&news:*"}, args: []string{"psubscribe", "news:a"}, want: &Denial{Reason: ReasonChannel, Object: "news:a"}},
+
+		// selectors allow what the root selector does not, and report the most relevant denial
+		{rules: []string{"+get", "~a", "(+set ~b)"}, args: []string{"set", "b", "v"}, want: nil},
+		{rules: []string{"+get", "~a", "(+set", "~b)"}, args: []string{"set", "b", "v"}, want: nil},
+		{rules: []string{"+get", "~a", "(+set ~b)"}, args: []string{"get", "a"}, want: nil},
+		{rules: []string{"+get", "~a", "(+set ~b)"}, args: []string{"set", "a", "v"}, want: &Denial{Reason: ReasonKey, Object: "a"}},
+		{rules: []string{"+get", "~a", "(+set ~b)"}, args: []string{"get", "b"}, want: &Denial{Reason: ReasonKey, Object: "b"}},
+		{rules: []string{"+publish", "&a", "(+publish ~k)"}, args: []string{"publish", "c", "m"}, want: &Denial{Reason: ReasonChannel, Object: "c"}},
+		{rules: []string{"+get", "~a", "(+set ~b)", "clearselectors"}, args: []string{"set", "b", "v"}, want: &Denial{Reason: ReasonCommand, Object: "set"}},
+	}
+
+	for _, tt := range tests {
+		u := NewUser("u")
+		if err := u.SetRules(tt.rules); err != nil {
+			t.Errorf("SetRules(%q) failed: %v", tt.rules, err)
+			continue
+		}
+
+		cmd, found := spec.LookupCommand(commandNameOf(tt.args))
+		if !found {
+			t.Fatalf("LookupCommand(%q) not found", commandNameOf(tt.args))
+		}
+
+		got := u.Check(cmd, tt.args)
+		switch {
+		case got == nil && tt.want == nil:
+		case got == nil || tt.want == nil || *got != *tt.want:
+			t.Errorf("Check(%q) with rules %q = %v, want %v", tt.args, tt.rules, got, tt.want)
+		}
+	}
+}
+
+// commandNameOf returns the full name of the command, joining the subcommand of a container
+// command, e.g. "client|kill".
+func commandNameOf(args []string) string {
+	if _, found := spec.LookupCommand(args[0]); !found && len(args) > 1 {
+		return args[0] + "|" + args[1]
+	}
+
+	return args[0]
+}
+
+func TestUserSetRulesErrors(t *testing.T) {
+	hash := strings.Repeat("a", 64)
+	tests := []struct {
+		rules []string
+		want  error
+	}{
+		{rules: []string{"#" + hash}, want: nil},
+		{rules: []string{"#" + hash[1:]}, want: ErrBadHash},
+		{rules: []string{"#" + strings.ToUpper(hash)}, want: ErrBadHash},
+		{rules: []string{"#" + hash[1:] + "g"}, want: ErrBadHash},
+		{rules: []string{"!" + hash}, want: ErrNoSuchPassword},
+		{rules: []string{"<secret"}, want: ErrNoSuchPassword},
+		{rules: []string{"%R~k", "%W~k", "%RW~k"}, want: nil},
+		{rules: []string{"%X~k"}, want: ErrSyntax},
+		{rules: []string{"%~k"}, want: ErrSyntax},
+		{rules: []string{"%R"}, want: ErrSyntax},
+		{rules: []string{"~*", "~k"}, want: ErrKeysAfterAll},
+		{rules: []string{"allkeys", "%R~k"}, want: ErrKeysAfterAll},
+		{rules: []string{"&*", "&news"}, want: ErrChannelsAfterAll},
+		{rules: []string{"+@unknown"}, want: ErrUnknownCommand},
+		{rules: []string{"+unknown"}, want: ErrUnknownCommand},
+		{rules: []string{"(+get ~k %X~k)"}, want: ErrSyntax},
+		{rules: []string{"unknown"}, want: ErrSyntax},
+	}
+
+	for _, tt := range tests {
+		err := NewUser("u").SetRules(tt.rules)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("SetRules(%q) = %v, want %v", tt.rules, err, tt.want)
+		}
+	}
+
+	if err := NewUser("u").SetRules([]string{"(+get", "~k"}); err == nil {
+		t.Errorf("SetRules with an unmatched parenthesis succeeded")
+	}
+}
+
+func TestUserAuthenticate(t *testing.T) {
+	sum := sha256.Sum256([]byte("secret"))
+	hash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		rules    []string
+		password string
+		want     bool
+	}{
+		{rules: []string{"on", ">secret"}, password: "secret", want: true},
+		{rules: []string{"on", ">secret"}, password: "wrong", want: false},
+		{rules: []string{"on", "#" + hash}, password: "secret", want: true},
+		{rules: []string{"on", "#" + hash}, password: "wrong", want: false},
+		{rules: []string{"on", "#" + hash, "!" + hash}, password: "secret", want: false},
+		{rules: []string{"on", ">secret", "<secret"}, password: "secret", want: false},
+		{rules: []string{"on", ">other", ">secret"}, password: "secret", want: true},
+		{rules: []string{"off", ">secret"}, password: "secret", want: false},
+		{rules: []string{"on", "nopass"}, password: "anything", want: true},
+		{rules: []string{"on", "nopass", ">secret"}, password: "anything", want: false},
+		{rules: []string{"on", ">secret", "resetpass"}, password: "secret", want: false},
+	}
+
+	for _, tt := range tests {
+		u := NewUser("u")
+		if err := u.SetRules(tt.rules); err != nil {
+			t.Errorf("SetRules(%q) failed: %v", tt.rules, err)
+			continue
+		}
+
+		if got := u.Authenticate(tt.password); got != tt.want {
+			t.Errorf("Authenticate(%q) with rules %q = %v, want %v", tt.password, tt.rules, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package acl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+)
+
+// Users are the users of ACL by name, always including the default user.
+// It is only accessed from the event loop, so no locking is needed.
+type Users struct {
+	users map[string]*User
+}
+
+func NewUsers() *Users {
+	return &Users{
+		users: map[string]*User{DefaultUser: newDefaultUser()},
+	}
+}
+
+func (u *Users) Get(name string) (*User, bool) {
+	user, found := u.users[name]
+	return user, found
+}
+
+// Names returns the names of the users in order.
+func (u *Users) Names() []string {
+	return slices.Sorted(maps.Keys(u.users))
+}
+
+// SetUser applies the rules to the user, which is created when it does not exist. The user
+// is left as it was when a rule fails.
+func (u *Users) SetUser(name string, rules []string) error {
+	if !validUsername(name) {
+		return errors.New("Usernames can't contain spaces or null characters")
+	}
+
+	user := NewUser(name)
+	if existing, found := u.users[name]; found {
+		user = existing.clone()
+	}
+
+	if err := user.SetRules(rules); err != nil {
+		return err
+	}
+
+	u.users[name] = user
+	return nil
+}
+
+// Delete removes the user, reporting whether it existed. The default user is never removed.
+func (u *Users) Delete(name string) bool {
+	_, found := u.users[name]
+	if !found || name == DefaultUser {
+		return false
+	}
+
+	delete(u.users, name)
+	return true
+}
+
+// Authenticate reports whether the user exists, is enabled, and the password matches.
+func (u *Users) Authenticate(name, password string) bool {
+	user, found := u.users[name]
+	return found && user.Authenticate(password)
+}
+
+func validUsername(name string) bool {
+	return !strings.ContainsAny(name, " \t\r\n\x00")
+}
+
+// Load replaces the users with the ones of the ACL file, with a "user <name> <rules...>"
+// line for each user. The default user keeps its permissions when it is not in the file.
+// The users are left as they were when the file is invalid.
+func (u *Users) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Error loading ACLs, opening file '%s': %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	users := map[string]*User{DefaultUser: newDefaultUser()}
+	defined := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		args, err := pkg.SplitArgs(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: unbalanced quotes in acl line", path, n)
+		}
+		if len(args) < 2 || args[0] != "user" {
+			return fmt.Errorf("%s:%d: line should start with user keyword", path, n)
+		}
+
+		name := args[1]
+		if !validUsername(name) {
+			return fmt.Errorf("%s:%d: Usernames can't contain spaces or null characters", path, n)
+		}
+		if defined[name] {
+			return fmt.Errorf("%s:%d: Duplicate user '%s' found", path, n, name)
+		}
+		defined[name] = true
+
+		user := NewUser(name)
+		if err := user.SetRules(args[2:]); err != nil {
+			var ruleErr *RuleError
+			if errors.As(err, &ruleErr) {
+				return fmt.Errorf("%s:%d: %w. ", path, n, ruleErr.Err)
+			}
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		users[name] = user
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Error loading ACLs, reading file '%s': %w", path, err)
+	}
+
+	u.users = users
+	return nil
+}
+
+// Save writes every user to the ACL file, replacing it at once so it is never left half
+// written.
+func (u *Users) Save(path string) error {
+	var sb strings.Builder
+	for _, name := range u.Names() {
+		fmt.Fprintf(&sb, "user %s %s\n", name, u.users[name].Describe())
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "temp-acl-*.acl")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.WriteString(sb.String()); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -4,8 +4,10 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
-	"time"
+	"strconv"
 
+	"github.com/codecrafters-io/redis-starter-go/acl"
+	"github.com/codecrafters-io/redis-starter-go/config"
 	"github.com/codecrafters-io/redis-starter-go/event"
 	"github.com/codecrafters-io/redis-starter-go/id"
 	"github.com/codecrafters-io/redis-starter-go/processor"
@@ -20,26 +22,126 @@ func main() {
 	// initialize logger
 	slog.Info("Starting Redis server...")
 
+	// load configuration
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	if err := changeDir(cfg); err != nil {
+		slog.Error("failed to change directory", "dir", cfg.Dir, "error", err)
+		os.Exit(1)
+	}
+	if cfg.ReplicaOf != nil {
+		slog.Warn("replication is not supported, replicaof is ignored",
+			"host", cfg.ReplicaOf.Host,
+			"port", cfg.ReplicaOf.Port,
+		)
+	}
+
+	// initialize users, which the ACL file replaces
+	users := acl.NewUsers()
+	if cfg.ACLFile != "" {
+		if err := users.Load(cfg.ACLFile); err != nil {
+			slog.Error("failed to load ACL file", "file", cfg.ACLFile, "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// initialize ID issuer
 	idIssuer := &id.NumIDIssuer{}
 
 	// initialize storage
 	storage := storage.NewInMemoryStorage()
+	storage.SetEviction(cfg.Eviction())
 
 	// initialize handlers
-	tcpProcessor, err := processor.NewTCPProcessor("0.0.0.0:6379", idIssuer)
+	clients := processor.NewClients()
+	tcpProcessor, err := processor.NewTCPProcessor(cfg, idIssuer, clients)
 	if err != nil {
 		slog.Error("failed to initialicze tcp processor", "error", err)
 		os.Exit(1)
 	}
 	defer func() { _ = tcpProcessor.Close() }()
 
-	expirer := processor.NewExpirer(1000*time.Millisecond, idIssuer, storage)
+	latency := processor.NewLatencyMonitor(cfg)
+	expirer := processor.NewExpirer(cfg.Interval(), idIssuer, storage, latency)
+	cron := processor.NewCron(cfg.Interval(), idIssuer)
+
+	pubsub := processor.NewPubSub()
+	tcpProcessor.AddCloseListener(pubsub.RemoveClient)
+
+	notifier := processor.NewKeyspaceNotifier(cfg, pubsub)
+	storage.AddKeyEventListener(notifier.Notify)
 
 	lexer := processor.NewLexer()
+	tcpProcessor.AddCloseListener(lexer.RemoveClient)
 	parser := processor.NewParser()
-	executor := processor.NewExecutor(storage)
-	formatter := processor.NewFormatter()
+	executor := processor.NewExecutor(cfg, storage, pubsub, notifier, clients, latency, users)
+	formatter := processor.NewFormatter(cfg, clients, pubsub)
+	tcpProcessor.AddCloseListener(executor.RemoveClient)
+	cron.AddJob(executor.PauseCron)
+	cron.AddJob(executor.TimeoutCron)
+	cron.AddJob(executor.StatsCron)
+	cron.AddJob(tcpProcessor.StatsCron)
+	executor.AddConfigListener("proto-max-bulk-len", func(cfg *config.Config) error {
+		tcpProcessor.SetMaxBulkLen(cfg.ProtoMaxBulkLen)
+		return nil
+	})
+	executor.AddConfigListener("maxclients", func(cfg *config.Config) error {
+		tcpProcessor.SetMaxClients(int64(cfg.MaxClients))
+		return nil
+	})
+	executor.AddConfigListener("tcp-keepalive", func(cfg *config.Config) error {
+		tcpProcessor.SetKeepAlive(cfg.TCPKeepAlive)
+		return nil
+	})
+	executor.AddConfigListener("client-output-buffer-limit", func(cfg *config.Config) error {
+		formatter.SetOutputBufferLimits(cfg.ClientOutputBufferLimits)
+		return nil
+	})
+	executor.AddConfigListener("hz", func(cfg *config.Config) error {
+		expirer.SetInterval(cfg.Interval())
+		cron.SetInterval(cfg.Interval())
+		return nil
+	})
+	executor.AddConfigListener("dir", changeDir)
+	for _, name := range []string{"maxmemory", "maxmemory-policy", "maxmemory-samples", "lfu-log-factor", "lfu-decay-time"} {
+		executor.AddConfigListener(name, func(cfg *config.Config) error {
+			storage.SetEviction(cfg.Eviction())
+			return nil
+		})
+	}
+	executor.AddInfoField("stats", "total_connections_received", func() string {
+		return strconv.FormatInt(tcpProcessor.Stats().ConnectionsReceived, 10)
+	})
+	executor.AddInfoField("stats", "rejected_connections", func() string {
+		return strconv.FormatInt(tcpProcessor.Stats().ConnectionsRejected, 10)
+	})
+	executor.AddInfoField("stats", "total_net_input_bytes", func() string {
+		return strconv.FormatInt(tcpProcessor.Stats().NetInputBytes, 10)
+	})
+	executor.AddInfoField("stats", "total_net_output_bytes", func() string {
+		return strconv.FormatInt(tcpProcessor.Stats().NetOutputBytes, 10)
+	})
+	executor.AddInfoField("stats", "instantaneous_input_kbps", func() string {
+		return strconv.FormatFloat(tcpProcessor.Stats().InputKbps, 'f', 2, 64)
+	})
+	executor.AddInfoField("stats", "instantaneous_output_kbps", func() string {
+		return strconv.FormatFloat(tcpProcessor.Stats().OutputKbps, 'f', 2, 64)
+	})
+	executor.AddInfoField("stats", "total_reads_processed", func() string {
+		return strconv.FormatInt(tcpProcessor.Stats().ReadsProcessed, 10)
+	})
+	executor.AddInfoField("stats", "total_writes_processed", func() string {
+		return strconv.FormatInt(tcpProcessor.Stats().WritesProcessed, 10)
+	})
+	executor.AddInfoField("stats", "client_output_buffer_limit_disconnections", func() string {
+		return strconv.FormatInt(formatter.OutputLimitDisconnections(), 10)
+	})
+	executor.AddStatsResetter(tcpProcessor.ResetStats)
+	executor.AddStatsResetter(formatter.ResetStats)
 
 	loop := event.NewLoop(
 		[]event.Handler{
@@ -47,6 +149,7 @@ func main() {
 			tcpProcessor.WriteHandler(),
 			tcpProcessor.CloseHandler(),
 			expirer.ExpireEventHandler(),
+			cron.CronEventHandler(),
 			lexer.LexingHandler(),
 			parser.ParseHandler(),
 			executor.ExecuteHandler(),
@@ -56,10 +159,48 @@ func main() {
 		[]event.Pusher{
 			tcpProcessor,
 			expirer,
+			cron,
+			pubsub,
+			executor,
+		},
+		[]event.Flusher{
+			tcpProcessor,
 		},
 	)
 
+	if err := executor.LoadFunctions(); err != nil {
+		slog.Warn("failed to load functions", "file", processor.FunctionsFilename, "error", err)
+	}
+
+	executor.SetYield(loop.Yield)
+	executor.AddInfoField("stats", "eventloop_cycles", func() string {
+		return strconv.FormatInt(loop.Cycles(), 10)
+	})
+	executor.AddInfoField("stats", "eventloop_duration_sum", func() string {
+		return strconv.FormatInt(loop.Duration().Microseconds(), 10)
+	})
+
 	loop.Start()
 	<-shutdownCh
 	loop.Shutdown()
+
+	// the loop is stopped, so the executor is not used concurrently anymore
+	if err := executor.SaveFunctionsIfDirty(); err != nil {
+		slog.Error("failed to save functions", "file", processor.FunctionsFilename, "error", err)
+	}
+}
+
+// changeDir changes the working directory to dir, which is kept absolute for CONFIG GET.
+func changeDir(cfg *config.Config) error {
+	if err := os.Chdir(cfg.Dir); err != nil {
+		return err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	cfg.Dir = wd
+	return nil
 }
@@ -0,0 +1,254 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+	"github.com/codecrafters-io/redis-starter-go/storage"
+)
+
+// Config is the configuration of the server, loaded from a redis.conf style file and the
+// command line. Components receive it when they are created, and parameters changed at
+// runtime are applied to the components owning them.
+type Config struct {
+	File string // absolute path of the configuration file, empty when started without it
+
+	Bind       []string // "*" for every IPv4 address, "::*" for every IPv6 address
+	Port       int
+	TCPBacklog int
+
+	Dir        string
+	DBFilename string
+	ReplicaOf  *ReplicaOf // nil for a master
+
+	Hz int // frequency of the background tasks
+
+	Timeout                  time.Duration // idle time before clients are closed, 0 to keep them
+	TCPKeepAlive             time.Duration // 0 to disable keepalive probes
+	MaxClients               int
+	ProtoMaxBulkLen          int64
+	ClientOutputBufferLimits OutputBufferLimits
+
+	MaxMemory        int64 // 0 for no limit
+	MaxMemoryPolicy  storage.EvictionPolicy
+	MaxMemorySamples int
+	LFULogFactor     int
+	LFUDecayTime     int // minutes
+
+	LuaTimeLimit         time.Duration
+	NotifyKeyspaceEvents storage.NotifyFlag
+
+	SlowlogLogSlowerThan int64 // microseconds, negative to disable the slow log
+	SlowlogMaxLen        int
+
+	LatencyMonitorThreshold int64 // milliseconds, 0 to disable the latency monitor
+
+	RequirePass  string // password of the default user, empty for no password
+	ACLFile      string // users loaded at startup and by ACL LOAD, empty for no file
+	ACLLogMaxLen int
+}
+
+// ReplicaOf is the master the server replicates from.
+type ReplicaOf struct {
+	Host string
+	Port int
+}
+
+const (
+	// MinHz and MaxHz bound hz, which is clamped to them like Redis does.
+	MinHz = 1
+	MaxHz = 500
+
+	// MinProtoMaxBulkLen is the lowest value accepted for proto-max-bulk-len.
+	MinProtoMaxBulkLen = 1024 * 1024
+)
+
+// Default returns the configuration used when nothing is configured.
+func Default() *Config {
+	return &Config{
+		Bind:       []string{"*"},
+		Port:       6379,
+		TCPBacklog: 511,
+
+		Dir:        ".",
+		DBFilename: "dump.rdb",
+
+		Hz: 10,
+
+		TCPKeepAlive:             300 * time.Second,
+		MaxClients:               10000,
+		ProtoMaxBulkLen:          512 * 1024 * 1024,
+		ClientOutputBufferLimits: DefaultOutputBufferLimits,
+
+		MaxMemoryPolicy:  storage.NoEviction,
+		MaxMemorySamples: 5,
+		LFULogFactor:     10,
+		LFUDecayTime:     1,
+
+		LuaTimeLimit: 5 * time.Second,
+
+		SlowlogLogSlowerThan: 10000,
+		SlowlogMaxLen:        128,
+
+		ACLLogMaxLen: 128,
+	}
+}
+
+// Clone returns a copy of the config, which is changed without affecting the original.
+func (c *Config) Clone() *Config {
+	clone := *c
+	clone.Bind = slices.Clone(c.Bind)
+	if c.ReplicaOf != nil {
+		replicaOf := *c.ReplicaOf
+		clone.ReplicaOf = &replicaOf
+	}
+
+	return &clone
+}
+
+// Eviction returns the configuration of maxmemory for the storage.
+func (c *Config) Eviction() storage.Eviction {
+	return storage.Eviction{
+		MaxMemory:    c.MaxMemory,
+		Policy:       c.MaxMemoryPolicy,
+		Samples:      c.MaxMemorySamples,
+		LFULogFactor: c.LFULogFactor,
+		LFUDecayTime: c.LFUDecayTime,
+	}
+}
+
+// Interval returns the interval of the background tasks run hz times per second.
+func (c *Config) Interval() time.Duration {
+	return time.Second / time.Duration(c.Hz)
+}
+
+// Addresses returns the addresses to listen on, where addresses prefixed with "-" are
+// optional, so failing to listen on them is not fatal.
+func (c *Config) Addresses() []string {
+	port := strconv.Itoa(c.Port)
+
+	addresses := make([]string, 0, len(c.Bind))
+	for _, bind := range c.Bind {
+		host, optional := strings.CutPrefix(bind, "-")
+		switch host {
+		case "*":
+			host = "0.0.0.0"
+		case "::*":
+			host = "::"
+		}
+
+		address := net.JoinHostPort(host, port)
+		if optional {
+			address = "-" + address
+		}
+		addresses = append(addresses, address)
+	}
+
+	return addresses
+}
+
+// Load loads the configuration from the command line arguments, which are an optional
+// path to a configuration file followed by options such as "--port 6380". Options are
+// applied after the file, the same as if they were appended to it.
+func Load(args []string) (*Config, error) {
+	c := Default()
+
+	if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+		if err := c.loadFile(args[0]); err != nil {
+			return nil, err
+		}
+		args = args[1:]
+	}
+
+	options, err := parseOptions(args)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range options {
+		if err := c.Apply(d[0], d[1:]); err != nil {
+			return nil, fmt.Errorf("invalid option --%s: %w", d[0], err)
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	// kept absolute, since the working directory is changed to dir
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config file %s: %w", path, err)
+	}
+
+	f, err := os.Open(abs)
+	if err != nil {
+		return fmt.Errorf("failed to open config file %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	c.File = abs
+	return c.Read(path, f)
+}
+
+// Read applies the directives of a redis.conf style configuration, where each line is a
+// directive name followed by its arguments, and lines starting with # are comments.
+func (c *Config) Read(name string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		args, err := pkg.SplitArgs(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: '%s': %w", name, lineNo, line, err)
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		if err := c.Apply(args[0], args[1:]); err != nil {
+			return fmt.Errorf("%s:%d: '%s': %w", name, lineNo, line, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// parseOptions groups the command line options into directives. Every "--name" starts a
+// directive, and the following arguments up to the next option are its arguments.
+func parseOptions(args []string) ([][]string, error) {
+	var options [][]string
+	for _, arg := range args {
+		if name, isOption := strings.CutPrefix(arg, "--"); isOption {
+			if name == "" {
+				return nil, fmt.Errorf("invalid option %s", arg)
+			}
+			options = append(options, []string{name})
+			continue
+		}
+
+		if len(options) == 0 {
+			return nil, fmt.Errorf("unexpected argument %s: expected an option", arg)
+		}
+		last := len(options) - 1
+		options[last] = append(options[last], arg)
+	}
+
+	return options, nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+)
+
+// ClientClass is the class of clients sharing an output buffer limit.
+type ClientClass int
+
+const (
+	ClientClassNormal ClientClass = iota
+	ClientClassReplica
+	ClientClassPubSub
+)
+
+// clientClassNames are the names of the classes in client-output-buffer-limit.
+var clientClassNames = [...]string{"normal", "slave", "pubsub"}
+
+func parseClientClass(s string) (ClientClass, error) {
+	switch strings.ToLower(s) {
+	case "normal":
+		return ClientClassNormal, nil
+	case "replica", "slave":
+		return ClientClassReplica, nil
+	case "pubsub":
+		return ClientClassPubSub, nil
+	default:
+		return 0, fmt.Errorf("invalid client class %s", s)
+	}
+}
+
+// OutputBufferLimit closes the clients whose output buffer reaches the hard limit, or stays
+// over the soft limit for longer than the soft duration. 0 disables a limit.
+type OutputBufferLimit struct {
+	Hard        int64
+	Soft        int64
+	SoftSeconds int64
+}
+
+type OutputBufferLimits [len(clientClassNames)]OutputBufferLimit
+
+var DefaultOutputBufferLimits = OutputBufferLimits{
+	ClientClassNormal:  {},
+	ClientClassReplica: {Hard: 256 * 1024 * 1024, Soft: 64 * 1024 * 1024, SoftSeconds: 60},
+	ClientClassPubSub:  {Hard: 32 * 1024 * 1024, Soft: 8 * 1024 * 1024, SoftSeconds: 60},
+}
+
+func (l *OutputBufferLimits) String() string {
+	parts := make([]string, 0, len(l))
+	for class, limit := range l {
+		parts = append(parts, fmt.Sprintf("%s %d %d %d",
+			clientClassNames[class], limit.Hard, limit.Soft, limit.SoftSeconds))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// ParseOutputBufferLimits parses groups of "<class> <hard> <soft> <soft seconds>" into a
+// copy of the limits, so no limit is changed when a group is invalid.
+func ParseOutputBufferLimits(limits OutputBufferLimits, args []string) (OutputBufferLimits, error) {
+	if len(args) == 0 || len(args)%4 != 0 {
+		return limits, fmt.Errorf("wrong number of arguments in buffer limit configuration")
+	}
+
+	for i := 0; i < len(args); i += 4 {
+		class, err := parseClientClass(args[i])
+		if err != nil {
+			return limits, err
+		}
+
+		hard, err := pkg.ParseMemory(args[i+1])
+		if err != nil {
+			return limits, fmt.Errorf("error in hard, soft or soft_seconds setting in buffer limit configuration")
+		}
+		soft, err := pkg.ParseMemory(args[i+2])
+		if err != nil {
+			return limits, fmt.Errorf("error in hard, soft or soft_seconds setting in buffer limit configuration")
+		}
+		softSeconds, err := strconv.ParseInt(args[i+3], 10, 64)
+		if err != nil || softSeconds < 0 {
+			return limits, fmt.Errorf("error in hard, soft or soft_seconds setting in buffer limit configuration")
+		}
+
+		limits[class] = OutputBufferLimit{Hard: hard, Soft: soft, SoftSeconds: softSeconds}
+	}
+
+	return limits, nil
+}
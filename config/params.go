@@ -0,0 +1,482 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+	"github.com/codecrafters-io/redis-starter-go/storage"
+)
+
+var (
+	ErrBadDirective = errors.New("bad directive or wrong number of arguments")
+	ErrImmutable    = errors.New("can't set immutable config")
+)
+
+// ParamType is the type of the value of a parameter.
+type ParamType int
+
+const (
+	ParamInt ParamType = iota
+	ParamMemory
+	ParamString
+	ParamFlags
+	ParamEnum
+	ParamList    // a list of values separated by spaces
+	ParamSpecial // several values with a format of their own
+)
+
+// Param is a configuration parameter, which is set from the configuration file, the
+// command line and CONFIG SET when it is mutable.
+type Param struct {
+	Name      string
+	Alias     string // the old name, e.g. slaveof for replicaof
+	Type      ParamType
+	Mutable   bool
+	Sensitive bool // the value is redacted from the slow log, e.g. a password
+
+	set     func(c *Config, args []string) error // validates the arguments before setting them
+	get     func(c *Config) []string
+	rewrite func(c *Config) [][]string // the lines written by CONFIG REWRITE, one line of get if nil
+}
+
+// Set validates the value given by CONFIG SET and sets it to the config. Values of list
+// and special types are split into their arguments by spaces.
+func (p *Param) Set(c *Config, value string) error {
+	if p.Type == ParamList || p.Type == ParamSpecial {
+		return p.set(c, strings.Fields(value))
+	}
+
+	return p.set(c, []string{value})
+}
+
+// Get returns the value of the parameter as reported by CONFIG GET.
+func (p *Param) Get(c *Config) string {
+	return strings.Join(p.get(c), " ")
+}
+
+// params are the configuration parameters in the order they are rewritten.
+var params = []*Param{
+	{
+		Name: "bind", Type: ParamList,
+		set: func(c *Config, args []string) error {
+			if len(args) == 0 {
+				return ErrBadDirective
+			}
+
+			c.Bind = args
+			return nil
+		},
+		get: func(c *Config) []string { return c.Bind },
+	},
+	{
+		Name: "port", Type: ParamInt,
+		set: single(func(c *Config, value string) error {
+			port, err := strconv.Atoi(value)
+			if err != nil || port < 1 || port > 65535 {
+				return fmt.Errorf("invalid port %s", value)
+			}
+
+			c.Port = port
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.Itoa(c.Port)} },
+	},
+	{
+		Name: "tcp-backlog", Type: ParamInt,
+		set: single(func(c *Config, value string) error {
+			backlog, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+
+			c.TCPBacklog = int(backlog)
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.Itoa(c.TCPBacklog)} },
+	},
+	{
+		Name: "timeout", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			seconds, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+
+			c.Timeout = time.Duration(seconds) * time.Second
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{formatSeconds(c.Timeout)} },
+	},
+	{
+		Name: "tcp-keepalive", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			seconds, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+
+			c.TCPKeepAlive = time.Duration(seconds) * time.Second
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{formatSeconds(c.TCPKeepAlive)} },
+	},
+	{
+		Name: "hz", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			hz, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid hz %s", value)
+			}
+
+			c.Hz = min(max(hz, MinHz), MaxHz)
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.Itoa(c.Hz)} },
+	},
+	{
+		Name: "dir", Type: ParamString, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			if value == "" {
+				return errors.New("dir can't be empty")
+			}
+
+			c.Dir = value
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{c.Dir} },
+	},
+	{
+		Name: "dbfilename", Type: ParamString, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			if value == "" || path.Base(value) != value {
+				return errors.New("dbfilename can't be a path, just a filename")
+			}
+
+			c.DBFilename = value
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{c.DBFilename} },
+	},
+	{
+		Name: "replicaof", Alias: "slaveof", Type: ParamSpecial,
+		set: func(c *Config, args []string) error {
+			if len(args) != 2 {
+				return ErrBadDirective
+			}
+
+			if strings.EqualFold(args[0], "no") && strings.EqualFold(args[1], "one") {
+				c.ReplicaOf = nil
+				return nil
+			}
+
+			port, err := strconv.Atoi(args[1])
+			if err != nil || port < 1 || port > 65535 {
+				return fmt.Errorf("invalid master port %s", args[1])
+			}
+
+			c.ReplicaOf = &ReplicaOf{Host: args[0], Port: port}
+			return nil
+		},
+		get: func(c *Config) []string {
+			if c.ReplicaOf == nil {
+				return nil
+			}
+			return []string{c.ReplicaOf.Host, strconv.Itoa(c.ReplicaOf.Port)}
+		},
+	},
+	{
+		Name: "maxclients", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return errors.New("argument must be a positive integer")
+			}
+
+			c.MaxClients = n
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.Itoa(c.MaxClients)} },
+	},
+	{
+		Name: "proto-max-bulk-len", Type: ParamMemory, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := pkg.ParseMemory(value)
+			if err != nil {
+				return err
+			}
+			if n < MinProtoMaxBulkLen {
+				return fmt.Errorf("argument must be a memory value bigger than %d", MinProtoMaxBulkLen)
+			}
+
+			c.ProtoMaxBulkLen = n
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.FormatInt(c.ProtoMaxBulkLen, 10)} },
+	},
+	{
+		Name: "client-output-buffer-limit", Type: ParamSpecial, Mutable: true,
+		set: func(c *Config, args []string) error {
+			limits, err := ParseOutputBufferLimits(c.ClientOutputBufferLimits, args)
+			if err != nil {
+				return err
+			}
+
+			c.ClientOutputBufferLimits = limits
+			return nil
+		},
+		get: func(c *Config) []string { return strings.Fields(c.ClientOutputBufferLimits.String()) },
+		rewrite: func(c *Config) [][]string {
+			// a line for each class, as the limits of a class are set at once
+			args := strings.Fields(c.ClientOutputBufferLimits.String())
+			lines := make([][]string, 0, len(args)/4)
+			for i := 0; i < len(args); i += 4 {
+				lines = append(lines, append([]string{"client-output-buffer-limit"}, args[i:i+4]...))
+			}
+			return lines
+		},
+	},
+	{
+		Name: "maxmemory", Type: ParamMemory, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := pkg.ParseMemory(value)
+			if err != nil {
+				return err
+			}
+
+			c.MaxMemory = n
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.FormatInt(c.MaxMemory, 10)} },
+	},
+	{
+		Name: "maxmemory-policy", Type: ParamEnum, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			policy, err := storage.ParseEvictionPolicy(value)
+			if err != nil {
+				return err
+			}
+
+			c.MaxMemoryPolicy = policy
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{c.MaxMemoryPolicy.String()} },
+	},
+	{
+		Name: "maxmemory-samples", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 64 {
+				return errors.New("argument must be between 1 and 64 inclusive")
+			}
+
+			c.MaxMemorySamples = n
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.Itoa(c.MaxMemorySamples)} },
+	},
+	{
+		Name: "lfu-log-factor", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+
+			c.LFULogFactor = int(n)
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.Itoa(c.LFULogFactor)} },
+	},
+	{
+		Name: "lfu-decay-time", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+
+			c.LFUDecayTime = int(n)
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.Itoa(c.LFUDecayTime)} },
+	},
+	{
+		Name: "lua-time-limit", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			ms, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+
+			c.LuaTimeLimit = time.Duration(ms) * time.Millisecond
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.FormatInt(c.LuaTimeLimit.Milliseconds(), 10)} },
+	},
+	{
+		Name: "notify-keyspace-events", Type: ParamFlags, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			flags, err := storage.ParseNotifyFlags(value)
+			if err != nil {
+				return err
+			}
+
+			// without K or E nothing would be published at all
+			if flags&(storage.NotifyKeyspace|storage.NotifyKeyevent) == 0 {
+				flags = 0
+			}
+			c.NotifyKeyspaceEvents = flags
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{c.NotifyKeyspaceEvents.String()} },
+	},
+	{
+		Name: "slowlog-log-slower-than", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return errors.New("argument must be an integer")
+			}
+
+			c.SlowlogLogSlowerThan = n
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.FormatInt(c.SlowlogLogSlowerThan, 10)} },
+	},
+	{
+		Name: "slowlog-max-len", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+
+			c.SlowlogMaxLen = int(n)
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.Itoa(c.SlowlogMaxLen)} },
+	},
+	{
+		Name: "latency-monitor-threshold", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+
+			c.LatencyMonitorThreshold = n
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.FormatInt(c.LatencyMonitorThreshold, 10)} },
+	},
+	{
+		Name: "requirepass", Type: ParamString, Mutable: true, Sensitive: true,
+		set: single(func(c *Config, value string) error {
+			c.RequirePass = value
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{c.RequirePass} },
+	},
+	{
+		Name: "aclfile", Type: ParamString,
+		set: single(func(c *Config, value string) error {
+			c.ACLFile = value
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{c.ACLFile} },
+	},
+	{
+		Name: "acllog-max-len", Type: ParamInt, Mutable: true,
+		set: single(func(c *Config, value string) error {
+			n, err := parseNonNegative(value)
+			if err != nil {
+				return err
+			}
+
+			c.ACLLogMaxLen = int(n)
+			return nil
+		}),
+		get: func(c *Config) []string { return []string{strconv.Itoa(c.ACLLogMaxLen)} },
+	},
+}
+
+// lines returns the lines of the parameter written by CONFIG REWRITE.
+func (p *Param) lines(c *Config) [][]string {
+	if p.rewrite != nil {
+		return p.rewrite(c)
+	}
+
+	args := p.get(c)
+	if len(args) == 0 {
+		return nil
+	}
+	return [][]string{append([]string{p.Name}, args...)}
+}
+
+// Lookup returns the parameter by its name or alias, case-insensitively.
+func Lookup(name string) (*Param, bool) {
+	name = strings.ToLower(name)
+	i := slices.IndexFunc(params, func(p *Param) bool {
+		return p.Name == name || (p.Alias != "" && p.Alias == name)
+	})
+	if i < 0 {
+		return nil, false
+	}
+
+	return params[i], true
+}
+
+// Match returns the parameters whose names match the glob pattern case-insensitively.
+// An alias is matched only when the pattern has no wildcards, so a parameter is not
+// reported twice by patterns such as "*".
+func Match(pattern string) []*Param {
+	var matched []*Param
+	for _, p := range params {
+		if pkg.MatchGlobFold(pattern, p.Name) || (p.Alias != "" && strings.EqualFold(pattern, p.Alias)) {
+			matched = append(matched, p)
+		}
+	}
+
+	return matched
+}
+
+// Apply applies a directive of the configuration file or the command line with its
+// arguments to the config.
+func (c *Config) Apply(name string, args []string) error {
+	p, found := Lookup(name)
+	if !found {
+		return ErrBadDirective
+	}
+
+	return p.set(c, args)
+}
+
+// single adapts the setter of a parameter taking exactly one argument.
+func single(set func(c *Config, value string) error) func(c *Config, args []string) error {
+	return func(c *Config, args []string) error {
+		if len(args) != 1 {
+			return ErrBadDirective
+		}
+
+		return set(c, args[0])
+	}
+}
+
+func parseNonNegative(value string) (int64, error) {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("argument must be a non-negative integer")
+	}
+
+	return n, nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(d.Seconds()), 10)
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+)
+
+const rewriteSignature = "# Generated by CONFIG REWRITE"
+
+var ErrNoConfigFile = errors.New("the server is running without a config file")
+
+// Rewrite rewrites the configuration file with the current config. Comments and unknown
+// lines are kept, and the first lines of a parameter are replaced by its current value,
+// while the rest of its lines are removed. Parameters not in the file are appended unless
+// they have their default values.
+func Rewrite(c *Config) error {
+	if c.File == "" {
+		return ErrNoConfigFile
+	}
+
+	old, err := readLines(c.File)
+	if err != nil {
+		return err
+	}
+
+	// indexes of the lines of each parameter in the file
+	lines := make([]string, 0, len(old))
+	positions := make(map[*Param][]int)
+	for _, line := range old {
+		if strings.TrimSpace(line) == rewriteSignature {
+			continue
+		}
+
+		if p, found := lineParam(line); found {
+			positions[p] = append(positions[p], len(lines))
+		}
+		lines = append(lines, line)
+	}
+
+	removed := make(map[int]bool)
+	var appended []string
+	defaults := Default()
+	for _, p := range params {
+		defaultLines := p.lines(defaults)
+		for _, args := range p.lines(c) {
+			isDefault := slices.ContainsFunc(defaultLines, func(d []string) bool { return slices.Equal(d, args) })
+			// a parameter of several lines only keeps the lines changed from the defaults,
+			// e.g. the classes of client-output-buffer-limit
+			if isDefault && (p.rewrite != nil || len(positions[p]) == 0) {
+				continue
+			}
+
+			line := formatLine(args)
+			if pos := positions[p]; len(pos) > 0 {
+				lines[pos[0]] = line
+				positions[p] = pos[1:]
+				continue
+			}
+			appended = append(appended, line)
+		}
+
+		for _, pos := range positions[p] {
+			removed[pos] = true
+		}
+	}
+
+	var sb strings.Builder
+	for i, line := range lines {
+		if removed[i] {
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	if len(appended) > 0 {
+		sb.WriteString(rewriteSignature)
+		sb.WriteByte('\n')
+		for _, line := range appended {
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+	}
+
+	return writeFile(c.File, sb.String())
+}
+
+func readLines(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil // the file is created
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	return lines, nil
+}
+
+// lineParam returns the parameter set by the line, if it is not a comment.
+func lineParam(line string) (*Param, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return nil, false
+	}
+
+	args, err := pkg.SplitArgs(trimmed)
+	if err != nil || len(args) == 0 {
+		return nil, false
+	}
+
+	return Lookup(args[0])
+}
+
+// writeFile replaces the file through a temporary file, so it is never left half written.
+func writeFile(name string, content string) error {
+	mode := fs.FileMode(0o644)
+	if info, err := os.Stat(name); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), "redis.conf.*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary config file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.WriteString(content); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to sync config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close config file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to set mode of config file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+
+	return nil
+}
+
+// formatLine formats the arguments as a line, quoting the ones which would not be read
+// back as they are.
+func formatLine(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, quoteArg(arg))
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+func quoteArg(s string) string {
+	needsQuote := s == "" || strings.ContainsFunc(s, func(r rune) bool {
+		return r <= ' ' || r == '"' || r == '\'' || r == '\\' || r == 0x7f
+	})
+	if !needsQuote {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; b {
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(b)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\a':
+			sb.WriteString(`\a`)
+		case '\b':
+			sb.WriteString(`\b`)
+		default:
+			if b < ' ' || b == 0x7f {
+				fmt.Fprintf(&sb, `\x%02x`, b)
+			} else {
+				sb.WriteByte(b)
+			}
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String()
+}
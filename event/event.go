@@ -24,6 +24,12 @@ type Pusher interface {
 	ShutdownPushing()
 }
 
+// Flusher is called by the loop after each batch of events is handled,
+// e.g. to write the replies buffered while handling them at once.
+type Flusher interface {
+	Flush(push func(Event))
+}
+
 // events
 
 const (
@@ -34,6 +40,7 @@ const (
 	CloseEventType = "close"
 
 	ExpireEventType = "expire"
+	CronEventType   = "cron"
 
 	LexingEventType  = "lexing"
 	ParseEventType   = "parse"
@@ -68,9 +75,10 @@ func (r *ReadEvent) ID() uint64 {
 	return r.ID_
 }
 
+// WriteEvent tells that the output buffer of the connection has data to be written on the next flush.
 type WriteEvent struct {
 	ID_  uint64
-	Data []byte
+	Done bool // a previous write is done, so the rest of the output can be written
 }
 
 func (w *WriteEvent) ID() uint64 {
@@ -106,9 +114,24 @@ func (e *ExpireEvent) ID() uint64 {
 	return e.ID_
 }
 
+// CronEvent is pushed periodically to run background tasks on the loop.
+type CronEvent struct {
+	ID_  uint64
+	Time time.Time
+}
+
+func (c *CronEvent) Type() Type {
+	return CronEventType
+}
+
+func (c *CronEvent) ID() uint64 {
+	return c.ID_
+}
+
 type LexingEvent struct {
 	ID_  uint64
 	Data []byte
+	Last bool // the last line read at once, so reading is resumed after lexing it
 }
 
 func (p *LexingEvent) ID() uint64 {
@@ -135,6 +158,8 @@ func (c *ParseEvent) Type() Type {
 type ExecuteEvent struct {
 	ID_     uint64
 	Command spec.Command
+	Args    []string // the command name and arguments as sent by the client
+	Err     error    // set instead of Command when the data could not be parsed into a command
 }
 
 func (e *ExecuteEvent) ID() uint64 {
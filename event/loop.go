@@ -12,15 +12,21 @@ import (
 type Loop struct {
 	handlerMap map[Type]Handler
 	pushers    []Pusher
+	flushers   []Flusher
 
 	queue      *queue.ConcurrentQueue[Event]
 	stopSignal chan struct{}
 	wg         *sync.WaitGroup
+
+	// only accessed from the loop, as INFO is handled on it
+	cycles   int64
+	duration time.Duration // spent handling batches of events
 }
 
 func NewLoop(
 	handlers []Handler,
 	pushers []Pusher,
+	flushers []Flusher,
 ) *Loop {
 	handlerMap := make(map[Type]Handler, len(handlers))
 	for _, h := range handlers {
@@ -30,6 +36,7 @@ func NewLoop(
 	return &Loop{
 		handlerMap: handlerMap,
 		pushers:    slices.Clone(pushers),
+		flushers:   slices.Clone(flushers),
 		queue:      queue.NewConcurrentQueue[Event](),
 	}
 }
@@ -62,18 +69,72 @@ func (l *Loop) loop() {
 			for {
 				e, has := l.queue.Dequeue()
 				if !has {
+					l.flush()
 					return
 				}
 				l.handleEvent(e)
 			}
 		default:
-			e, has := l.queue.Dequeue()
-			if has {
-				l.handleEvent(e)
-			} else {
+			// handle the events queued so far as a batch, then flush once
+			n := l.queue.Len()
+			if n == 0 {
 				time.Sleep(time.Microsecond)
+				continue
+			}
+
+			start := time.Now()
+			for range n {
+				e, has := l.queue.Dequeue()
+				if !has {
+					break
+				}
+				l.handleEvent(e)
 			}
+			l.flush()
+
+			l.cycles++
+			l.duration += time.Since(start)
+		}
+	}
+}
+
+// Cycles returns the number of batches of events handled so far.
+func (l *Loop) Cycles() int64 {
+	return l.cycles
+}
+
+// Duration returns the total time spent handling the batches of events.
+func (l *Loop) Duration() time.Duration {
+	return l.duration
+}
+
+// Yield handles the events queued so far, so that a handler blocking the loop for a long time
+// (e.g. a busy script) can still serve other events. It must be called from a handler.
+// Events not accepted are put back at the front of the queue in their order, so they are
+// handled after the yielding handler returns, before the events queued meanwhile.
+func (l *Loop) Yield(accept func(Event) bool) {
+	var deferred []Event
+
+	for range l.queue.Len() {
+		e, has := l.queue.Dequeue()
+		if !has {
+			break
 		}
+
+		if !accept(e) {
+			deferred = append(deferred, e)
+			continue
+		}
+		l.handleEvent(e)
+	}
+
+	l.queue.EnqueueFront(deferred...)
+	l.flush()
+}
+
+func (l *Loop) flush() {
+	for _, f := range l.flushers {
+		f.Flush(l.queue.Enqueue)
 	}
 }
 
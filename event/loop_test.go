@@ -0,0 +1,66 @@
+package event
+
+import (
+	"slices"
+	"testing"
+)
+
+const testEventType Type = "test"
+
+type testEvent struct {
+	id   uint64
+	name string
+}
+
+func (e *testEvent) ID() uint64 { return e.id }
+func (e *testEvent) Type() Type { return testEventType }
+
+// recordHandler records the names of the events it handles, and pushes the event named by
+// next when it handles an event, if any.
+type recordHandler struct {
+	handled []string
+	next    map[string]*testEvent
+}
+
+func (h *recordHandler) Target() Type { return testEventType }
+
+func (h *recordHandler) Handle(e Event, push func(Event)) error {
+	te := e.(*testEvent)
+	h.handled = append(h.handled, te.name)
+	if next, found := h.next[te.name]; found {
+		push(next)
+	}
+	return nil
+}
+
+func TestLoopYield(t *testing.T) {
+	h := &recordHandler{next: map[string]*testEvent{"b1": {id: 2, name: "b3"}}}
+	l := NewLoop([]Handler{h}, nil, nil)
+
+	for _, e := range []*testEvent{
+		{id: 1, name: "a1"},
+		{id: 2, name: "b1"},
+		{id: 1, name: "a2"},
+		{id: 2, name: "b2"},
+	} {
+		l.queue.Enqueue(e)
+	}
+
+	l.Yield(func(e Event) bool { return e.ID() != 1 })
+	if want := []string{"b1", "b2"}; !slices.Equal(h.handled, want) {
+		t.Errorf("Yield handled %q, want %q", h.handled, want)
+	}
+
+	// the events not accepted are handled first in their order, before the ones pushed meanwhile
+	var queued []string
+	for {
+		e, has := l.queue.Dequeue()
+		if !has {
+			break
+		}
+		queued = append(queued, e.(*testEvent).name)
+	}
+	if want := []string{"a1", "a2", "b3"}; !slices.Equal(queued, want) {
+		t.Errorf("queue after Yield = %q, want %q", queued, want)
+	}
+}
@@ -11,5 +11,5 @@ type NumIDIssuer struct {
 }
 
 func (i *NumIDIssuer) Issue() uint64 {
-	return i.next.Add(1) - 1
+	return i.next.Add(1)
 }
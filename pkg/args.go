@@ -0,0 +1,150 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnbalancedQuotes = errors.New("unbalanced quotes")
+
+// SplitArgs splits a line into arguments separated by whitespace, following the
+// same rules as Redis for inline commands and configuration files. Arguments in
+// double quotes may contain escapes such as "\n" and "\x41", while arguments in
+// single quotes only allow "\'". A closing quote must be followed by whitespace.
+func SplitArgs(line string) ([]string, error) {
+	args := make([]string, 0)
+
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var sb strings.Builder
+		inDoubleQuotes, inSingleQuotes := false, false
+		done := false
+		for !done {
+			if inDoubleQuotes {
+				switch {
+				case i == len(line):
+					return nil, ErrUnbalancedQuotes
+				case line[i] == '\\' && i+3 < len(line) && line[i+1] == 'x' &&
+					isHexDigit(line[i+2]) && isHexDigit(line[i+3]):
+					sb.WriteByte(hexDigitValue(line[i+2])<<4 | hexDigitValue(line[i+3]))
+					i += 3
+				case line[i] == '\\' && i+1 < len(line):
+					i++
+					switch line[i] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 'r':
+						sb.WriteByte('\r')
+					case 't':
+						sb.WriteByte('\t')
+					case 'b':
+						sb.WriteByte('\b')
+					case 'a':
+						sb.WriteByte('\a')
+					default:
+						sb.WriteByte(line[i])
+					}
+				case line[i] == '"':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					sb.WriteByte(line[i])
+				}
+			} else if inSingleQuotes {
+				switch {
+				case i == len(line):
+					return nil, ErrUnbalancedQuotes
+				case line[i] == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					sb.WriteByte('\'')
+				case line[i] == '\'':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					sb.WriteByte(line[i])
+				}
+			} else {
+				switch {
+				case i == len(line) || isSpace(line[i]):
+					done = true
+				case line[i] == '"':
+					inDoubleQuotes = true
+				case line[i] == '\'':
+					inSingleQuotes = true
+				default:
+					sb.WriteByte(line[i])
+				}
+			}
+
+			if i < len(line) {
+				i++
+			}
+		}
+
+		args = append(args, sb.String())
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\v' || c == '\f'
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexDigitValue(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+// Repr quotes the string as Redis shows arguments, e.g. in MONITOR: within double quotes,
+// escaping quotes, backslashes and non-printable bytes such as "\n" or "\x01".
+func Repr(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; b {
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(b)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\a':
+			sb.WriteString(`\a`)
+		case '\b':
+			sb.WriteString(`\b`)
+		default:
+			if b < ' ' || b >= 0x7f {
+				fmt.Fprintf(&sb, `\x%02x`, b)
+			} else {
+				sb.WriteByte(b)
+			}
+		}
+	}
+	sb.WriteByte('"')
+
+	return sb.String()
+}
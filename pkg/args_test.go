@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want []string
+		err  error
+	}{
+		{name: "empty", line: "", want: []string{}},
+		{name: "only spaces", line: " \t\r\n ", want: []string{}},
+		{name: "words", line: "SET  key\tvalue", want: []string{"SET", "key", "value"}},
+		{name: "double quotes", line: `SET "a key" "a value"`, want: []string{"SET", "a key", "a value"}},
+		{name: "empty double quotes", line: `ECHO ""`, want: []string{"ECHO", ""}},
+		{name: "single quotes", line: `ECHO 'a "b"'`, want: []string{"ECHO", `a "b"`}},
+		{name: "escaped single quote", line: `ECHO 'it\'s'`, want: []string{"ECHO", "it's"}},
+		{name: "no escapes in single quotes", line: `ECHO 'a\nb'`, want: []string{"ECHO", `a\nb`}},
+		{name: "escapes", line: `ECHO "a\nb\r\t\b\a\"\\"`, want: []string{"ECHO", "a\nb\r\t\b\a\"\\"}},
+		{name: "hex escape", line: `ECHO "\x41\x6a"`, want: []string{"ECHO", "Aj"}},
+		{name: "uppercase hex escape", line: `ECHO "\xFF"`, want: []string{"ECHO", "\xff"}},
+		{name: "truncated hex escape", line: `ECHO "\x4"`, want: []string{"ECHO", "x4"}},
+		{name: "invalid hex escape", line: `ECHO "\xzz"`, want: []string{"ECHO", "xzz"}},
+		{name: "quotes within a word", line: `ECHO a"b c"`, want: []string{"ECHO", "ab c"}},
+		{name: "text after closing double quote", line: `ECHO "a"b`, err: ErrUnbalancedQuotes},
+		{name: "text after closing single quote", line: `ECHO 'a'b`, err: ErrUnbalancedQuotes},
+		{name: "unterminated double quotes", line: `ECHO "abc`, err: ErrUnbalancedQuotes},
+		{name: "unterminated single quotes", line: `ECHO 'abc`, err: ErrUnbalancedQuotes},
+		{name: "trailing backslash in quotes", line: `ECHO "abc\`, err: ErrUnbalancedQuotes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SplitArgs(tt.line)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("SplitArgs(%q) error = %v, want %v", tt.line, err, tt.err)
+			}
+			if tt.err == nil && !slices.Equal(got, tt.want) {
+				t.Errorf("SplitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepr(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "", want: `""`},
+		{s: "get", want: `"get"`},
+		{s: `a "quoted" \ value`, want: `"a \"quoted\" \\ value"`},
+		{s: "\n\r\t\a\b", want: `"\n\r\t\a\b"`},
+		{s: "\x00\x01\x1f", want: `"\x00\x01\x1f"`},
+		{s: "\x7f\xff", want: `"\x7f\xff"`},
+		{s: "é", want: `"\xc3\xa9"`},
+	}
+
+	for _, tt := range tests {
+		if got := Repr(tt.s); got != tt.want {
+			t.Errorf("Repr(%q) = %s, want %s", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestReprRoundTrip(t *testing.T) {
+	for _, s := range []string{"", "plain", "a b", "\x00\xff\n\"\\", "\x1b[31m"} {
+		args, err := SplitArgs(Repr(s))
+		if err != nil {
+			t.Fatalf("SplitArgs(Repr(%q)) error = %v", s, err)
+		}
+		if len(args) != 1 || args[0] != s {
+			t.Errorf("SplitArgs(Repr(%q)) = %q", s, args)
+		}
+	}
+}
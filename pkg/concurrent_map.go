@@ -87,3 +87,10 @@ func (m *ConcurrentMap[K, V]) Swap(key K, value V) (previous V, loaded bool) {
 	}
 	return previous, true
 }
+
+// Range calls f for each key and value until f returns false, like sync.Map.Range.
+func (m *ConcurrentMap[K, V]) Range(f func(key K, value V) bool) {
+	m.m.Range(func(key, value any) bool {
+		return f(key.(K), value.(V))
+	})
+}
@@ -1,28 +0,0 @@
-package pkg
-
-import (
-	"bufio"
-	"bytes"
-	"io"
-)
-
-func NewCRLFScanner(r io.Reader) *bufio.Scanner {
-	sc := bufio.NewScanner(r)
-	sc.Split(
-		func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-			if atEOF && len(data) == 0 {
-				return 0, nil, nil
-			}
-			if i := bytes.Index(data, []byte{'\r', '\n'}); i >= 0 {
-				return i + 2, data[0:i], nil
-			}
-			// If we're at EOF, we have a final, non-terminated line. Return it.
-			if atEOF {
-				return len(data), data, nil
-			}
-			// Request more data.
-			return 0, nil, nil
-		},
-	)
-	return sc
-}
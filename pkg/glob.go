@@ -0,0 +1,118 @@
+package pkg
+
+// MatchGlob reports whether s matches the glob-style pattern, following the
+// same rules as Redis: '*' matches any sequence, '?' matches a single byte,
+// '[...]' matches a set or range (negated with '^') and '\' escapes the next byte.
+func MatchGlob(pattern, s string) bool {
+	return matchGlob(pattern, s, false)
+}
+
+// MatchGlobFold is the case-insensitive variant of MatchGlob.
+func MatchGlobFold(pattern, s string) bool {
+	return matchGlob(pattern, s, true)
+}
+
+func matchGlob(pattern, s string, fold bool) bool {
+	p, i := 0, 0
+	for p < len(pattern) {
+		switch pattern[p] {
+		case '*':
+			for p+1 < len(pattern) && pattern[p+1] == '*' {
+				p++
+			}
+			if p+1 == len(pattern) {
+				return true
+			}
+			for j := i; j <= len(s); j++ {
+				if matchGlob(pattern[p+1:], s[j:], fold) {
+					return true
+				}
+			}
+			return false
+
+		case '?':
+			if i >= len(s) {
+				return false
+			}
+			i++
+
+		case '[':
+			if i >= len(s) {
+				return false
+			}
+
+			p++
+			not := p < len(pattern) && pattern[p] == '^'
+			if not {
+				p++
+			}
+
+			match := false
+			for p < len(pattern) && pattern[p] != ']' {
+				switch {
+				case pattern[p] == '\\' && p+1 < len(pattern):
+					p++
+					if equalByte(pattern[p], s[i], fold) {
+						match = true
+					}
+				case p+2 < len(pattern) && pattern[p+1] == '-':
+					start, end := pattern[p], pattern[p+2]
+					if start > end {
+						start, end = end, start
+					}
+					c := s[i]
+					if fold {
+						start, end, c = lowerByte(start), lowerByte(end), lowerByte(c)
+					}
+					if c >= start && c <= end {
+						match = true
+					}
+					p += 2
+				default:
+					if equalByte(pattern[p], s[i], fold) {
+						match = true
+					}
+				}
+				p++
+			}
+
+			if not {
+				match = !match
+			}
+			if !match {
+				return false
+			}
+			i++
+
+		case '\\':
+			if p+1 < len(pattern) {
+				p++
+			}
+			fallthrough
+
+		default:
+			if i >= len(s) || !equalByte(pattern[p], s[i], fold) {
+				return false
+			}
+			i++
+		}
+
+		p++
+	}
+
+	return i == len(s)
+}
+
+func equalByte(a, b byte, fold bool) bool {
+	if fold {
+		return lowerByte(a) == lowerByte(b)
+	}
+	return a == b
+}
+
+func lowerByte(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + ('a' - 'A')
+	}
+	return c
+}
@@ -0,0 +1,69 @@
+package pkg
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{pattern: "", s: "", want: true},
+		{pattern: "", s: "a", want: false},
+		{pattern: "*", s: "", want: true},
+		{pattern: "*", s: "anything", want: true},
+		{pattern: "cache:*", s: "cache:1", want: true},
+		{pattern: "cache:*", s: "cache", want: false},
+		{pattern: "*:id", s: "user:1:id", want: true},
+		{pattern: "a**b", s: "axxb", want: true},
+		{pattern: "a*b*c", s: "abxbc", want: true},
+		{pattern: "a*b*c", s: "abxb", want: false},
+		{pattern: "h?llo", s: "hello", want: true},
+		{pattern: "h?llo", s: "hllo", want: false},
+		{pattern: "?", s: "", want: false},
+		{pattern: "h[ae]llo", s: "hallo", want: true},
+		{pattern: "h[ae]llo", s: "hillo", want: false},
+		{pattern: "h[^e]llo", s: "hallo", want: true},
+		{pattern: "h[^e]llo", s: "hello", want: false},
+		{pattern: "[a-c]x", s: "bx", want: true},
+		{pattern: "[a-c]x", s: "dx", want: false},
+		{pattern: "[c-a]x", s: "bx", want: true},
+		{pattern: "[^a-c]x", s: "dx", want: true},
+		{pattern: "[^a-c]x", s: "ax", want: false},
+		{pattern: "[a-c]", s: "", want: false},
+		{pattern: `[\]]`, s: "]", want: true},
+		{pattern: `[\-]`, s: "-", want: true},
+		{pattern: `a\*b`, s: "a*b", want: true},
+		{pattern: `a\*b`, s: "axb", want: false},
+		{pattern: `a\?`, s: "a?", want: true},
+		{pattern: `\[x]`, s: "[x]", want: true},
+		{pattern: `ab\`, s: `ab\`, want: true},
+		{pattern: "Key", s: "key", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMatchGlobFold(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{pattern: "Key", s: "kEY", want: true},
+		{pattern: "max*", s: "MAXMEMORY", want: true},
+		{pattern: "[A-C]x", s: "bX", want: true},
+		{pattern: "[^a-c]", s: "B", want: false},
+		{pattern: "h?llo", s: "HELLO", want: true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlobFold(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("MatchGlobFold(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
@@ -31,6 +31,10 @@ func (h *Heap[T]) Pop() (T, bool) {
 	return val.(T), true
 }
 
+func (h *Heap[T]) Clear() {
+	h.heap.data = []T{}
+}
+
 func (h *Heap[T]) Len() int {
 	return h.heap.Len()
 }
@@ -69,8 +73,9 @@ func (h *heap[T]) Pop() any {
 		return nil
 	}
 
-	val := h.data[0]
-	h.data = h.data[1:]
+	// container/heap moves the element to remove to the end before calling Pop
+	val := h.data[len(h.data)-1]
+	h.data = h.data[:len(h.data)-1]
 
 	return val
 }
@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var memoryUnits = []struct {
+	suffix string
+	mul    int64
+}{
+	// longer suffixes first, so "kb" is not taken for "b"
+	{"kb", 1024},
+	{"mb", 1024 * 1024},
+	{"gb", 1024 * 1024 * 1024},
+	{"k", 1000},
+	{"m", 1000 * 1000},
+	{"g", 1000 * 1000 * 1000},
+	{"b", 1},
+}
+
+// ParseMemory parses a memory amount such as "100", "1k", "64mb" or "1gb" the same
+// way as Redis configuration does: k, m and g are powers of 1000 while kb, mb and
+// gb are powers of 1024. Units are case-insensitive.
+func ParseMemory(s string) (int64, error) {
+	lower := strings.ToLower(s)
+
+	mul := int64(1)
+	for _, u := range memoryUnits {
+		if strings.HasSuffix(lower, u.suffix) {
+			lower = strings.TrimSuffix(lower, u.suffix)
+			mul = u.mul
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(lower, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory amount: %s", s)
+	}
+	if n > 0 && mul > 1 && n > (1<<63-1)/mul {
+		return 0, fmt.Errorf("memory amount out of range: %s", s)
+	}
+
+	return n * mul, nil
+}
@@ -24,6 +24,13 @@ func (q *ConcurrentQueue[T]) IsEmpty() bool {
 	return q.list.Len() == 0
 }
 
+func (q *ConcurrentQueue[T]) Len() int {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	return q.list.Len()
+}
+
 func (q *ConcurrentQueue[T]) Enqueue(val T) {
 	q.lock.Lock()
 	defer q.lock.Unlock()
@@ -43,3 +50,14 @@ func (q *ConcurrentQueue[T]) Dequeue() (T, bool) {
 		return zero, false
 	}
 }
+
+// EnqueueFront puts the values back at the front of the queue, keeping their order, so
+// they are dequeued before the values already queued.
+func (q *ConcurrentQueue[T]) EnqueueFront(vals ...T) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for i := len(vals) - 1; i >= 0; i-- {
+		_ = q.list.PushFront(vals[i])
+	}
+}
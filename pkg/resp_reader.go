@@ -0,0 +1,246 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	// MaxInlineLen is the maximum length of an inline command or a RESP header line.
+	MaxInlineLen = 64 * 1024
+	// MaxMultiBulkLen is the maximum number of elements in a single aggregate.
+	MaxMultiBulkLen = 1024 * 1024
+
+	readChunkLen = 16 * 1024
+)
+
+var (
+	ErrTooBigInline        = errors.New("Protocol error: too big inline request")
+	ErrInvalidBulkLen      = errors.New("Protocol error: invalid bulk length")
+	ErrInvalidMultiBulk    = errors.New("Protocol error: invalid multibulk length")
+	ErrBulkNotTerminated   = errors.New("Protocol error: bulk string is not terminated by CRLF")
+	ErrUnexpectedEndOfBulk = errors.New("Protocol error: unexpected end of bulk string")
+)
+
+// RESPReader splits a RESP stream into lines, except that the payload of a bulk
+// string, bulk error or verbatim string is read by its declared length, so it may
+// contain CRLF. The payload is returned as one token without the trailing CRLF.
+// Lines outside of an aggregate not starting with '*' are inline commands,
+// terminated by LF alone as well.
+//
+// Tokens are only valid until the next call to Next or NextBuffered.
+type RESPReader struct {
+	r        io.Reader
+	buf      []byte
+	start    int
+	end      int
+	err      error
+	splitter *respSplitter
+
+	buffered atomic.Int64 // bytes read but not returned yet, for other goroutines
+}
+
+// NewRESPReader returns a reader of the RESP stream from r. maxBulkLen is called
+// for every bulk header, so the limit can be changed while reading.
+func NewRESPReader(r io.Reader, maxBulkLen func() int) *RESPReader {
+	return &RESPReader{
+		r:        r,
+		buf:      make([]byte, readChunkLen),
+		splitter: &respSplitter{maxBulkLen: maxBulkLen, bulkLen: -1},
+	}
+}
+
+// Next returns the next token, blocking until it is read. It returns io.EOF at the end of the stream.
+func (rr *RESPReader) Next() ([]byte, error) {
+	for {
+		token, found, err := rr.split(rr.err != nil)
+		if err != nil || found {
+			return token, err
+		}
+
+		if rr.err != nil {
+			return nil, rr.err
+		}
+		rr.fill()
+	}
+}
+
+// NextBuffered returns the next token if it is already read, without blocking.
+func (rr *RESPReader) NextBuffered() ([]byte, bool, error) {
+	return rr.split(false)
+}
+
+// Buffered returns the number of bytes read but not returned as tokens yet.
+// It is safe to call from another goroutine than the reading one.
+func (rr *RESPReader) Buffered() int {
+	return int(rr.buffered.Load())
+}
+
+func (rr *RESPReader) split(atEOF bool) ([]byte, bool, error) {
+	if rr.start == rr.end && !atEOF {
+		return nil, false, nil
+	}
+
+	advance, token, err := rr.splitter.split(rr.buf[rr.start:rr.end], atEOF)
+	if err != nil {
+		return nil, false, err
+	}
+	if advance == 0 && token == nil {
+		return nil, false, nil
+	}
+
+	rr.start += advance
+	rr.buffered.Store(int64(rr.end - rr.start))
+	return token, true, nil
+}
+
+func (rr *RESPReader) fill() {
+	// release the memory taken by a big payload once it is consumed
+	if rr.start == rr.end && len(rr.buf) > 4*readChunkLen {
+		rr.buf = make([]byte, readChunkLen)
+		rr.start, rr.end = 0, 0
+	}
+
+	if rr.start > 0 {
+		copy(rr.buf, rr.buf[rr.start:rr.end])
+		rr.end -= rr.start
+		rr.start = 0
+	}
+
+	// make room for a chunk, or the whole bulk payload being read
+	need := rr.end + readChunkLen
+	if rr.splitter.bulkLen >= 0 {
+		need = max(need, rr.splitter.bulkLen+2)
+	}
+	if need > len(rr.buf) {
+		buf := make([]byte, max(need, 2*len(rr.buf)))
+		copy(buf, rr.buf[:rr.end])
+		rr.buf = buf
+	}
+
+	n, err := rr.r.Read(rr.buf[rr.end:])
+	rr.end += n
+	rr.buffered.Store(int64(rr.end - rr.start))
+	if err != nil {
+		rr.err = err
+	}
+}
+
+type respSplitter struct {
+	maxBulkLen func() int
+
+	remaining []int // elements left in each open aggregate, innermost last
+	bulkLen   int   // length of the next payload, or -1 when the next token is a line
+}
+
+func (s *respSplitter) split(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if s.bulkLen >= 0 {
+		n := s.bulkLen
+		if len(data) < n+2 {
+			if atEOF {
+				return 0, nil, ErrUnexpectedEndOfBulk
+			}
+			// Request more data.
+			return 0, nil, nil
+		}
+
+		if data[n] != '\r' || data[n+1] != '\n' {
+			return 0, nil, ErrBulkNotTerminated
+		}
+
+		s.bulkLen = -1
+		s.elementDone()
+		return n + 2, data[:n], nil
+	}
+
+	if len(s.remaining) == 0 && data[0] != '*' {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			return s.incompleteLine(data, atEOF)
+		}
+
+		return i + 1, bytes.TrimSuffix(data[:i], []byte{'\r'}), nil
+	}
+
+	i := bytes.Index(data, []byte{'\r', '\n'})
+	if i < 0 {
+		return s.incompleteLine(data, atEOF)
+	}
+
+	line := data[:i]
+	if err := s.frame(line); err != nil {
+		return 0, nil, err
+	}
+
+	return i + 2, line, nil
+}
+
+func (s *respSplitter) incompleteLine(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) > MaxInlineLen {
+		return 0, nil, ErrTooBigInline
+	}
+	// If we're at EOF, we have a final, non-terminated line. Return it.
+	if atEOF {
+		return len(data), data, nil
+	}
+	// Request more data.
+	return 0, nil, nil
+}
+
+// frame tracks the structure of the stream from a header line.
+func (s *respSplitter) frame(line []byte) error {
+	if len(line) == 0 {
+		s.elementDone()
+		return nil
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	switch line[0] {
+	case '$', '!', '=':
+		if err != nil || n < 0 {
+			break // malformed or null, left to the lexer
+		}
+		if n > s.maxBulkLen() {
+			return ErrInvalidBulkLen
+		}
+
+		s.bulkLen = n
+		return nil
+
+	case '*', '%', '~', '|', '>':
+		if err != nil || n <= 0 {
+			break
+		}
+		if n > MaxMultiBulkLen {
+			return ErrInvalidMultiBulk
+		}
+
+		if line[0] == '%' || line[0] == '|' {
+			n *= 2
+		}
+		s.remaining = append(s.remaining, n)
+		return nil
+	}
+
+	s.elementDone()
+	return nil
+}
+
+// elementDone counts a complete element in the innermost aggregate, closing the
+// aggregates completed by it.
+func (s *respSplitter) elementDone() {
+	for len(s.remaining) > 0 {
+		s.remaining[len(s.remaining)-1]--
+		if s.remaining[len(s.remaining)-1] > 0 {
+			return
+		}
+		s.remaining = s.remaining[:len(s.remaining)-1]
+	}
+}
@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// readTokens returns the tokens of the stream until an error, io.EOF excluded.
+func readTokens(r io.Reader, maxBulkLen int) ([]string, error) {
+	rr := NewRESPReader(r, func() int { return maxBulkLen })
+
+	var tokens []string
+	for {
+		token, err := rr.Next()
+		if errors.Is(err, io.EOF) {
+			return tokens, nil
+		}
+		if err != nil {
+			return tokens, err
+		}
+		tokens = append(tokens, string(token))
+	}
+}
+
+func TestRESPReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+		err   error
+	}{
+		{
+			name:  "command",
+			input: "*2\r\n$4\r\nECHO\r\n$3\r\nhey\r\n",
+			want:  []string{"*2", "$4", "ECHO", "$3", "hey"},
+		},
+		{
+			name:  "bulk string with CRLF",
+			input: "*2\r\n$4\r\nECHO\r\n$4\r\na\r\nb\r\n",
+			want:  []string{"*2", "$4", "ECHO", "$4", "a\r\nb"},
+		},
+		{
+			name:  "empty bulk string",
+			input: "*1\r\n$0\r\n\r\n",
+			want:  []string{"*1", "$0", ""},
+		},
+		{
+			name:  "null bulk string",
+			input: "*2\r\n$-1\r\n:1\r\n",
+			want:  []string{"*2", "$-1", ":1"},
+		},
+		{
+			name:  "nested aggregates",
+			input: "*2\r\n%1\r\n+k\r\n*1\r\n=7\r\ntxt:a\r\n\r\n$1\r\nx\r\n",
+			want:  []string{"*2", "%1", "+k", "*1", "=7", "txt:a\r\n", "$1", "x"},
+		},
+		{
+			name:  "inline commands",
+			input: "PING\r\nECHO \"a b\"\nQUIT",
+			want:  []string{"PING", `ECHO "a b"`, "QUIT"},
+		},
+		{
+			name:  "inline command after array",
+			input: "*1\r\n$4\r\nPING\r\nPING\n",
+			want:  []string{"*1", "$4", "PING", "PING"},
+		},
+		{
+			name:  "bulk string too long",
+			input: "*1\r\n$11\r\nhello world\r\n",
+			want:  []string{"*1"},
+			err:   ErrInvalidBulkLen,
+		},
+		{
+			name:  "too many elements",
+			input: "*2000000\r\n",
+			err:   ErrInvalidMultiBulk,
+		},
+		{
+			name:  "bulk string not terminated",
+			input: "*1\r\n$3\r\nabcd\r\n",
+			want:  []string{"*1", "$3"},
+			err:   ErrBulkNotTerminated,
+		},
+		{
+			name:  "unexpected end of bulk string",
+			input: "*1\r\n$3\r\nab",
+			want:  []string{"*1", "$3"},
+			err:   ErrUnexpectedEndOfBulk,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// read byte by byte as well, so tokens span several reads
+			for _, r := range []io.Reader{strings.NewReader(tt.input), iotest.OneByteReader(strings.NewReader(tt.input))} {
+				got, err := readTokens(r, 10)
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("tokens = %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRESPReaderTooBigInline(t *testing.T) {
+	_, err := readTokens(strings.NewReader(strings.Repeat("a", MaxInlineLen+1)), 10)
+	if !errors.Is(err, ErrTooBigInline) {
+		t.Errorf("error = %v, want %v", err, ErrTooBigInline)
+	}
+}
+
+func TestRESPReaderNextBuffered(t *testing.T) {
+	rr := NewRESPReader(strings.NewReader("PING\r\nPING\r\n"), func() int { return 10 })
+
+	if _, found, err := rr.NextBuffered(); found || err != nil {
+		t.Fatalf("NextBuffered() before reading = %v, %v, want nothing", found, err)
+	}
+
+	if token, err := rr.Next(); err != nil || string(token) != "PING" {
+		t.Fatalf("Next() = %q, %v", token, err)
+	}
+	if rr.Buffered() != len("PING\r\n") {
+		t.Errorf("Buffered() = %d, want %d", rr.Buffered(), len("PING\r\n"))
+	}
+
+	token, found, err := rr.NextBuffered()
+	if err != nil || !found || string(token) != "PING" {
+		t.Errorf("NextBuffered() = %q, %v, %v", token, found, err)
+	}
+}
@@ -0,0 +1,237 @@
+package processor
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/acl"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+var errNoACLFile = spec.ErrorOf("ERR", "This Redis instance is not configured to use an ACL file. "+
+	"You may want to specify users via the ACL SETUSER command and then issue a CONFIG REWRITE "+
+	"(assuming you have a Redis configuration file set) in order to store users in the Redis configuration.")
+
+// aclContext returns the context of the commands of the client reported by ACL LOG.
+func (e *Executor) aclContext(id uint64) string {
+	if _, inMulti := e.transactions[id]; inMulti {
+		return acl.ContextMulti
+	}
+
+	return acl.ContextTopLevel
+}
+
+// checkPermissions returns why the user of the client is not allowed to run the command
+// with the arguments, or an empty string when it is allowed. Denials are logged to ACL LOG.
+// AUTH and HELLO are always allowed, so any client can authenticate.
+func (e *Executor) checkPermissions(client *Client, args []string, context string) string {
+	name := commandName(args)
+	if name == "auth" || name == "hello" {
+		return ""
+	}
+
+	// commands missing from the registry are denied, as no rule can allow them, and so are
+	// the commands of a deleted user, whose clients are closed but may still send commands meanwhile
+	denial := &acl.Denial{Reason: acl.ReasonCommand, Object: name}
+	cmd, registered := spec.LookupCommand(name)
+	if user, found := e.users.Get(client.User); found && registered {
+		denial = user.Check(cmd, args)
+	}
+	if denial == nil {
+		return ""
+	}
+
+	e.logDenial(client, denial, client.User, context)
+	return denial.Message(client.User)
+}
+
+// logDenial records the denial of the user to the client in ACL LOG and INFO stats.
+func (e *Executor) logDenial(client *Client, denial *acl.Denial, user, context string) {
+	e.aclDenied[denial.Reason]++
+	e.aclLog.Add(acl.LogEntry{
+		Reason:     denial.Reason,
+		Context:    context,
+		Object:     denial.Object,
+		Username:   user,
+		ClientInfo: e.clientInfoLine(client, time.Now()),
+	}, e.cfg.ACLLogMaxLen)
+}
+
+func (e *Executor) aclSetUser(cmd *spec.AclSetUserCommand) (spec.Data, error) {
+	if cmd.Username == acl.DefaultUser {
+		e.keepAuthenticated()
+	}
+
+	if err := e.users.SetUser(cmd.Username, cmd.Rules); err != nil {
+		return nil, spec.ErrorOf("ERR", "%s", err)
+	}
+
+	return spec.SimpleStringOf("OK"), nil
+}
+
+func (e *Executor) aclGetUser(name string) spec.Data {
+	user, found := e.users.Get(name)
+	if !found {
+		return spec.NullBulkString()
+	}
+
+	selectors := make([]spec.Data, 0, len(user.Selectors()))
+	for _, s := range user.Selectors() {
+		selectors = append(selectors, spec.MapOf(
+			spec.BulkStringOf("commands"), spec.BulkStringOf(s.DescribeCommands()),
+			spec.BulkStringOf("keys"), spec.BulkStringOf(s.DescribeKeys()),
+			spec.BulkStringOf("channels"), spec.BulkStringOf(s.DescribeChannels()),
+		))
+	}
+
+	root := user.Root()
+	return spec.MapOf(
+		spec.BulkStringOf("flags"), spec.BulkStringArrayOf(user.Flags()...),
+		spec.BulkStringOf("passwords"), spec.BulkStringArrayOf(user.Passwords()...),
+		spec.BulkStringOf("commands"), spec.BulkStringOf(root.DescribeCommands()),
+		spec.BulkStringOf("keys"), spec.BulkStringOf(root.DescribeKeys()),
+		spec.BulkStringOf("channels"), spec.BulkStringOf(root.DescribeChannels()),
+		spec.BulkStringOf("selectors"), spec.ArrayOf(selectors...),
+	)
+}
+
+// aclDelUser removes the users and closes the connections authenticated as them.
+func (e *Executor) aclDelUser(id uint64, names []string) (spec.Data, error) {
+	if slices.Contains(names, acl.DefaultUser) {
+		return nil, spec.ErrorOf("ERR", "The 'default' user cannot be removed")
+	}
+
+	deleted := 0
+	for _, name := range names {
+		if e.users.Delete(name) {
+			deleted++
+		}
+	}
+	e.killUnknownUsers(id)
+
+	return spec.IntegerOf(int64(deleted)), nil
+}
+
+// killUnknownUsers closes the connections authenticated as users which do not exist anymore.
+func (e *Executor) killUnknownUsers(id uint64) {
+	for _, client := range e.clients.All() {
+		if _, found := e.users.Get(client.User); !found {
+			e.killClient(id, client)
+		}
+	}
+}
+
+func (e *Executor) aclList() spec.Data {
+	names := e.users.Names()
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		user, _ := e.users.Get(name)
+		lines = append(lines, "user "+name+" "+user.Describe())
+	}
+
+	return spec.BulkStringArrayOf(lines...)
+}
+
+func (e *Executor) aclWhoAmI(id uint64) (spec.Data, error) {
+	client, found := e.clients.Get(id)
+	if !found {
+		return nil, spec.ErrorOf("ERR", "client is already closed")
+	}
+
+	return spec.BulkStringOf(client.User), nil
+}
+
+// aclCat returns the categories, or the commands of the category.
+func (e *Executor) aclCat(category *string) (spec.Data, error) {
+	if category == nil {
+		return spec.BulkStringArrayOf(spec.Categories...), nil
+	}
+
+	name := strings.ToLower(*category)
+	if !slices.Contains(spec.Categories, name) {
+		return nil, spec.ErrorOf("ERR", "Unknown category '%s'", *category)
+	}
+
+	var commands []string
+	for _, c := range spec.CommandSpecs() {
+		if c.HasCategory(name) {
+			commands = append(commands, c.Name)
+		}
+	}
+
+	return spec.BulkStringArrayOf(commands...), nil
+}
+
+func (e *Executor) aclLogEntries(count int) spec.Data {
+	now := time.Now()
+	entries := e.aclLog.Entries()
+	replies := make([]spec.Data, 0, min(count, len(entries)))
+	for _, entry := range entries[:min(count, len(entries))] {
+		replies = append(replies, spec.MapOf(
+			spec.BulkStringOf("count"), spec.IntegerOf(entry.Count),
+			spec.BulkStringOf("reason"), spec.BulkStringOf(entry.Reason.String()),
+			spec.BulkStringOf("context"), spec.BulkStringOf(entry.Context),
+			spec.BulkStringOf("object"), spec.BulkStringOf(entry.Object),
+			spec.BulkStringOf("username"), spec.BulkStringOf(entry.Username),
+			spec.BulkStringOf("age-seconds"), spec.DoubleOf(now.Sub(entry.Updated).Seconds()),
+			spec.BulkStringOf("client-info"), spec.BulkStringOf(entry.ClientInfo),
+			spec.BulkStringOf("entry-id"), spec.IntegerOf(entry.ID),
+			spec.BulkStringOf("timestamp-created"), spec.IntegerOf(entry.Created.UnixMilli()),
+			spec.BulkStringOf("timestamp-last-updated"), spec.IntegerOf(entry.Updated.UnixMilli()),
+		))
+	}
+
+	return spec.ArrayOf(replies...)
+}
+
+// aclDryRun checks whether the user is allowed to run the command, without running it or
+// logging a denial.
+func (e *Executor) aclDryRun(name string, args []string) (spec.Data, error) {
+	user, found := e.users.Get(name)
+	if !found {
+		return nil, spec.ErrorOf("ERR", "User '%s' not found", name)
+	}
+
+	cmd, found := spec.LookupCommand(commandName(args))
+	if !found {
+		return nil, spec.ErrorOf("ERR", "Command '%s' not found", args[0])
+	}
+
+	if denial := user.Check(cmd, args); denial != nil {
+		return spec.BulkStringOf(denial.Message(name)), nil
+	}
+
+	return spec.SimpleStringOf("OK"), nil
+}
+
+func (e *Executor) aclSave() (spec.Data, error) {
+	if e.cfg.ACLFile == "" {
+		return nil, errNoACLFile
+	}
+
+	if err := e.users.Save(e.cfg.ACLFile); err != nil {
+		slog.Error("failed to save ACL file", slog.String("file", e.cfg.ACLFile), slog.Any("error", err))
+		return nil, spec.ErrorOf("ERR", "There was an error trying to save the ACLs. "+
+			"Please check the server logs for more information")
+	}
+
+	return spec.SimpleStringOf("OK"), nil
+}
+
+// aclLoad replaces the users with the ones of the ACL file, closing the connections
+// authenticated as users which are not in the file.
+func (e *Executor) aclLoad(id uint64) (spec.Data, error) {
+	if e.cfg.ACLFile == "" {
+		return nil, errNoACLFile
+	}
+
+	e.keepAuthenticated()
+	if err := e.users.Load(e.cfg.ACLFile); err != nil {
+		return nil, spec.ErrorOf("ERR", "%s", err)
+	}
+	e.killUnknownUsers(id)
+
+	return spec.SimpleStringOf("OK"), nil
+}
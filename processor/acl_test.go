@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// TestParsedCommandsRegistered checks that every command accepted by the parser has a spec in
+// the registry, as commands missing from it are denied to every user.
+func TestParsedCommandsRegistered(t *testing.T) {
+	lines := []string{
+		"PING",
+		"ECHO hello",
+		"GET key",
+		"SET key value",
+		"SUBSCRIBE channel",
+		"PSUBSCRIBE pattern*",
+		"UNSUBSCRIBE",
+		"PUNSUBSCRIBE",
+		"PUBLISH channel message",
+		"MULTI",
+		"EXEC",
+		"DISCARD",
+		"MONITOR",
+		"WATCH key",
+		"UNWATCH",
+		"FLUSHDB",
+		"EVAL return 1 0",
+		"EVALSHA e0e1f9fabfc9d4800c877a703b823ac0578ff8db 0",
+		"FCALL f 0",
+		"FCALL_RO f 0",
+		"FUNCTION LOAD code",
+		"FUNCTION LIST",
+		"FUNCTION DELETE lib",
+		"FUNCTION DUMP",
+		"FUNCTION RESTORE payload",
+		"FUNCTION FLUSH",
+		"FUNCTION KILL",
+		"FUNCTION STATS",
+		"SCRIPT LOAD code",
+		"SCRIPT EXISTS e0e1f9fabfc9d4800c877a703b823ac0578ff8db",
+		"SCRIPT FLUSH",
+		"SCRIPT KILL",
+		"AUTH password",
+		"HELLO",
+		"CLIENT ID",
+		"CLIENT INFO",
+		"CLIENT GETNAME",
+		"CLIENT SETNAME name",
+		"CLIENT UNPAUSE",
+		"CLIENT GETREDIR",
+		"CLIENT TRACKINGINFO",
+		"CLIENT LIST",
+		"CLIENT KILL 127.0.0.1:6379",
+		"CLIENT PAUSE 100",
+		"CLIENT NO-EVICT ON",
+		"CLIENT NO-TOUCH ON",
+		"CLIENT REPLY ON",
+		"CLIENT TRACKING ON",
+		"CLIENT CACHING YES",
+		"INFO",
+		"CONFIG GET maxmemory",
+		"CONFIG SET maxmemory 0",
+		"CONFIG REWRITE",
+		"CONFIG RESETSTAT",
+		"LATENCY LATEST",
+		"LATENCY DOCTOR",
+		"LATENCY HISTORY command",
+		"LATENCY GRAPH command",
+		"LATENCY RESET",
+		"LATENCY HISTOGRAM",
+		"ACL SETUSER user",
+		"ACL GETUSER user",
+		"ACL DELUSER user",
+		"ACL LIST",
+		"ACL USERS",
+		"ACL WHOAMI",
+		"ACL SAVE",
+		"ACL LOAD",
+		"ACL CAT",
+		"ACL LOG",
+		"ACL DRYRUN user GET key",
+		"SLOWLOG GET",
+		"SLOWLOG LEN",
+		"SLOWLOG RESET",
+		"MEMORY DOCTOR",
+		"MEMORY STATS",
+		"MEMORY PURGE",
+		"MEMORY USAGE key",
+		"OBJECT ENCODING key",
+		"OBJECT REFCOUNT key",
+		"OBJECT IDLETIME key",
+		"OBJECT FREQ key",
+	}
+
+	parser := NewParser()
+	for _, line := range lines {
+		args := strings.Fields(line)
+		if _, err := parser.Parse(spec.BulkStringArrayOf(args...)); err != nil {
+			t.Errorf("Parse(%q) failed: %v", line, err)
+			continue
+		}
+
+		if _, found := spec.LookupCommand(commandName(args)); !found {
+			t.Errorf("LookupCommand(%q) not found for %q", commandName(args), line)
+		}
+	}
+}
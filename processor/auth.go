@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/acl"
+	"github.com/codecrafters-io/redis-starter-go/config"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// redacted replaces the arguments with secrets in the slow log and in MONITOR.
+const redacted = "(redacted)"
+
+var (
+	errNoAuth    = spec.ErrorOf("NOAUTH", "Authentication required.")
+	errWrongPass = spec.ErrorOf("WRONGPASS", "invalid username-password pair or user is disabled.")
+)
+
+// passwordRequired reports whether clients must authenticate to run commands, which they
+// don't while the default user is enabled without a password.
+func (e *Executor) passwordRequired() bool {
+	user, _ := e.users.Get(acl.DefaultUser)
+	return !user.Enabled() || !user.NoPass()
+}
+
+// authRequired reports whether the command is rejected until the client authenticates.
+// AUTH and HELLO are allowed, so the client can authenticate. A client closed already is
+// not authenticated.
+func (e *Executor) authRequired(client *Client, cmd spec.Command) bool {
+	if !e.passwordRequired() || (client != nil && client.Authenticated) {
+		return false
+	}
+
+	switch cmd.(type) {
+	case *spec.AuthCommand, *spec.HelloCommand:
+		return false
+	default:
+		return true
+	}
+}
+
+// keepAuthenticated marks the clients authenticated before the default user is changed
+// while no password is required, so they stay connected as the default user as in Redis.
+func (e *Executor) keepAuthenticated() {
+	if e.passwordRequired() {
+		return
+	}
+
+	for _, client := range e.clients.All() {
+		client.Authenticated = true
+	}
+}
+
+// setRequirePass makes the password the only one of the default user, or removes its
+// passwords when it is empty.
+func (e *Executor) setRequirePass(password string) {
+	rules := []string{"resetpass", "nopass"}
+	if password != "" {
+		rules[1] = ">" + password
+	}
+
+	e.keepAuthenticated()
+	if err := e.users.SetUser(acl.DefaultUser, rules); err != nil {
+		slog.Error("failed to set the password of the default user", slog.Any("error", err))
+	}
+}
+
+func (e *Executor) auth(id uint64, username *string, password string) (spec.Data, error) {
+	if username == nil && !e.passwordRequired() {
+		return nil, spec.ErrorOf("ERR", "AUTH <password> called without any password configured for "+
+			"the default user. Are you sure your configuration is correct?")
+	}
+
+	client, found := e.clients.Get(id)
+	if !found {
+		return nil, spec.ErrorOf("ERR", "client is already closed")
+	}
+
+	user := acl.DefaultUser
+	if username != nil {
+		user = *username
+	}
+	if err := e.authenticate(client, user, password); err != nil {
+		return nil, err
+	}
+
+	return spec.SimpleStringOf("OK"), nil
+}
+
+// authenticate authenticates the client as the user when the password matches. Failures are
+// logged to ACL LOG.
+func (e *Executor) authenticate(client *Client, user, password string) error {
+	if !e.users.Authenticate(user, password) {
+		e.logDenial(client, &acl.Denial{Reason: acl.ReasonAuth, Object: "AUTH"}, user, e.aclContext(client.ID))
+		return errWrongPass
+	}
+
+	client.Authenticated = true
+	client.User = user
+	return nil
+}
+
+// redactArgs returns the arguments with the secrets replaced, such as the passwords of AUTH,
+// HELLO AUTH and CONFIG SET requirepass, and the rules of ACL SETUSER which may have
+// passwords. The arguments are returned as they are when they have no secret.
+func redactArgs(args []string) []string {
+	var secrets []int
+	switch commandName(args) {
+	case "auth":
+		for i := 1; i < len(args); i++ {
+			secrets = append(secrets, i)
+		}
+
+	case "hello":
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "AUTH":
+				for j := i + 1; j <= i+2 && j < len(args); j++ {
+					secrets = append(secrets, j)
+				}
+				i += 2
+			case "SETNAME":
+				i++
+			}
+		}
+
+	case "acl|setuser":
+		for i := 3; i < len(args); i++ {
+			secrets = append(secrets, i)
+		}
+
+	case "config|set":
+		for i := 2; i+1 < len(args); i += 2 {
+			if p, found := config.Lookup(args[i]); found && p.Sensitive {
+				secrets = append(secrets, i+1)
+			}
+		}
+	}
+
+	if len(secrets) == 0 {
+		return args
+	}
+
+	redactedArgs := make([]string, len(args))
+	copy(redactedArgs, args)
+	for _, i := range secrets {
+		redactedArgs[i] = redacted
+	}
+
+	return redactedArgs
+}
@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"bytes"
+	"cmp"
+	"net"
+	"slices"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/acl"
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// Client is an accepted connection, identified by the same ID until it is closed.
+type Client struct {
+	ID        uint64
+	Conn      net.Conn
+	CreatedAt time.Time
+
+	// the fields below are only accessed from the event loop
+
+	Protocol        int    // negotiated with HELLO
+	Name            string // set with HELLO SETNAME or CLIENT SETNAME
+	User            string // the user the client is authenticated as, or would be
+	Authenticated   bool   // with AUTH or HELLO AUTH, or connected while no password was required
+	LastInteraction time.Time
+	LastCommand     string // e.g. "get" or "client|list"
+
+	NoEvict bool
+	NoTouch bool // commands do not update the access time of keys
+
+	replyOff           bool // CLIENT REPLY OFF
+	replySkipNext      bool // CLIENT REPLY SKIP, so the reply of the next command is skipped
+	replySkip          bool
+	closeAfterReply    bool
+	monitor            bool            // MONITOR, so every command processed is fed to the client
+	closeASAP          bool            // closed for reaching the output buffer limit
+	softLimitReachedAt time.Time       // zero while the output buffer is under the soft limit
+	tracking           *clientTracking // nil when CLIENT TRACKING is off
+
+	reader  *pkg.RESPReader
+	output  *bytes.Buffer // replies not written yet
+	writing *bytes.Buffer // replies being written, nil when no write is in progress
+	spare   *bytes.Buffer
+}
+
+func newClient(id uint64, conn net.Conn) *Client {
+	now := time.Now()
+	return &Client{
+		ID:              id,
+		Conn:            conn,
+		CreatedAt:       now,
+		Protocol:        spec.RESP2,
+		User:            acl.DefaultUser,
+		LastInteraction: now,
+		output:          new(bytes.Buffer),
+	}
+}
+
+// Addr returns the remote address of the client.
+func (c *Client) Addr() string {
+	return c.Conn.RemoteAddr().String()
+}
+
+// LocalAddr returns the address of the server the client is connected to.
+func (c *Client) LocalAddr() string {
+	return c.Conn.LocalAddr().String()
+}
+
+// FD returns the file descriptor of the connection, or -1 when it is unavailable.
+func (c *Client) FD() int {
+	sc, isSyscallConn := c.Conn.(syscall.Conn)
+	if !isSyscallConn {
+		return -1
+	}
+
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return -1
+	}
+
+	fd := -1
+	_ = rc.Control(func(sysfd uintptr) { fd = int(sysfd) })
+	return fd
+}
+
+// QueryBufferLen returns the bytes read from the client but not handled yet.
+func (c *Client) QueryBufferLen() int {
+	if c.reader == nil {
+		return 0
+	}
+
+	return c.reader.Buffered()
+}
+
+// OutputBufferLen returns the bytes of replies not written to the client yet.
+func (c *Client) OutputBufferLen() int {
+	n := c.output.Len()
+	if c.writing != nil {
+		n += c.writing.Len()
+	}
+
+	return n
+}
+
+// Clients is the registry of connected clients. Clients are added when accepted and
+// removed when closed by the TCP processor, while other components look them up by ID.
+type Clients struct {
+	clients *pkg.ConcurrentMap[uint64, *Client] // clients are added from the accepting goroutine
+	count   atomic.Int64
+}
+
+func NewClients() *Clients {
+	return &Clients{
+		clients: pkg.NewConcurrentMap[uint64, *Client](),
+	}
+}
+
+func (c *Clients) Get(id uint64) (*Client, bool) {
+	return c.clients.Load(id)
+}
+
+// Protocol returns the protocol version of the client, RESP2 if it is already closed.
+func (c *Clients) Protocol(id uint64) int {
+	if client, found := c.clients.Load(id); found {
+		return client.Protocol
+	}
+
+	return spec.RESP2
+}
+
+// Len returns the number of connected clients.
+func (c *Clients) Len() int {
+	return int(c.count.Load())
+}
+
+// All returns the connected clients ordered by ID.
+func (c *Clients) All() []*Client {
+	all := make([]*Client, 0)
+	c.clients.Range(func(_ uint64, client *Client) bool {
+		all = append(all, client)
+		return true
+	})
+
+	slices.SortFunc(all, func(a, b *Client) int { return cmp.Compare(a.ID, b.ID) })
+	return all
+}
+
+func (c *Clients) add(client *Client) {
+	c.clients.Store(client.ID, client)
+	c.count.Add(1)
+}
+
+func (c *Clients) remove(id uint64) (*Client, bool) {
+	client, loaded := c.clients.LoadAndDelete(id)
+	if loaded {
+		c.count.Add(-1)
+	}
+
+	return client, loaded
+}
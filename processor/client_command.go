@@ -0,0 +1,357 @@
+package processor
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// clientPause is the state of CLIENT PAUSE, which postpones commands until it ends.
+type clientPause struct {
+	end      time.Time
+	write    bool // only the commands which may write are paused
+	deferred []*event.ExecuteEvent
+}
+
+// containerCommands have subcommands, which are reported along with the command (e.g. "client|list").
+var containerCommands = []string{"client", "config", "script", "function", "object", "memory", "slowlog", "latency", "acl"}
+
+// commandName returns the name of the command reported by CLIENT LIST.
+func commandName(args []string) string {
+	if len(args) == 0 {
+		return "NULL"
+	}
+
+	name := strings.ToLower(args[0])
+	if len(args) > 1 && slices.Contains(containerCommands, name) {
+		return name + "|" + strings.ToLower(args[1])
+	}
+
+	return name
+}
+
+func (e *Executor) clientSetName(id uint64, name string) (spec.Data, error) {
+	if !validClientName(name) {
+		return nil, spec.ErrorOf("ERR", "Client names cannot contain spaces, newlines or special characters.")
+	}
+
+	if client, found := e.clients.Get(id); found {
+		client.Name = name
+	}
+
+	return spec.SimpleStringOf("OK"), nil
+}
+
+func (e *Executor) clientGetName(id uint64) spec.Data {
+	client, found := e.clients.Get(id)
+	if !found || client.Name == "" {
+		return spec.NullBulkString()
+	}
+
+	return spec.BulkStringOf(client.Name)
+}
+
+func (e *Executor) clientInfo(id uint64) (spec.Data, error) {
+	client, found := e.clients.Get(id)
+	if !found {
+		return nil, spec.ErrorOf("ERR", "client is already closed")
+	}
+
+	return spec.VerbatimStringOf("txt", e.clientInfoLine(client, time.Now())+"\n"), nil
+}
+
+func (e *Executor) clientList(cmd *spec.ClientListCommand) spec.Data {
+	now := time.Now()
+
+	var sb strings.Builder
+	for _, client := range e.clients.All() {
+		if cmd.Type != "" && e.clientType(client) != cmd.Type {
+			continue
+		}
+		if len(cmd.IDs) > 0 && !slices.Contains(cmd.IDs, client.ID) {
+			continue
+		}
+
+		sb.WriteString(e.clientInfoLine(client, now))
+		sb.WriteByte('\n')
+	}
+
+	return spec.VerbatimStringOf("txt", sb.String())
+}
+
+// clientInfoLine formats the client as a line of CLIENT LIST.
+func (e *Executor) clientInfoLine(client *Client, now time.Time) string {
+	multi := -1
+	if tx, inMulti := e.transactions[client.ID]; inMulti {
+		multi = len(tx.queue)
+	}
+
+	watched := 0
+	if w, watching := e.watches[client.ID]; watching {
+		watched = len(w.keys)
+	}
+
+	events := "r"
+	if client.writing != nil {
+		events += "w"
+	}
+
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"id", strconv.FormatUint(client.ID, 10)},
+		{"addr", client.Addr()},
+		{"laddr", client.LocalAddr()},
+		{"fd", strconv.Itoa(client.FD())},
+		{"name", client.Name},
+		{"age", strconv.FormatInt(int64(now.Sub(client.CreatedAt).Seconds()), 10)},
+		{"idle", strconv.FormatInt(int64(now.Sub(client.LastInteraction).Seconds()), 10)},
+		{"flags", e.clientFlags(client)},
+		{"db", "0"},
+		{"sub", strconv.Itoa(e.pubsub.ChannelCount(client.ID))},
+		{"psub", strconv.Itoa(e.pubsub.PatternCount(client.ID))},
+		{"ssub", "0"},
+		{"multi", strconv.Itoa(multi)},
+		{"watch", strconv.Itoa(watched)},
+		{"qbuf", strconv.Itoa(client.QueryBufferLen())},
+		{"obl", strconv.Itoa(client.output.Len())},
+		{"omem", strconv.Itoa(client.OutputBufferLen())},
+		{"events", events},
+		{"cmd", client.LastCommand},
+		{"user", client.User},
+		{"redir", strconv.FormatInt(trackingRedirect(client), 10)},
+		{"resp", strconv.Itoa(client.Protocol)},
+	}
+
+	var sb strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(f.name)
+		sb.WriteByte('=')
+		sb.WriteString(f.value)
+	}
+
+	return sb.String()
+}
+
+// clientFlags formats the flags of the client in the same order as Redis.
+func (e *Executor) clientFlags(client *Client) string {
+	var sb strings.Builder
+	if client.monitor {
+		sb.WriteByte('O')
+	}
+	if e.pubsub.SubscriptionCount(client.ID) > 0 {
+		sb.WriteByte('P')
+	}
+	if _, inMulti := e.transactions[client.ID]; inMulti {
+		sb.WriteByte('x')
+	}
+	if t := client.tracking; t != nil {
+		sb.WriteByte('t')
+		if t.brokenRedirect {
+			sb.WriteByte('R')
+		}
+		if t.bcast {
+			sb.WriteByte('B')
+		}
+	}
+	if w, watching := e.watches[client.ID]; watching && w.dirty {
+		sb.WriteByte('d')
+	}
+	if client.closeAfterReply {
+		sb.WriteByte('c')
+	}
+	if client.closeASAP {
+		sb.WriteByte('A')
+	}
+	if client.NoEvict {
+		sb.WriteByte('e')
+	}
+	if client.NoTouch {
+		sb.WriteByte('T')
+	}
+
+	if sb.Len() == 0 {
+		return "N"
+	}
+	return sb.String()
+}
+
+// clientType returns the type used to filter clients: there are no replication links, so
+// clients are either pubsub or normal.
+func (e *Executor) clientType(client *Client) string {
+	if e.pubsub.SubscriptionCount(client.ID) > 0 {
+		return "pubsub"
+	}
+
+	return "normal"
+}
+
+func (e *Executor) clientKill(id uint64, cmd *spec.ClientKillCommand) (spec.Data, error) {
+	now := time.Now()
+
+	killed := 0
+	for _, client := range e.clients.All() {
+		switch {
+		case cmd.ID != nil && client.ID != *cmd.ID,
+			cmd.Addr != "" && client.Addr() != cmd.Addr,
+			cmd.LAddr != "" && client.LocalAddr() != cmd.LAddr,
+			cmd.User != "" && client.User != cmd.User,
+			cmd.Type != "" && e.clientType(client) != cmd.Type,
+			cmd.MaxAge > 0 && int64(now.Sub(client.CreatedAt).Seconds()) <= cmd.MaxAge,
+			cmd.SkipMe && client.ID == id:
+			continue
+		}
+
+		e.killClient(id, client)
+		killed++
+	}
+
+	if cmd.Legacy {
+		if killed == 0 {
+			return nil, spec.ErrorOf("ERR", "No such client")
+		}
+		return spec.SimpleStringOf("OK"), nil
+	}
+
+	return spec.IntegerOf(int64(killed)), nil
+}
+
+// killClient closes the connection of the client, which is cleaned up once the reading
+// goroutine sees it closed. The calling client is closed after its reply is written.
+func (e *Executor) killClient(id uint64, client *Client) {
+	if client.ID == id {
+		client.closeAfterReply = true
+		return
+	}
+
+	_ = client.Conn.Close()
+}
+
+func (e *Executor) clientPause(cmd *spec.ClientPauseCommand) spec.Data {
+	end := time.Now().Add(cmd.Timeout)
+	if e.pause == nil {
+		e.pause = &clientPause{end: end, write: cmd.Write}
+	}
+
+	// like Redis, the more restrictive mode is kept and the pause never ends earlier than
+	// already set, so a WRITE pause does not release the reads postponed by an ALL one
+	e.pause.write = e.pause.write && cmd.Write
+	if end.After(e.pause.end) {
+		e.pause.end = end
+	}
+
+	return spec.SimpleStringOf("OK")
+}
+
+// unpause ends the pause and executes the commands postponed by it in order.
+func (e *Executor) unpause() {
+	if e.pause == nil {
+		return
+	}
+
+	deferred := e.pause.deferred
+	e.pause = nil
+	for _, ev := range deferred {
+		e.handle(ev)
+	}
+}
+
+// postpone keeps the command while clients are paused. Once a command of a client is
+// postponed, the following ones are postponed as well to keep their order.
+func (e *Executor) postpone(ev *event.ExecuteEvent) bool {
+	if e.pause == nil {
+		return false
+	}
+
+	if !time.Now().Before(e.pause.end) {
+		e.unpause()
+		return false
+	}
+
+	if !e.postponed(ev.ID()) && !e.pausedCommand(ev) {
+		return false
+	}
+
+	e.pause.deferred = append(e.pause.deferred, ev)
+	return true
+}
+
+// pausedCommand reports whether the command is paused by the current pause mode.
+func (e *Executor) pausedCommand(ev *event.ExecuteEvent) bool {
+	// otherwise nobody could end a pause of all commands before the timeout
+	if _, isUnpause := ev.Command.(*spec.ClientUnpauseCommand); isUnpause {
+		return false
+	}
+
+	if !e.pause.write {
+		return true
+	}
+
+	// commands are only queued in a transaction, so EXEC is paused instead
+	tx, inMulti := e.transactions[ev.ID()]
+	if _, isExec := ev.Command.(*spec.ExecCommand); isExec && inMulti {
+		return slices.ContainsFunc(tx.queue, func(queued queuedCommand) bool {
+			return mayWrite(queued.cmd)
+		})
+	}
+	if inMulti || ev.Err != nil {
+		return false
+	}
+
+	return mayWrite(ev.Command)
+}
+
+// mayWrite reports whether the command may modify the dataset or propagate, which is
+// paused by CLIENT PAUSE WRITE.
+func mayWrite(cmd spec.Command) bool {
+	switch cmd.(type) {
+	case *spec.EvalCommand, *spec.EvalShaCommand, *spec.PublishCommand:
+		return true
+	case *spec.FCallCommand:
+		fcallCmd := cmd.(*spec.FCallCommand)
+		return !fcallCmd.ReadOnly
+	default:
+		return isWriteCommand(cmd)
+	}
+}
+
+// PauseCron ends the client pause once its timeout is reached.
+func (e *Executor) PauseCron(now time.Time, _ func(event.Event)) {
+	if e.pause != nil && !now.Before(e.pause.end) {
+		e.unpause()
+	}
+}
+
+func (e *Executor) clientReply(id uint64, mode spec.ClientReplyMode) (spec.Data, error) {
+	client, found := e.clients.Get(id)
+	if !found {
+		return nil, spec.ErrorOf("ERR", "client is already closed")
+	}
+
+	switch mode {
+	case spec.ClientReplyOn:
+		client.replyOff = false
+		client.replySkip = false
+		return spec.SimpleStringOf("OK"), nil
+	case spec.ClientReplyOff:
+		client.replyOff = true
+	case spec.ClientReplySkip:
+		if !client.replyOff {
+			client.replySkipNext = true
+		}
+	default:
+		return nil, fmt.Errorf("unknown reply mode %s", mode)
+	}
+
+	// OFF and SKIP are not replied
+	return nil, nil
+}
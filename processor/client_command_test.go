@@ -0,0 +1,171 @@
+package processor
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestClientPause(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []command
+		replies  [][]string // replies to the postponed commands of each client on unpause
+	}{
+		{
+			name: "write",
+			commands: []command{
+				{args: []string{"CLIENT", "PAUSE", "10000", "WRITE"}, want: "+OK\r\n"},
+				{args: []string{"GET", "k"}, want: "$-1\r\n"},
+				{args: []string{"SET", "k", "v"}, want: ""},
+				{args: []string{"PUBLISH", "ch", "m"}, want: ""},
+				// the commands following a postponed one are postponed to keep their order
+				{args: []string{"GET", "k"}, want: ""},
+				{client: 1, args: []string{"GET", "k"}, want: "$-1\r\n"},
+			},
+			replies: [][]string{{"+OK\r\n", ":0\r\n", "$1\r\nv\r\n"}, nil},
+		},
+		{
+			name: "all",
+			commands: []command{
+				{args: []string{"CLIENT", "PAUSE", "10000"}, want: "+OK\r\n"},
+				{args: []string{"GET", "k"}, want: ""},
+				{client: 1, args: []string{"PING"}, want: ""},
+			},
+			replies: [][]string{{"$-1\r\n"}, {"+PONG\r\n"}},
+		},
+		{
+			// CLIENT PAUSE is paused by an ALL pause, unless run in the same transaction
+			name: "write after all",
+			commands: []command{
+				{client: 2, args: []string{"MULTI"}, want: "+OK\r\n"},
+				{client: 2, args: []string{"CLIENT", "PAUSE", "10000", "ALL"}, want: "+QUEUED\r\n"},
+				{client: 2, args: []string{"CLIENT", "PAUSE", "10000", "WRITE"}, want: "+QUEUED\r\n"},
+				{client: 2, args: []string{"EXEC"}, want: "*2\r\n+OK\r\n+OK\r\n"},
+				{args: []string{"GET", "k"}, want: ""},
+			},
+			replies: [][]string{{"$-1\r\n"}, nil},
+		},
+		{
+			name: "all after write",
+			commands: []command{
+				{args: []string{"CLIENT", "PAUSE", "10000", "WRITE"}, want: "+OK\r\n"},
+				{args: []string{"CLIENT", "PAUSE", "10000", "ALL"}, want: "+OK\r\n"},
+				{args: []string{"GET", "k"}, want: ""},
+			},
+			replies: [][]string{{"$-1\r\n"}, nil},
+		},
+		{
+			name: "transaction with a write",
+			commands: []command{
+				{args: []string{"CLIENT", "PAUSE", "10000", "WRITE"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "v"}, want: "+QUEUED\r\n"},
+				{args: []string{"EXEC"}, want: ""},
+				{client: 1, args: []string{"MULTI"}, want: "+OK\r\n"},
+				{client: 1, args: []string{"GET", "k"}, want: "+QUEUED\r\n"},
+				{client: 1, args: []string{"EXEC"}, want: "*1\r\n$-1\r\n"},
+			},
+			replies: [][]string{{"*1\r\n+OK\r\n"}, nil},
+		},
+		{
+			// the postponed commands of every client run in the order they were received
+			name: "order",
+			commands: []command{
+				{client: 2, args: []string{"CLIENT", "PAUSE", "10000", "WRITE"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "1"}, want: ""},
+				{client: 1, args: []string{"SET", "k", "2"}, want: ""},
+				{args: []string{"GET", "k"}, want: ""},
+				{client: 1, args: []string{"GET", "k"}, want: ""},
+				{args: []string{"SET", "k", "3"}, want: ""},
+			},
+			replies: [][]string{{"+OK\r\n", "$1\r\n2\r\n", "+OK\r\n"}, {"+OK\r\n", "$1\r\n2\r\n"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			ids := s.run(3, tt.commands)
+
+			// CLIENT UNPAUSE itself is never paused
+			if got, want := s.do(ids[2], "CLIENT", "UNPAUSE"), "+OK\r\n"; got != want {
+				t.Fatalf("CLIENT UNPAUSE = %q, want %q", got, want)
+			}
+			for i, want := range tt.replies {
+				if got := s.take(ids[i]); !slices.Equal(got, want) {
+					t.Errorf("client %d: replies on unpause = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestClientPauseTimeout(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.run(1, []command{
+		{args: []string{"CLIENT", "PAUSE", "10"}, want: "+OK\r\n"},
+		{args: []string{"GET", "k"}, want: ""},
+		{args: []string{"SET", "k", "v"}, want: ""},
+	})
+
+	s.executor.PauseCron(time.Now(), s.push)
+	if got := s.take(ids[0]); got != nil {
+		t.Errorf("replies before the timeout = %q, want none", got)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	s.executor.PauseCron(time.Now(), s.push)
+	if got, want := s.take(ids[0]), []string{"$-1\r\n", "+OK\r\n"}; !slices.Equal(got, want) {
+		t.Errorf("replies after the timeout = %q, want %q", got, want)
+	}
+}
+
+func TestClientKill(t *testing.T) {
+	// clients, with the IDs 1 to 4: 0 runs CLIENT KILL, 1 is a normal client, 2 a pubsub one,
+	// 3 is authenticated as the user app
+	tests := []struct {
+		args   []string
+		want   string
+		killed []int
+	}{
+		{args: []string{"ID", "2"}, want: ":1\r\n", killed: []int{1}},
+		{args: []string{"ID", "1"}, want: ":0\r\n"},
+		{args: []string{"ID", "1", "SKIPME", "no"}, want: ":1\r\n", killed: []int{0}},
+		{args: []string{"ID", "99"}, want: ":0\r\n"},
+		{args: []string{"TYPE", "pubsub"}, want: ":1\r\n", killed: []int{2}},
+		{args: []string{"TYPE", "normal"}, want: ":2\r\n", killed: []int{1, 3}},
+		{args: []string{"TYPE", "normal", "SKIPME", "no"}, want: ":3\r\n", killed: []int{0, 1, 3}},
+		{args: []string{"USER", "app"}, want: ":1\r\n", killed: []int{3}},
+		{args: []string{"USER", "default"}, want: ":2\r\n", killed: []int{1, 2}},
+		{args: []string{"USER", "app", "TYPE", "pubsub"}, want: ":0\r\n"},
+		{args: []string{"USER", "default", "TYPE", "normal", "SKIPME", "no"}, want: ":2\r\n", killed: []int{0, 1}},
+		{args: []string{"127.0.0.1:1"}, want: "-ERR No such client\r\n"},
+	}
+
+	for _, tt := range tests {
+		s := newTestServer(t)
+		ids := s.run(4, []command{
+			{args: []string{"ACL", "SETUSER", "app", "on", ">secret", "+@all", "~*", "&*"}, want: "+OK\r\n"},
+			{client: 2, args: []string{"SUBSCRIBE", "ch"}, want: "*3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n"},
+			{client: 3, args: []string{"AUTH", "app", "secret"}, want: "+OK\r\n"},
+		})
+
+		args := append([]string{"CLIENT", "KILL"}, tt.args...)
+		if got := s.do(ids[0], args...); got != tt.want {
+			t.Errorf("%q = %q, want %q", args, got, tt.want)
+		}
+
+		var killed []int
+		for i, id := range ids {
+			client, _ := s.clients.Get(id)
+			// the calling client is closed once replied, the others at once
+			if client.closeAfterReply || client.Conn.SetDeadline(time.Time{}) != nil {
+				killed = append(killed, i)
+			}
+		}
+		if !slices.Equal(killed, tt.killed) {
+			t.Errorf("%q killed clients %v, want %v", args, killed, tt.killed)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/id"
+)
+
+var _ event.Pusher = (*Cron)(nil)
+
+// CronJob is a background task run on the event loop at every cron tick.
+type CronJob func(now time.Time, push func(event.Event))
+
+// Cron pushes a CronEvent periodically, so background tasks which need the state
+// owned by the event loop (e.g. ending a client pause) run on the loop itself.
+type Cron struct {
+	d        time.Duration
+	idissuer id.IDIssuer[uint64]
+	jobs     []CronJob
+
+	t              *time.Ticker
+	pushStopSignal chan struct{}
+}
+
+func NewCron(duration time.Duration, idIssuer id.IDIssuer[uint64]) *Cron {
+	return &Cron{
+		d:        duration,
+		idissuer: idIssuer,
+	}
+}
+
+// AddJob registers a job run at every tick. It must be called before the loop starts.
+func (c *Cron) AddJob(job CronJob) {
+	c.jobs = append(c.jobs, job)
+}
+
+// SetInterval changes the interval of the ticks, taking effect on the next tick.
+func (c *Cron) SetInterval(d time.Duration) {
+	c.d = d
+	if c.t != nil {
+		c.t.Reset(d)
+	}
+}
+
+func (c *Cron) InitPushing(push func(event.Event)) {
+	c.pushStopSignal = make(chan struct{})
+	c.t = time.NewTicker(c.d)
+	go c.loop(push)
+}
+
+func (c *Cron) ShutdownPushing() {
+	if c.pushStopSignal != nil {
+		close(c.pushStopSignal)
+	}
+
+	if c.t != nil {
+		c.t.Stop()
+	}
+}
+
+func (c *Cron) loop(push func(event.Event)) {
+	for {
+		select {
+		case now := <-c.t.C:
+			push(&event.CronEvent{
+				ID_:  c.idissuer.Issue(),
+				Time: now,
+			})
+		case <-c.pushStopSignal:
+			return
+		}
+	}
+}
+
+func (c *Cron) CronEventHandler() *cronEventHandler {
+	return &cronEventHandler{
+		c: c,
+	}
+}
+
+type cronEventHandler struct {
+	c *Cron
+}
+
+func (h *cronEventHandler) Handle(e event.Event, push func(event.Event)) error {
+	cronEvent, ok := e.(*event.CronEvent)
+	if !ok {
+		return event.ErrInvalidEventType
+	}
+
+	for _, job := range h.c.jobs {
+		job(cronEvent.Time, push)
+	}
+	return nil
+}
+
+func (h *cronEventHandler) Target() event.Type {
+	return event.CronEventType
+}
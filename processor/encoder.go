@@ -0,0 +1,236 @@
+package processor
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+var errorLineReplacer = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// RESPWriter is the destination of an Encoder, satisfied by *bufio.Writer and *bytes.Buffer.
+type RESPWriter interface {
+	io.Writer
+	io.ByteWriter
+	io.StringWriter
+}
+
+// Encoder streams replies into a writer without building them in memory first.
+// RESP3 types are downgraded to their RESP2 counterparts for RESP2 (e.g. maps into flat arrays).
+type Encoder struct {
+	w        RESPWriter
+	protocol int
+	err      error
+}
+
+func NewEncoder(w RESPWriter, protocol int) *Encoder {
+	return &Encoder{
+		w:        w,
+		protocol: protocol,
+	}
+}
+
+// Encode writes the data and returns the first error from the writer.
+func (enc *Encoder) Encode(data spec.Data) error {
+	enc.encode(data)
+	return enc.err
+}
+
+func (enc *Encoder) encode(data spec.Data) {
+	switch data.(type) {
+	case *spec.SimpleStringData:
+		str, _ := spec.Value[string](data)
+		enc.writeLine(SimpleStringPrefix, str)
+
+	case *spec.SimpleErrorData:
+		err, _ := spec.Value[error](data)
+		// error messages may come from anywhere (e.g. script errors), but must stay in a single line
+		enc.writeLine(SimpleErrorPrefix, errorLineReplacer.Replace(err.Error()))
+
+	case *spec.IntegerData:
+		i, _ := spec.Value[int64](data)
+		enc.writeLine(IntegerPrefix, strconv.FormatInt(i, 10))
+
+	case *spec.BulkStringData:
+		bulkStringData := data.(*spec.BulkStringData)
+		if bulkStringData.IsNull() {
+			enc.encodeNull(BulkStringPrefix)
+			return
+		}
+
+		enc.writeBlob(BulkStringPrefix, bulkStringData.S)
+
+	case *spec.ArrayData:
+		arrayData := data.(*spec.ArrayData)
+		if arrayData.IsNull() {
+			enc.encodeNull(ArrayPrefix)
+			return
+		}
+
+		enc.encodeAggregate(ArrayPrefix, len(arrayData.A), arrayData.A)
+
+	case *spec.MultiData:
+		multi, _ := spec.Value[[]spec.Data](data)
+		for _, d := range multi {
+			enc.encode(d)
+		}
+
+	case *spec.NullData:
+		enc.encodeNull(BulkStringPrefix)
+
+	case *spec.BooleanData:
+		b, _ := spec.Value[bool](data)
+		if enc.protocol == spec.RESP2 {
+			i := int64(0)
+			if b {
+				i = 1
+			}
+			enc.encode(spec.IntegerOf(i))
+			return
+		}
+
+		if b {
+			enc.writeLine(BooleanPrefix, "t")
+		} else {
+			enc.writeLine(BooleanPrefix, "f")
+		}
+
+	case *spec.DoubleData:
+		d, _ := spec.Value[float64](data)
+		s := formatDouble(d)
+		if enc.protocol == spec.RESP2 {
+			enc.writeBlob(BulkStringPrefix, s)
+			return
+		}
+
+		enc.writeLine(DoublePrefix, s)
+
+	case *spec.BigNumberData:
+		n := data.(*spec.BigNumberData).N.String()
+		if enc.protocol == spec.RESP2 {
+			enc.writeBlob(BulkStringPrefix, n)
+			return
+		}
+
+		enc.writeLine(BigNumberPrefix, n)
+
+	case *spec.BulkErrorData:
+		str, _ := spec.Value[string](data)
+		if enc.protocol == spec.RESP2 {
+			enc.writeLine(SimpleErrorPrefix, errorLineReplacer.Replace(str))
+			return
+		}
+
+		enc.writeBlob(BulkErrorPrefix, str)
+
+	case *spec.VerbatimStringData:
+		verbatimString := data.(*spec.VerbatimStringData)
+		if enc.protocol == spec.RESP2 {
+			enc.writeBlob(BulkStringPrefix, verbatimString.S)
+			return
+		}
+
+		enc.writeLength(VerbatimStringPrefix, len(verbatimString.Format)+1+len(verbatimString.S))
+		enc.writeString(verbatimString.Format)
+		enc.writeByte(':')
+		enc.writeString(verbatimString.S)
+		enc.writeString("\r\n")
+
+	case *spec.MapData:
+		m, _ := spec.Value[[]spec.Data](data)
+		if enc.protocol == spec.RESP2 {
+			enc.encodeAggregate(ArrayPrefix, len(m), m)
+			return
+		}
+
+		enc.encodeAggregate(MapPrefix, len(m)/2, m)
+
+	case *spec.SetData:
+		set, _ := spec.Value[[]spec.Data](data)
+		if enc.protocol == spec.RESP2 {
+			enc.encodeAggregate(ArrayPrefix, len(set), set)
+			return
+		}
+
+		enc.encodeAggregate(SetPrefix, len(set), set)
+
+	case *spec.AttributeData:
+		// attributes are auxiliary, so RESP2 connections simply do not receive them
+		if enc.protocol == spec.RESP2 {
+			return
+		}
+
+		attribute, _ := spec.Value[[]spec.Data](data)
+		enc.encodeAggregate(AttributePrefix, len(attribute)/2, attribute)
+
+	case *spec.PushData:
+		push, _ := spec.Value[[]spec.Data](data)
+		if enc.protocol == spec.RESP2 {
+			enc.encodeAggregate(ArrayPrefix, len(push), push)
+			return
+		}
+
+		enc.encodeAggregate(PushPrefix, len(push), push)
+	}
+}
+
+func (enc *Encoder) encodeAggregate(prefix byte, l int, elems []spec.Data) {
+	enc.writeLength(prefix, l)
+	for _, elem := range elems {
+		enc.encode(elem)
+	}
+}
+
+// encodeNull writes the RESP3 null, or the null of the given RESP2 type (bulk string or array).
+func (enc *Encoder) encodeNull(resp2Prefix byte) {
+	if enc.protocol == spec.RESP2 {
+		enc.writeLine(resp2Prefix, "-1")
+		return
+	}
+
+	enc.writeLine(NullPrefix, "")
+}
+
+func (enc *Encoder) writeBlob(prefix byte, s string) {
+	enc.writeLength(prefix, len(s))
+	enc.writeString(s)
+	enc.writeString("\r\n")
+}
+
+func (enc *Encoder) writeLength(prefix byte, l int) {
+	enc.writeLine(prefix, strconv.Itoa(l))
+}
+
+func (enc *Encoder) writeLine(prefix byte, s string) {
+	enc.writeByte(prefix)
+	enc.writeString(s)
+	enc.writeString("\r\n")
+}
+
+func (enc *Encoder) writeByte(c byte) {
+	if enc.err == nil {
+		enc.err = enc.w.WriteByte(c)
+	}
+}
+
+func (enc *Encoder) writeString(s string) {
+	if enc.err == nil {
+		_, enc.err = enc.w.WriteString(s)
+	}
+}
+
+func formatDouble(d float64) string {
+	switch {
+	case math.IsInf(d, 1):
+		return "inf"
+	case math.IsInf(d, -1):
+		return "-inf"
+	case math.IsNaN(d):
+		return "nan"
+	default:
+		return strconv.FormatFloat(d, 'g', -1, 64)
+	}
+}
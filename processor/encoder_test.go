@@ -0,0 +1,237 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// dataGen builds replies from fuzzing input, one byte choosing each value. The input is
+// treated as zeros once consumed, so any input builds a finite reply.
+type dataGen struct {
+	b     []byte
+	large bool // a large bulk string is built already
+}
+
+func (g *dataGen) byte() byte {
+	if len(g.b) == 0 {
+		return 0
+	}
+
+	c := g.b[0]
+	g.b = g.b[1:]
+	return c
+}
+
+func (g *dataGen) int64() int64 {
+	var i int64
+	for range 8 {
+		i = i<<8 | int64(g.byte())
+	}
+	return i
+}
+
+func (g *dataGen) string() string {
+	n := min(int(g.byte()), len(g.b))
+	s := string(g.b[:n])
+	g.b = g.b[n:]
+	return s
+}
+
+// line returns a string without CR and LF, as simple strings can't have them.
+func (g *dataGen) line() string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(g.string())
+}
+
+const maxGenDepth = 4
+
+// data builds a reply, only with scalars beyond maxGenDepth. Attributes are left out as
+// they are not elements of the aggregate they are sent in.
+func (g *dataGen) data(depth int) spec.Data {
+	kind := g.byte() % 20
+	if depth >= maxGenDepth {
+		kind %= 12
+	}
+
+	switch kind {
+	case 0:
+		return spec.SimpleStringOf(g.line())
+	case 1:
+		// errors are kept in a single line by the encoder
+		return spec.SimpleErrorOf(errors.New(g.string()))
+	case 2:
+		return spec.IntegerOf(g.int64())
+	case 3:
+		return spec.BulkStringOf(g.string())
+	case 4:
+		// large replies span many reads, one at most so the input stays quick to check
+		if g.large {
+			return spec.BulkStringOf(g.string())
+		}
+		g.large = true
+		return spec.BulkStringOf(strings.Repeat(g.string(), 1+int(g.byte())*64))
+	case 5:
+		return spec.NullBulkString()
+	case 6:
+		return spec.Null()
+	case 7:
+		return spec.BooleanOf(g.byte()%2 == 0)
+	case 8:
+		doubles := []float64{0, -1.5, math.Pi, 1e300, math.Inf(1), math.Inf(-1), math.NaN()}
+		if c := g.byte(); int(c) < len(doubles) {
+			return spec.DoubleOf(doubles[c])
+		}
+		return spec.DoubleOf(math.Float64frombits(uint64(g.int64())))
+	case 9:
+		n := new(big.Int).SetInt64(g.int64())
+		return &spec.BigNumberData{N: n.Mul(n, n)}
+	case 10:
+		return spec.BulkErrorOf(g.string())
+	case 11:
+		return spec.VerbatimStringOf("txt", g.string())
+	case 12, 13:
+		return spec.ArrayOf(g.elements(depth, 1)...)
+	case 14:
+		return spec.NullArray()
+	case 15:
+		return spec.MapOf(g.elements(depth, 2)...)
+	case 16:
+		return spec.SetOf(g.elements(depth, 1)...)
+	case 17:
+		return spec.PushOf(g.elements(depth, 1)...)
+	default:
+		return spec.BulkStringArrayOf(g.line(), g.line())
+	}
+}
+
+// elements builds the elements of an aggregate, a multiple of n of them.
+func (g *dataGen) elements(depth, n int) []spec.Data {
+	elems := make([]spec.Data, 0, n*4)
+	for range n * int(g.byte()%4) {
+		elems = append(elems, g.data(depth+1))
+	}
+	return elems
+}
+
+func encode(t testing.TB, data spec.Data, protocol int) []byte {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf, protocol).Encode(data); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return buf.Bytes()
+}
+
+// lex lexes the stream, which must hold exactly one reply, as the stream of a client.
+func lex(t testing.TB, stream []byte) spec.Data {
+	reader := pkg.NewRESPReader(bytes.NewReader(stream), func() int { return math.MaxInt32 })
+	lexer := NewLexer()
+
+	var data spec.Data
+	for {
+		token, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading %q: %v", stream, err)
+		}
+		if data != nil {
+			t.Fatalf("token %q after the complete reply in %q", token, stream)
+		}
+
+		state, err := lexer.Lexing(1, bytes.Clone(token))
+		if err != nil {
+			t.Fatalf("lexing %q: %v", stream, err)
+		}
+		if state.complete() {
+			data = state.completeData
+		}
+	}
+
+	if data == nil {
+		t.Fatalf("incomplete reply %q", stream)
+	}
+	return data
+}
+
+// checkRESP2 fails when the reply lexed from the stream has types only sent to RESP3 connections.
+func checkRESP2(t testing.TB, data spec.Data, stream []byte) {
+	switch d := data.(type) {
+	case *spec.SimpleStringData, *spec.SimpleErrorData, *spec.IntegerData, *spec.BulkStringData:
+	case *spec.ArrayData:
+		for _, elem := range d.A {
+			checkRESP2(t, elem, stream)
+		}
+	default:
+		t.Fatalf("RESP2 reply %q has %T", stream, data)
+	}
+}
+
+func FuzzEncoder(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{3, 5, 'h', 'e', 'l', 'l', 'o'})
+	f.Add([]byte{12, 3, 14, 5, 6})
+	f.Add([]byte{15, 2, 0, 1, 'k', 8, 3, 2, 0, 0, 0, 0, 0, 0, 0, 7})
+	f.Add([]byte{12, 2, 12, 1, 13, 1, 16, 2, 2, 1, 2, 3, 4, 5, 6, 7, 8, 17, 1, 11, 1, 'x'})
+	f.Add([]byte{4, 3, 'a', '\r', '\n', 255})
+	f.Add([]byte{1, 4, 'a', '\r', '\n', 'b', 10, 3, 'e', '\n', 'r', 9, 255, 255, 255, 255, 255, 255, 255, 255})
+	f.Add([]byte{19, 2, 'a', ' ', 3, 'b', '\r', 'c'})
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		gen := &dataGen{b: b}
+		// the lexer takes arrays as requests and anything else as inline commands
+		data := spec.ArrayOf(gen.data(0))
+
+		for _, protocol := range []int{spec.RESP2, spec.RESP3} {
+			encoded := encode(t, data, protocol)
+
+			// the reply lexed back is encoded the same, so nothing is lost
+			lexed := lex(t, encoded)
+			if got := encode(t, lexed, protocol); !bytes.Equal(got, encoded) {
+				t.Fatalf("protocol %d: encoded %q, lexed back into %q", protocol, encoded, got)
+			}
+			if protocol == spec.RESP2 {
+				checkRESP2(t, lexed, encoded)
+			}
+		}
+	})
+}
+
+func TestEncoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     spec.Data
+		protocol int
+		want     string
+	}{
+		{name: "array", data: spec.BulkStringArrayOf("a", "bc"), protocol: spec.RESP2, want: "*2\r\n$1\r\na\r\n$2\r\nbc\r\n"},
+		{name: "empty array", data: spec.ArrayOf(), protocol: spec.RESP2, want: "*0\r\n"},
+		{name: "null array", data: spec.NullArray(), protocol: spec.RESP2, want: "*-1\r\n"},
+		{name: "null array in RESP3", data: spec.NullArray(), protocol: spec.RESP3, want: "_\r\n"},
+		{name: "nested arrays", data: spec.ArrayOf(spec.ArrayOf(spec.IntegerOf(1)), spec.NullArray()), protocol: spec.RESP2, want: "*2\r\n*1\r\n:1\r\n*-1\r\n"},
+		{name: "null", data: spec.Null(), protocol: spec.RESP2, want: "$-1\r\n"},
+		{name: "map in RESP2", data: spec.MapOf(spec.BulkStringOf("k"), spec.IntegerOf(1)), protocol: spec.RESP2, want: "*2\r\n$1\r\nk\r\n:1\r\n"},
+		{name: "map in RESP3", data: spec.MapOf(spec.BulkStringOf("k"), spec.IntegerOf(1)), protocol: spec.RESP3, want: "%1\r\n$1\r\nk\r\n:1\r\n"},
+		{name: "boolean in RESP2", data: spec.BooleanOf(true), protocol: spec.RESP2, want: ":1\r\n"},
+		{name: "double in RESP2", data: spec.DoubleOf(1.5), protocol: spec.RESP2, want: "$3\r\n1.5\r\n"},
+		{name: "double in RESP3", data: spec.DoubleOf(math.Inf(-1)), protocol: spec.RESP3, want: ",-inf\r\n"},
+		{name: "bulk error in RESP2", data: spec.BulkErrorOf("ERR a\r\nb"), protocol: spec.RESP2, want: "-ERR a b\r\n"},
+		{name: "verbatim string in RESP3", data: spec.VerbatimStringOf("txt", "hi"), protocol: spec.RESP3, want: "=6\r\ntxt:hi\r\n"},
+		{name: "multiline error", data: spec.SimpleErrorOf(errors.New("a\nb")), protocol: spec.RESP3, want: "-a b\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encode(t, tt.data, tt.protocol); string(got) != tt.want {
+				t.Errorf("encoded %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package processor
 
 import (
+	"errors"
 	"log/slog"
 
 	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/spec"
 )
 
 type ErrorHandler struct {
@@ -11,7 +13,7 @@ type ErrorHandler struct {
 }
 
 func NewErrorHandler() *ErrorHandler {
-	return &ErrorHandler{}
+	return &ErrorHandler{logger: slog.Default()}
 }
 
 func (h *ErrorHandler) Target() event.Type {
@@ -31,3 +33,14 @@ func (h *ErrorHandler) Handle(e event.Event, _ func(event.Event)) error {
 	h.logger.Error("error occurred while processing event", slog.Any("event", errorEvent.Event), slog.Any("error", errorEvent.Err))
 	return nil
 }
+
+// errorReplyOf converts an error into the error reply sent to the client.
+// Errors without an explicit error code are replied as generic "ERR" errors.
+func errorReplyOf(err error) *spec.SimpleErrorData {
+	var replyErr *spec.ReplyError
+	if errors.As(err, &replyErr) {
+		return spec.SimpleErrorOf(replyErr)
+	}
+
+	return spec.SimpleErrorOf(&spec.ReplyError{Code: "ERR", Msg: err.Error()})
+}
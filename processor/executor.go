@@ -1,30 +1,282 @@
 package processor
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/acl"
+	"github.com/codecrafters-io/redis-starter-go/config"
 	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/script"
 	"github.com/codecrafters-io/redis-starter-go/spec"
 	"github.com/codecrafters-io/redis-starter-go/storage"
 )
 
+const (
+	serverName    = "redis"
+	serverVersion = "7.2.0"
+)
+
 type Executor struct {
-	storage storage.Storage
+	storage  storage.Storage
+	pubsub   *PubSub
+	notifier *KeyspaceNotifier
+	clients  *Clients
+	latency  *LatencyMonitor
+	users    *acl.Users
+	parser   *Parser // parses commands called from scripts
+
+	transactions    map[uint64]*transaction
+	watches         map[uint64]*watch
+	watchedKeys     map[string]map[uint64]struct{}
+	cfg             *config.Config
+	configListeners map[string][]func(cfg *config.Config) error
+	statsResetters  []func()
+	infoFields      map[string][]infoField
+
+	runID             string
+	startedAt         time.Time
+	commandStats      map[string]*commandStat // by the names reported by CLIENT LIST
+	errorStats        map[string]int64        // by error codes
+	commandsProcessed int64
+	errorReplies      int64
+	opsRate           instantaneousMetric
+	memoryPeak        uint64
+	startupMemory     uint64 // used by the server before any command
+	slowlog           slowlog
+	monitors          map[uint64]struct{}
+	aclLog            acl.Log
+	aclDenied         map[acl.Reason]int64 // by the reasons of denials
+
+	trackedKeys      map[string]map[uint64]struct{} // keys read by clients tracking them
+	trackingPrefixes map[string]map[uint64]struct{} // prefixes registered in BCAST mode
+	caller           uint64                         // client executing the current command, 0 for none
+	flushing         bool
+
+	scripts         map[string]*script.Script
+	libraries       map[string]*script.Library
+	functions       map[string]*script.Function
+	script          *runningScript
+	scriptTimeLimit time.Duration
+	functionsDirty  bool // the libraries changed since they were saved
+	yielding        bool // other events are served from within a busy script
+	yield           func(accept func(event.Event) bool)
+
+	outOfMemory bool          // the memory could not be freed before the current command
+	pause       *clientPause  // nil when clients are not paused
+	idleTimeout time.Duration // 0 to keep idle clients
+	push        func(event.Event)
 }
 
-func NewExecutor(storage storage.Storage) *Executor {
-	return &Executor{
-		storage: storage,
+var _ event.Pusher = (*Executor)(nil)
+
+func NewExecutor(
+	cfg *config.Config,
+	storage storage.Storage,
+	pubsub *PubSub,
+	notifier *KeyspaceNotifier,
+	clients *Clients,
+	latency *LatencyMonitor,
+	users *acl.Users,
+) *Executor {
+	e := &Executor{
+		storage:  storage,
+		pubsub:   pubsub,
+		notifier: notifier,
+		clients:  clients,
+		latency:  latency,
+		users:    users,
+		parser:   NewParser(),
+
+		transactions: make(map[uint64]*transaction),
+		watches:      make(map[uint64]*watch),
+		watchedKeys:  make(map[string]map[uint64]struct{}),
+		infoFields:   make(map[string][]infoField),
+
+		cfg:             cfg,
+		configListeners: make(map[string][]func(cfg *config.Config) error),
+
+		runID:        newRunID(),
+		startedAt:    time.Now(),
+		commandStats: make(map[string]*commandStat),
+		errorStats:   make(map[string]int64),
+		monitors:     make(map[uint64]struct{}),
+		aclDenied:    make(map[acl.Reason]int64),
+
+		trackedKeys:      make(map[string]map[uint64]struct{}),
+		trackingPrefixes: make(map[string]map[uint64]struct{}),
+
+		scripts:         make(map[string]*script.Script),
+		libraries:       make(map[string]*script.Library),
+		functions:       make(map[string]*script.Function),
+		scriptTimeLimit: cfg.LuaTimeLimit,
+		idleTimeout:     cfg.Timeout,
+	}
+
+	// requirepass replaces the passwords of the default user loaded from the ACL file
+	if cfg.RequirePass != "" {
+		e.setRequirePass(cfg.RequirePass)
 	}
+
+	e.startupMemory = e.memStats().Alloc
+	e.initConfigListeners()
+	e.initInfoFields()
+	e.AddStatsResetter(e.resetStats)
+	storage.AddTouchListener(e.touchWatchedKey)
+	storage.AddTouchListener(e.invalidateKey)
+	return e
 }
 
+// initConfigListeners applies the parameters owned by the executor once they are set.
+func (e *Executor) initConfigListeners() {
+	e.AddConfigListener("notify-keyspace-events", func(cfg *config.Config) error {
+		e.notifier.SetFlags(cfg.NotifyKeyspaceEvents)
+		return nil
+	})
+	e.AddConfigListener("timeout", func(cfg *config.Config) error {
+		e.idleTimeout = cfg.Timeout
+		return nil
+	})
+	e.AddConfigListener("lua-time-limit", func(cfg *config.Config) error {
+		e.scriptTimeLimit = cfg.LuaTimeLimit
+		return nil
+	})
+	e.AddConfigListener("requirepass", func(cfg *config.Config) error {
+		e.setRequirePass(cfg.RequirePass)
+		return nil
+	})
+}
+
+// AddConfigListener registers a function applying the parameter to the component owning it
+// once it is changed by CONFIG SET. The listener receives the config with the new values,
+// and the change is reverted when it fails.
+func (e *Executor) AddConfigListener(name string, listener func(cfg *config.Config) error) {
+	e.configListeners[name] = append(e.configListeners[name], listener)
+}
+
+// AddStatsResetter registers a function resetting statistics of a component on CONFIG RESETSTAT.
+func (e *Executor) AddStatsResetter(reset func()) {
+	e.statsResetters = append(e.statsResetters, reset)
+}
+
+// InitPushing keeps the function to push the replies of commands executed later than
+// their events, such as the commands postponed by CLIENT PAUSE.
+func (e *Executor) InitPushing(push func(event.Event)) {
+	e.push = push
+}
+
+func (e *Executor) ShutdownPushing() {}
+
 func (e *Executor) ExecuteHandler() *executeHandler {
 	return &executeHandler{
 		executor: e,
 	}
 }
 
-func (e *Executor) Execute(cmd spec.Command) (spec.Data, error) {
+// handle executes the command of the event from a client and pushes its reply.
+func (e *Executor) handle(ev *event.ExecuteEvent) {
+	if e.postpone(ev) {
+		return
+	}
+
+	client, found := e.clients.Get(ev.ID())
+	if found {
+		client.LastInteraction = time.Now()
+		client.LastCommand = commandName(ev.Args)
+	}
+
+	// commands postponed by a pause are executed from within another one
+	caller := e.caller
+	e.caller = ev.ID()
+	defer func() { e.caller = caller }()
+
+	var output spec.Data
+	err := ev.Err
+	if err == nil && e.authRequired(client, ev.Command) {
+		err = errNoAuth
+	}
+	if err == nil && found {
+		if denial := e.checkPermissions(client, ev.Args, e.aclContext(ev.ID())); denial != "" {
+			err = spec.ErrorOf("NOPERM", "%s", denial)
+		}
+	}
+	if err == nil {
+		err = e.freeMemory(ev.Command)
+	}
+
+	if err != nil {
+		e.failTransaction(ev.ID())
+		if !errors.Is(err, errUnknownCommand) {
+			e.recordCommand(commandName(ev.Args), 0, false, err)
+		}
+	} else {
+		// queued commands are fed to monitors by EXEC, once they are executed
+		args := redactArgs(ev.Args)
+		reply, queued := e.queue(ev.ID(), ev.Command, args)
+		if found && !queued {
+			e.feedMonitors(client.Addr(), args)
+		}
+		e.storage.SetNoTouch(found && client.NoTouch)
+		start := time.Now()
+		if queued {
+			output = reply
+		} else {
+			output, err = e.Execute(ev.ID(), ev.Command)
+		}
+		duration := time.Since(start)
+		e.recordCommand(commandName(ev.Args), duration, true, err)
+		e.recordSlowCommand(ev.ID(), args, duration)
+		e.latency.Add(latencyEventCommand, duration)
+		e.storage.SetNoTouch(false)
+		e.endCaching(ev.ID(), ev.Command)
+	}
+
+	if err != nil {
+		slog.Warn("execute failed",
+			slog.Uint64("id", ev.ID()),
+			slog.Any("command", ev.Command),
+			slog.Any("error", err),
+		)
+		reply := errorReplyOf(err)
+		e.recordError(reply)
+		output = reply
+	}
+
+	suppressed := false
+	if found {
+		suppressed = client.replyOff || client.replySkip
+		client.replySkip, client.replySkipNext = client.replySkipNext, false
+
+		// a client killing itself is closed right away when no reply is written
+		if client.closeAfterReply && (suppressed || output == nil) {
+			_ = client.Conn.Close()
+		}
+	}
+
+	if output == nil || suppressed {
+		return
+	}
+
+	e.push(&event.FormatEvent{
+		ID_:  ev.ID(),
+		Data: output,
+	})
+}
+
+func (e *Executor) Execute(id uint64, cmd spec.Command) (spec.Data, error) {
+	if e.yielding {
+		return e.executeBusy(cmd)
+	}
+
+	// RESP3 connections can receive pushed messages along with other replies
+	if e.pubsub.SubscriptionCount(id) > 0 && e.clients.Protocol(id) == spec.RESP2 {
+		return e.executeSubscribed(id, cmd)
+	}
+
 	switch cmd.(type) {
 	case *spec.PingCommand:
 		return spec.SimpleStringOf("PONG"), nil
@@ -39,6 +291,7 @@ func (e *Executor) Execute(cmd spec.Command) (spec.Data, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to get key %s: %w", getCmd.Key, err)
 		}
+		e.trackRead(id, getCmd.Key)
 
 		if val == nil {
 			return spec.NullBulkString(), nil
@@ -54,11 +307,555 @@ func (e *Executor) Execute(cmd spec.Command) (spec.Data, error) {
 
 		return spec.SimpleStringOf("OK"), nil
 
+	case *spec.SubscribeCommand, *spec.UnsubscribeCommand, *spec.PSubscribeCommand, *spec.PUnsubscribeCommand:
+		return e.executeSubscribed(id, cmd)
+
+	case *spec.PublishCommand:
+		publishCmd := cmd.(*spec.PublishCommand)
+		receivers := e.pubsub.Publish(publishCmd.Channel, publishCmd.Message)
+		return spec.IntegerOf(int64(receivers)), nil
+
+	case *spec.MultiCommand:
+		return e.multi(id)
+
+	case *spec.MonitorCommand:
+		return e.monitor(id)
+
+	case *spec.AuthCommand:
+		authCmd := cmd.(*spec.AuthCommand)
+		return e.auth(id, authCmd.Username, authCmd.Password)
+
+	case *spec.ExecCommand:
+		return e.exec(id)
+
+	case *spec.DiscardCommand:
+		return e.discard(id)
+
+	case *spec.WatchCommand:
+		watchCmd := cmd.(*spec.WatchCommand)
+		return e.watch(id, watchCmd.Keys)
+
+	case *spec.UnwatchCommand:
+		e.unwatch(id)
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.FlushDBCommand:
+		e.flushing = true
+		e.storage.Flush()
+		e.flushing = false
+		e.invalidateAll()
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.EvalCommand:
+		evalCmd := cmd.(*spec.EvalCommand)
+		s, err := e.loadScript(evalCmd.Script)
+		if err != nil {
+			return nil, err
+		}
+
+		return e.evalSha(id, s.SHA, evalCmd.Keys, evalCmd.Args)
+
+	case *spec.EvalShaCommand:
+		evalShaCmd := cmd.(*spec.EvalShaCommand)
+		return e.evalSha(id, evalShaCmd.SHA, evalShaCmd.Keys, evalShaCmd.Args)
+
+	case *spec.ScriptLoadCommand:
+		scriptLoadCmd := cmd.(*spec.ScriptLoadCommand)
+		s, err := e.loadScript(scriptLoadCmd.Script)
+		if err != nil {
+			return nil, err
+		}
+
+		return spec.BulkStringOf(s.SHA), nil
+
+	case *spec.ScriptExistsCommand:
+		scriptExistsCmd := cmd.(*spec.ScriptExistsCommand)
+		exists := make([]spec.Data, 0, len(scriptExistsCmd.SHAs))
+		for _, sha := range scriptExistsCmd.SHAs {
+			if _, found := e.scripts[sha]; found {
+				exists = append(exists, spec.IntegerOf(1))
+			} else {
+				exists = append(exists, spec.IntegerOf(0))
+			}
+		}
+
+		return spec.ArrayOf(exists...), nil
+
+	case *spec.ScriptFlushCommand:
+		e.scripts = make(map[string]*script.Script)
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.ScriptKillCommand:
+		return e.killScript(false)
+
+	case *spec.FCallCommand:
+		fcallCmd := cmd.(*spec.FCallCommand)
+		return e.fcall(id, fcallCmd)
+
+	case *spec.FunctionLoadCommand:
+		functionLoadCmd := cmd.(*spec.FunctionLoadCommand)
+		return e.functionLoad(functionLoadCmd.Code, functionLoadCmd.Replace)
+
+	case *spec.FunctionListCommand:
+		functionListCmd := cmd.(*spec.FunctionListCommand)
+		return e.functionList(functionListCmd.LibraryPattern, functionListCmd.WithCode), nil
+
+	case *spec.FunctionDeleteCommand:
+		functionDeleteCmd := cmd.(*spec.FunctionDeleteCommand)
+		return e.functionDelete(functionDeleteCmd.Library)
+
+	case *spec.FunctionDumpCommand:
+		return spec.BulkStringOf(string(script.Dump(e.sortedLibraries()))), nil
+
+	case *spec.FunctionRestoreCommand:
+		functionRestoreCmd := cmd.(*spec.FunctionRestoreCommand)
+		return e.functionRestore(functionRestoreCmd.Payload, functionRestoreCmd.Policy)
+
+	case *spec.FunctionFlushCommand:
+		e.functionFlush()
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.FunctionKillCommand:
+		return e.killScript(true)
+
+	case *spec.FunctionStatsCommand:
+		return e.functionStats(), nil
+
+	case *spec.HelloCommand:
+		helloCmd := cmd.(*spec.HelloCommand)
+		return e.hello(id, helloCmd)
+
+	case *spec.ClientIDCommand:
+		return spec.IntegerOf(int64(id)), nil
+
+	case *spec.ClientInfoCommand:
+		return e.clientInfo(id)
+
+	case *spec.ClientListCommand:
+		clientListCmd := cmd.(*spec.ClientListCommand)
+		return e.clientList(clientListCmd), nil
+
+	case *spec.ClientSetNameCommand:
+		clientSetNameCmd := cmd.(*spec.ClientSetNameCommand)
+		return e.clientSetName(id, clientSetNameCmd.Name)
+
+	case *spec.ClientGetNameCommand:
+		return e.clientGetName(id), nil
+
+	case *spec.ClientKillCommand:
+		clientKillCmd := cmd.(*spec.ClientKillCommand)
+		return e.clientKill(id, clientKillCmd)
+
+	case *spec.ClientPauseCommand:
+		clientPauseCmd := cmd.(*spec.ClientPauseCommand)
+		return e.clientPause(clientPauseCmd), nil
+
+	case *spec.ClientUnpauseCommand:
+		e.unpause()
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.ClientNoEvictCommand:
+		clientNoEvictCmd := cmd.(*spec.ClientNoEvictCommand)
+		if client, found := e.clients.Get(id); found {
+			client.NoEvict = clientNoEvictCmd.On
+		}
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.ClientNoTouchCommand:
+		clientNoTouchCmd := cmd.(*spec.ClientNoTouchCommand)
+		if client, found := e.clients.Get(id); found {
+			client.NoTouch = clientNoTouchCmd.On
+		}
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.ClientReplyCommand:
+		clientReplyCmd := cmd.(*spec.ClientReplyCommand)
+		return e.clientReply(id, clientReplyCmd.Mode)
+
+	case *spec.ClientTrackingCommand:
+		clientTrackingCmd := cmd.(*spec.ClientTrackingCommand)
+		return e.clientTracking(id, clientTrackingCmd)
+
+	case *spec.ClientCachingCommand:
+		clientCachingCmd := cmd.(*spec.ClientCachingCommand)
+		return e.clientCaching(id, clientCachingCmd.Yes)
+
+	case *spec.ClientGetRedirCommand:
+		client, found := e.clients.Get(id)
+		if !found {
+			return nil, spec.ErrorOf("ERR", "client is already closed")
+		}
+		return spec.IntegerOf(trackingRedirect(client)), nil
+
+	case *spec.ClientTrackingInfoCommand:
+		return e.clientTrackingInfo(id)
+
+	case *spec.InfoCommand:
+		infoCmd := cmd.(*spec.InfoCommand)
+		return e.info(infoCmd.Sections), nil
+
+	case *spec.ConfigGetCommand:
+		configGetCmd := cmd.(*spec.ConfigGetCommand)
+		return e.configGet(configGetCmd.Patterns), nil
+
+	case *spec.ConfigSetCommand:
+		configSetCmd := cmd.(*spec.ConfigSetCommand)
+		if err := e.configSet(configSetCmd.Params); err != nil {
+			return nil, err
+		}
+
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.ConfigRewriteCommand:
+		if err := e.configRewrite(); err != nil {
+			return nil, err
+		}
+
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.ConfigResetStatCommand:
+		e.configResetStat()
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.ObjectEncodingCommand:
+		objectEncodingCmd := cmd.(*spec.ObjectEncodingCommand)
+		return e.objectEncoding(objectEncodingCmd.Key), nil
+
+	case *spec.ObjectRefCountCommand:
+		objectRefCountCmd := cmd.(*spec.ObjectRefCountCommand)
+		return e.objectRefCount(objectRefCountCmd.Key), nil
+
+	case *spec.ObjectIdleTimeCommand:
+		objectIdleTimeCmd := cmd.(*spec.ObjectIdleTimeCommand)
+		return e.objectIdleTime(objectIdleTimeCmd.Key)
+
+	case *spec.ObjectFreqCommand:
+		objectFreqCmd := cmd.(*spec.ObjectFreqCommand)
+		return e.objectFreq(objectFreqCmd.Key)
+
+	case *spec.MemoryUsageCommand:
+		memoryUsageCmd := cmd.(*spec.MemoryUsageCommand)
+		return e.memoryUsage(memoryUsageCmd.Key), nil
+
+	case *spec.MemoryStatsCommand:
+		return e.memoryStats(), nil
+
+	case *spec.MemoryDoctorCommand:
+		return e.memoryDoctor(), nil
+
+	case *spec.MemoryPurgeCommand:
+		debug.FreeOSMemory()
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.SlowlogGetCommand:
+		slowlogGetCmd := cmd.(*spec.SlowlogGetCommand)
+		return e.slowlogGet(slowlogGetCmd.Count), nil
+
+	case *spec.SlowlogLenCommand:
+		return spec.IntegerOf(int64(len(e.slowlog.entries))), nil
+
+	case *spec.SlowlogResetCommand:
+		e.slowlog.entries = nil
+		return spec.SimpleStringOf("OK"), nil
+
+	case *spec.LatencyLatestCommand:
+		return e.latency.Latest(), nil
+
+	case *spec.LatencyHistoryCommand:
+		latencyHistoryCmd := cmd.(*spec.LatencyHistoryCommand)
+		return e.latency.History(latencyHistoryCmd.Event), nil
+
+	case *spec.LatencyResetCommand:
+		latencyResetCmd := cmd.(*spec.LatencyResetCommand)
+		return spec.IntegerOf(int64(e.latency.Reset(latencyResetCmd.Events))), nil
+
+	case *spec.LatencyGraphCommand:
+		latencyGraphCmd := cmd.(*spec.LatencyGraphCommand)
+		return e.latency.Graph(latencyGraphCmd.Event)
+
+	case *spec.LatencyDoctorCommand:
+		return e.latency.Doctor(e.cfg.SlowlogLogSlowerThan), nil
+
+	case *spec.LatencyHistogramCommand:
+		latencyHistogramCmd := cmd.(*spec.LatencyHistogramCommand)
+		return e.latencyHistograms(latencyHistogramCmd.Commands), nil
+
+	case *spec.AclSetUserCommand:
+		aclSetUserCmd := cmd.(*spec.AclSetUserCommand)
+		return e.aclSetUser(aclSetUserCmd)
+
+	case *spec.AclGetUserCommand:
+		aclGetUserCmd := cmd.(*spec.AclGetUserCommand)
+		return e.aclGetUser(aclGetUserCmd.Username), nil
+
+	case *spec.AclDelUserCommand:
+		aclDelUserCmd := cmd.(*spec.AclDelUserCommand)
+		return e.aclDelUser(id, aclDelUserCmd.Usernames)
+
+	case *spec.AclListCommand:
+		return e.aclList(), nil
+
+	case *spec.AclUsersCommand:
+		return spec.BulkStringArrayOf(e.users.Names()...), nil
+
+	case *spec.AclWhoAmICommand:
+		return e.aclWhoAmI(id)
+
+	case *spec.AclCatCommand:
+		aclCatCmd := cmd.(*spec.AclCatCommand)
+		return e.aclCat(aclCatCmd.Category)
+
+	case *spec.AclLogCommand:
+		aclLogCmd := cmd.(*spec.AclLogCommand)
+		if aclLogCmd.Reset {
+			e.aclLog.Reset()
+			return spec.SimpleStringOf("OK"), nil
+		}
+		return e.aclLogEntries(aclLogCmd.Count), nil
+
+	case *spec.AclDryRunCommand:
+		aclDryRunCmd := cmd.(*spec.AclDryRunCommand)
+		return e.aclDryRun(aclDryRunCmd.Username, aclDryRunCmd.Args)
+
+	case *spec.AclSaveCommand:
+		return e.aclSave()
+
+	case *spec.AclLoadCommand:
+		return e.aclLoad(id)
+
 	default:
 		return nil, fmt.Errorf("invalid command: %+v", cmd)
 	}
 }
 
+func (e *Executor) hello(id uint64, cmd *spec.HelloCommand) (spec.Data, error) {
+	if cmd.Protocol != nil && *cmd.Protocol != spec.RESP2 && *cmd.Protocol != spec.RESP3 {
+		return nil, spec.ErrorOf("NOPROTO", "unsupported protocol version")
+	}
+
+	client, found := e.clients.Get(id)
+	if !found {
+		return nil, spec.ErrorOf("ERR", "client is already closed")
+	}
+
+	if cmd.Auth != nil {
+		if err := e.authenticate(client, cmd.Auth.Username, cmd.Auth.Password); err != nil {
+			return nil, err
+		}
+	}
+	if e.passwordRequired() && !client.Authenticated {
+		return nil, spec.ErrorOf("NOAUTH", "HELLO must be called with the client already authenticated, "+
+			"otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client "+
+			"and select the RESP protocol version at the same time")
+	}
+
+	if cmd.SetName != nil && !validClientName(*cmd.SetName) {
+		return nil, spec.ErrorOf("ERR", "Client names cannot contain spaces, newlines or special characters.")
+	}
+
+	if cmd.Protocol != nil {
+		client.Protocol = *cmd.Protocol
+	}
+	if cmd.SetName != nil {
+		client.Name = *cmd.SetName
+	}
+
+	return spec.MapOf(
+		spec.BulkStringOf("server"), spec.BulkStringOf(serverName),
+		spec.BulkStringOf("version"), spec.BulkStringOf(serverVersion),
+		spec.BulkStringOf("proto"), spec.IntegerOf(int64(client.Protocol)),
+		spec.BulkStringOf("id"), spec.IntegerOf(int64(id)),
+		spec.BulkStringOf("mode"), spec.BulkStringOf("standalone"),
+		spec.BulkStringOf("role"), spec.BulkStringOf("master"),
+		spec.BulkStringOf("modules"), spec.ArrayOf(),
+	), nil
+}
+
+// validClientName reports whether the name has only printable characters without spaces.
+func validClientName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < '!' || name[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RemoveClient drops the state kept for a closed connection.
+func (e *Executor) RemoveClient(id uint64) {
+	delete(e.transactions, id)
+	delete(e.monitors, id)
+	e.unwatch(id)
+	e.untrack(id)
+
+	if e.pause != nil {
+		e.pause.deferred = slices.DeleteFunc(e.pause.deferred, func(ev *event.ExecuteEvent) bool {
+			return ev.ID() == id
+		})
+	}
+}
+
+// executeSubscribed executes commands allowed for connections in subscribed state.
+func (e *Executor) executeSubscribed(id uint64, cmd spec.Command) (spec.Data, error) {
+	switch cmd.(type) {
+	case *spec.SubscribeCommand:
+		subscribeCmd := cmd.(*spec.SubscribeCommand)
+		return e.pubsub.Subscribe(id, subscribeCmd.Channels), nil
+
+	case *spec.UnsubscribeCommand:
+		unsubscribeCmd := cmd.(*spec.UnsubscribeCommand)
+		return e.pubsub.Unsubscribe(id, unsubscribeCmd.Channels), nil
+
+	case *spec.PSubscribeCommand:
+		psubscribeCmd := cmd.(*spec.PSubscribeCommand)
+		return e.pubsub.PSubscribe(id, psubscribeCmd.Patterns), nil
+
+	case *spec.PUnsubscribeCommand:
+		punsubscribeCmd := cmd.(*spec.PUnsubscribeCommand)
+		return e.pubsub.PUnsubscribe(id, punsubscribeCmd.Patterns), nil
+
+	case *spec.PingCommand:
+		return spec.BulkStringArrayOf("pong", ""), nil
+
+	default:
+		return nil, spec.ErrorOf("ERR", "only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING are allowed in this context")
+	}
+}
+
+func (e *Executor) configGet(patterns []string) spec.Data {
+	reply := make([]spec.Data, 0)
+	reported := make(map[*config.Param]bool)
+	for _, pattern := range patterns {
+		for _, p := range config.Match(pattern) {
+			if reported[p] {
+				continue
+			}
+			reported[p] = true
+
+			reply = append(reply, spec.BulkStringOf(p.Name), spec.BulkStringOf(p.Get(e.cfg)))
+		}
+	}
+
+	return spec.MapOf(reply...)
+}
+
+// configSet sets the parameters at once: they are validated on a copy of the config and
+// applied to the components, and when any of them fails the components are reverted.
+func (e *Executor) configSet(params [][2]string) error {
+	next := e.cfg.Clone()
+	set := make([]*config.Param, 0, len(params))
+	for _, kv := range params {
+		p, found := config.Lookup(kv[0])
+		if !found {
+			return spec.ErrorOf("ERR", "Unknown option or number of arguments for CONFIG SET - '%s'", kv[0])
+		}
+		if !p.Mutable {
+			return spec.ErrorOf("ERR", "CONFIG SET failed (possibly related to argument '%s') - %s", kv[0], config.ErrImmutable)
+		}
+		if slices.Contains(set, p) {
+			return spec.ErrorOf("ERR", "CONFIG SET failed (possibly related to argument '%s') - duplicate parameter", kv[0])
+		}
+
+		if err := p.Set(next, kv[1]); err != nil {
+			return spec.ErrorOf("ERR", "CONFIG SET failed (possibly related to argument '%s') - %s", kv[0], err)
+		}
+		set = append(set, p)
+	}
+
+	for i, p := range set {
+		for _, listener := range e.configListeners[p.Name] {
+			if err := listener(next); err != nil {
+				e.revertConfig(set[:i+1])
+				return spec.ErrorOf("ERR", "CONFIG SET failed (possibly related to argument '%s') - %s", p.Name, err)
+			}
+		}
+	}
+
+	*e.cfg = *next
+	for _, p := range set {
+		slog.Info("config parameter set",
+			slog.String("name", p.Name),
+			slog.String("value", p.Get(e.cfg)),
+		)
+	}
+
+	return nil
+}
+
+// revertConfig applies the current values of the parameters again after a failed CONFIG SET.
+func (e *Executor) revertConfig(params []*config.Param) {
+	for _, p := range params {
+		for _, listener := range e.configListeners[p.Name] {
+			if err := listener(e.cfg); err != nil {
+				slog.Error("failed to revert config parameter", slog.String("name", p.Name), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+func (e *Executor) configRewrite() error {
+	if err := config.Rewrite(e.cfg); err != nil {
+		if errors.Is(err, config.ErrNoConfigFile) {
+			return spec.ErrorOf("ERR", "The server is running without a config file")
+		}
+		return spec.ErrorOf("ERR", "Rewriting config file: %s", err)
+	}
+
+	slog.Info("config file rewritten", slog.String("file", e.cfg.File))
+	return nil
+}
+
+func (e *Executor) configResetStat() {
+	for _, reset := range e.statsResetters {
+		reset()
+	}
+}
+
+// freeMemory evicts keys until the used memory is under maxmemory before a command. When
+// the memory can't be freed, commands which may grow the dataset are rejected, and so are
+// the ones called from scripts started meanwhile.
+func (e *Executor) freeMemory(cmd spec.Command) error {
+	if e.yielding {
+		return nil // the dataset does not change under the busy script
+	}
+
+	var err error
+	e.latency.Measure(latencyEventEvictionCycle, func() { err = e.storage.FreeMemory() })
+	e.outOfMemory = errors.Is(err, storage.ErrOutOfMemory)
+	if e.outOfMemory && e.denyOOM(cmd) {
+		return errOutOfMemory
+	}
+
+	return nil
+}
+
+var errOutOfMemory = spec.ErrorOf("OOM", "command not allowed when used memory > 'maxmemory'.")
+
+// denyOOM reports whether the command is rejected when the memory is over maxmemory.
+// Scripts can't declare flags, so they are rejected as they may write, like functions
+// declared without the no-writes or allow-oom flags.
+func (e *Executor) denyOOM(cmd spec.Command) bool {
+	switch cmd := cmd.(type) {
+	case *spec.SetCommand, *spec.EvalCommand, *spec.EvalShaCommand:
+		return true
+	case *spec.FCallCommand:
+		f, found := e.functions[cmd.Function]
+		return found && !cmd.ReadOnly && !f.ReadOnly() && !f.AllowOOM()
+	default:
+		return false
+	}
+}
+
+// isWriteCommand reports whether the command may modify the dataset.
+func isWriteCommand(cmd spec.Command) bool {
+	switch cmd.(type) {
+	case *spec.SetCommand, *spec.FlushDBCommand:
+		return true
+	default:
+		return false
+	}
+}
+
 var _ event.Handler = (*executeHandler)(nil)
 
 type executeHandler struct {
@@ -75,15 +872,6 @@ func (h *executeHandler) Handle(e event.Event, push func(event.Event)) error {
 		return event.ErrInvalidEventType
 	}
 
-	output, err := h.executor.Execute(executeEvent.Command)
-	if err != nil {
-		// NOTE: maybe some error should be handled by returning error spec data
-		return fmt.Errorf("execute failed: %w", err)
-	}
-
-	push(&event.FormatEvent{
-		ID_:  executeEvent.ID(),
-		Data: output,
-	})
+	h.executor.handle(executeEvent)
 	return nil
 }
@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/acl"
+	"github.com/codecrafters-io/redis-starter-go/config"
+	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+	"github.com/codecrafters-io/redis-starter-go/storage"
+)
+
+func TestMain(m *testing.M) {
+	// every event is logged, which would bury the test failures
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	os.Exit(m.Run())
+}
+
+// testServer runs commands through the parser and the executor as the event loop does, and
+// keeps the replies and messages pushed to each client.
+type testServer struct {
+	t        *testing.T
+	cfg      *config.Config
+	storage  *storage.InMemoryStorage
+	clients  *Clients
+	pubsub   *PubSub
+	executor *Executor
+	parse    *parseHandler
+
+	lastID uint64
+	output map[uint64][]spec.Data
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Dir = t.TempDir()
+	t.Chdir(cfg.Dir)
+
+	s := &testServer{
+		t:       t,
+		cfg:     cfg,
+		storage: storage.NewInMemoryStorage(),
+		clients: NewClients(),
+		pubsub:  NewPubSub(),
+		parse:   NewParser().ParseHandler(),
+		output:  make(map[uint64][]spec.Data),
+	}
+	s.storage.SetEviction(cfg.Eviction())
+
+	notifier := NewKeyspaceNotifier(cfg, s.pubsub)
+	s.storage.AddKeyEventListener(notifier.Notify)
+	s.executor = NewExecutor(cfg, s.storage, s.pubsub, notifier, s.clients, NewLatencyMonitor(cfg), acl.NewUsers())
+	s.executor.InitPushing(s.push)
+	s.pubsub.InitPushing(s.push)
+	for _, name := range []string{"maxmemory", "maxmemory-policy", "maxmemory-samples", "lfu-log-factor", "lfu-decay-time"} {
+		s.executor.AddConfigListener(name, func(cfg *config.Config) error {
+			s.storage.SetEviction(cfg.Eviction())
+			return nil
+		})
+	}
+
+	return s
+}
+
+func (s *testServer) push(ev event.Event) {
+	switch ev := ev.(type) {
+	case *event.ExecuteEvent:
+		s.executor.handle(ev)
+	case *event.FormatEvent:
+		s.output[ev.ID()] = append(s.output[ev.ID()], ev.Data)
+	}
+}
+
+// connect adds a client, whose connection is never read or written.
+func (s *testServer) connect() uint64 {
+	s.lastID++
+	conn, peer := net.Pipe()
+	s.t.Cleanup(func() {
+		_ = conn.Close()
+		_ = peer.Close()
+	})
+
+	s.clients.add(newClient(s.lastID, conn))
+	return s.lastID
+}
+
+// disconnect removes the client, as the TCP processor does when the connection is closed.
+func (s *testServer) disconnect(id uint64) {
+	s.clients.remove(id)
+	s.pubsub.RemoveClient(id)
+	s.executor.RemoveClient(id)
+}
+
+// do runs the command from the client and returns its reply encoded with the protocol of
+// the client, or an empty string when nothing is replied. Messages pushed to the client
+// meanwhile are kept for take.
+func (s *testServer) do(id uint64, args ...string) string {
+	s.t.Helper()
+
+	before := len(s.output[id])
+	data := make([]spec.Data, 0, len(args))
+	for _, arg := range args {
+		data = append(data, spec.BulkStringOf(arg))
+	}
+	if err := s.parse.Handle(&event.ParseEvent{ID_: id, Data: spec.ArrayOf(data...)}, s.push); err != nil {
+		s.t.Fatalf("parse %q: %v", args, err)
+	}
+
+	// the reply is pushed last, after the messages caused by the command
+	output := s.output[id]
+	if len(output) == before {
+		return ""
+	}
+	s.output[id] = output[:len(output)-1]
+
+	return string(encode(s.t, output[len(output)-1], s.clients.Protocol(id)))
+}
+
+// take returns the messages pushed to the client since the last call, encoded with the
+// protocol of the client.
+func (s *testServer) take(id uint64) []string {
+	s.t.Helper()
+
+	var messages []string
+	for _, data := range s.output[id] {
+		messages = append(messages, string(encode(s.t, data, s.clients.Protocol(id))))
+	}
+	delete(s.output, id)
+
+	return messages
+}
+
+// command is a command run by a client in tests, with the reply it expects.
+type command struct {
+	client int // index of the client, 0 for the first one
+	args   []string
+	want   string
+}
+
+// run connects the clients and runs the commands in order, checking each reply.
+func (s *testServer) run(clients int, commands []command) []uint64 {
+	s.t.Helper()
+
+	ids := make([]uint64, clients)
+	for i := range ids {
+		ids[i] = s.connect()
+	}
+
+	for _, c := range commands {
+		if got := s.do(ids[c.client], c.args...); got != c.want {
+			s.t.Errorf("client %d: %q = %q, want %q", c.client, c.args, got, c.want)
+		}
+	}
+
+	return ids
+}
@@ -15,16 +15,26 @@ type Expirer struct {
 	d        time.Duration
 	idissuer id.IDIssuer[uint64]
 	storage  storage.Storage
+	latency  *LatencyMonitor
 
 	t              *time.Ticker
 	pushStopSignal chan struct{}
 }
 
-func NewExpirer(duration time.Duration, idIssuer id.IDIssuer[uint64], storage storage.Storage) *Expirer {
+func NewExpirer(duration time.Duration, idIssuer id.IDIssuer[uint64], storage storage.Storage, latency *LatencyMonitor) *Expirer {
 	return &Expirer{
 		d:        duration,
 		idissuer: idIssuer,
 		storage:  storage,
+		latency:  latency,
+	}
+}
+
+// SetInterval changes the interval of the ticks, taking effect on the next tick.
+func (t *Expirer) SetInterval(d time.Duration) {
+	t.d = d
+	if t.t != nil {
+		t.t.Reset(d)
 	}
 }
 
@@ -49,7 +59,7 @@ func (t *Expirer) loop(push func(event.Event)) {
 		select {
 		case time := <-t.t.C:
 			id := t.idissuer.Issue()
-			slog.Info("expirer ticker fired, pushing expire event",
+			slog.Debug("expirer ticker fired, pushing expire event",
 				slog.Uint64("id", id),
 				slog.Time("time", time),
 			)
@@ -74,7 +84,7 @@ func (t *Expirer) expire(time time.Time) {
 	slog.Info("expiring expired entries",
 		slog.Time("expiry_time", time),
 	)
-	t.storage.ExpireAllUntil(time)
+	t.latency.Measure(latencyEventExpireCycle, func() { t.storage.ExpireAllUntil(time) })
 }
 
 type expireEventHandler struct {
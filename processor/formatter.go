@@ -1,17 +1,29 @@
 package processor
 
 import (
-	"bytes"
-	"strconv"
+	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/codecrafters-io/redis-starter-go/config"
 	"github.com/codecrafters-io/redis-starter-go/event"
 	"github.com/codecrafters-io/redis-starter-go/spec"
 )
 
-type Formatter struct{}
+type Formatter struct {
+	clients *Clients
+	pubsub  *PubSub
 
-func NewFormatter() *Formatter {
-	return &Formatter{}
+	limits                  config.OutputBufferLimits
+	outputLimitDisconnected int64 // clients closed for reaching the limits
+}
+
+func NewFormatter(cfg *config.Config, clients *Clients, pubsub *PubSub) *Formatter {
+	return &Formatter{
+		clients: clients,
+		pubsub:  pubsub,
+		limits:  cfg.ClientOutputBufferLimits,
+	}
 }
 
 func (f *Formatter) FormatHandler() *formatHandler {
@@ -20,59 +32,59 @@ func (f *Formatter) FormatHandler() *formatHandler {
 	}
 }
 
-func (f *Formatter) Format(data spec.Data) []byte {
-	var bb bytes.Buffer
+// SetOutputBufferLimits sets the output buffer limits of the client classes.
+func (f *Formatter) SetOutputBufferLimits(limits config.OutputBufferLimits) {
+	f.limits = limits
+}
 
-	f.formatTo(data, &bb)
+// OutputLimitDisconnections returns the number of clients closed for reaching the limits.
+func (f *Formatter) OutputLimitDisconnections() int64 {
+	return f.outputLimitDisconnected
+}
 
-	return bb.Bytes()
+// ResetStats resets the statistics reported by INFO on CONFIG RESETSTAT.
+func (f *Formatter) ResetStats() {
+	f.outputLimitDisconnected = 0
 }
 
-func (f *Formatter) formatTo(data spec.Data, bb *bytes.Buffer) {
-	switch data.(type) {
-	case *spec.SimpleStringData:
-		bb.WriteByte(SimpleStringPrefix)
-		str, _ := spec.Value[string](data)
-		bb.WriteString(str)
-		bb.WriteString("\r\n")
-
-	case *spec.SimpleErrorData:
-		bb.WriteByte(SimpleErrorPrefix)
-		err, _ := spec.Value[error](data)
-		bb.WriteString(err.Error())
-		bb.WriteString("\r\n")
-
-	case *spec.IntegerData:
-		bb.WriteByte(IntegerPrefix)
-		i, _ := spec.Value[int64](data)
-		bb.WriteString(strconv.FormatInt(i, 10))
-		bb.WriteString("\r\n")
-
-	case *spec.BulkStringData:
-		bulkStringData := data.(*spec.BulkStringData)
-
-		bb.WriteByte(BulkStringPrefix)
-
-		bb.WriteString(strconv.Itoa(bulkStringData.Len))
-		bb.WriteString("\r\n")
-
-		if !bulkStringData.IsNull() {
-			str, _ := spec.Value[string](bulkStringData)
-			bb.WriteString(str)
-			bb.WriteString("\r\n")
-		}
-
-	case *spec.ArrayData:
-		bb.WriteByte(ArrayPrefix)
-		bb.WriteString("\r\n")
-
-		arr, _ := spec.Value[[]spec.Data](data)
-		for _, d := range arr {
-			f.formatTo(d, bb)
-		}
-
-		bb.WriteString("\r\n")
+// Format encodes the data into the output buffer of the client with its protocol version.
+// It reports false when the client is already closed, or closed for reaching the output
+// buffer limit of its class.
+func (f *Formatter) Format(id uint64, data spec.Data) (bool, error) {
+	client, found := f.clients.Get(id)
+	if !found || client.closeASAP {
+		return false, nil
+	}
+
+	if err := NewEncoder(client.output, client.Protocol).Encode(data); err != nil {
+		return false, err
 	}
+
+	class := config.ClientClassNormal
+	if f.pubsub.SubscriptionCount(id) > 0 {
+		class = config.ClientClassPubSub
+	}
+	if outputLimitReached(f.limits[class], client, int64(client.OutputBufferLen()), time.Now()) {
+		f.closeForLimit(client)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// closeForLimit drops the output of the client and closes it, which is cleaned up once
+// the reading goroutine sees it closed.
+func (f *Formatter) closeForLimit(client *Client) {
+	slog.Warn("client closed for overcoming of output buffer limits",
+		slog.Uint64("id", client.ID),
+		slog.Any("conn", client.Conn.RemoteAddr()),
+		slog.Int("omem", client.OutputBufferLen()),
+	)
+
+	client.closeASAP = true
+	client.output.Reset()
+	f.outputLimitDisconnected++
+	_ = client.Conn.Close()
 }
 
 var _ event.Handler = (*formatHandler)(nil)
@@ -91,11 +103,14 @@ func (f *formatHandler) Handle(e event.Event, push func(event.Event)) error {
 		return event.ErrInvalidEventType
 	}
 
-	b := f.formatter.Format(formatEvent.Data)
-	push(&event.WriteEvent{
-		ID_:  formatEvent.ID(),
-		Data: b,
-	})
+	formatted, err := f.formatter.Format(formatEvent.ID(), formatEvent.Data)
+	if err != nil {
+		return fmt.Errorf("failed to format reply: %w", err)
+	}
+	if !formatted {
+		return nil // the client is closed while the reply was in flight
+	}
 
+	push(&event.WriteEvent{ID_: formatEvent.ID()})
 	return nil
 }
@@ -0,0 +1,292 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+	"github.com/codecrafters-io/redis-starter-go/script"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// FunctionsFilename is the file in the working directory the libraries are persisted to.
+const FunctionsFilename = "functions.dump"
+
+func (e *Executor) fcall(id uint64, cmd *spec.FCallCommand) (spec.Data, error) {
+	f, found := e.functions[cmd.Function]
+	if !found {
+		return nil, spec.ErrorOf("ERR", "Function not found")
+	}
+
+	if cmd.ReadOnly && !f.ReadOnly() {
+		return nil, spec.ErrorOf("ERR", "Can not execute a script with write flag using *_ro command.")
+	}
+
+	name := "FCALL"
+	if cmd.ReadOnly {
+		name = "FCALL_RO"
+	}
+
+	rs := &runningScript{
+		id:       id,
+		name:     f.Name,
+		command:  append([]string{name, f.Name, strconv.Itoa(len(cmd.Keys))}, append(cmd.Keys, cmd.Args...)...),
+		function: true,
+		readOnly: f.ReadOnly(),
+		allowOOM: f.AllowOOM(),
+	}
+	return e.runScript(rs, func(ctx context.Context, call script.Caller) (spec.Data, error) {
+		return f.Call(ctx, cmd.Keys, cmd.Args, call)
+	})
+}
+
+func (e *Executor) functionLoad(code string, replace bool) (spec.Data, error) {
+	lib, err := script.LoadLibrary(code)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := spec.FunctionRestoreAppend
+	if replace {
+		policy = spec.FunctionRestoreReplace
+	}
+
+	if err := e.registerLibraries([]*script.Library{lib}, policy); err != nil {
+		lib.Close()
+		return nil, err
+	}
+
+	return spec.BulkStringOf(lib.Name), nil
+}
+
+func (e *Executor) functionList(libraryPattern string, withCode bool) spec.Data {
+	reply := make([]spec.Data, 0, len(e.libraries))
+	for _, lib := range e.sortedLibraries() {
+		if libraryPattern != "" && !pkg.MatchGlob(libraryPattern, lib.Name) {
+			continue
+		}
+
+		functions := make([]spec.Data, 0, len(lib.Functions))
+		for _, f := range lib.Functions {
+			description := spec.NullBulkString()
+			if f.Description != "" {
+				description = spec.BulkStringOf(f.Description)
+			}
+
+			flags := make([]spec.Data, 0, len(f.Flags))
+			for _, flag := range f.Flags {
+				flags = append(flags, spec.BulkStringOf(flag))
+			}
+
+			functions = append(functions, spec.MapOf(
+				spec.BulkStringOf("name"), spec.BulkStringOf(f.Name),
+				spec.BulkStringOf("description"), description,
+				spec.BulkStringOf("flags"), spec.SetOf(flags...),
+			))
+		}
+
+		info := []spec.Data{
+			spec.BulkStringOf("library_name"), spec.BulkStringOf(lib.Name),
+			spec.BulkStringOf("engine"), spec.BulkStringOf(script.Engine),
+			spec.BulkStringOf("functions"), spec.ArrayOf(functions...),
+		}
+		if withCode {
+			info = append(info, spec.BulkStringOf("library_code"), spec.BulkStringOf(lib.Code))
+		}
+
+		reply = append(reply, spec.MapOf(info...))
+	}
+
+	return spec.ArrayOf(reply...)
+}
+
+func (e *Executor) functionDelete(name string) (spec.Data, error) {
+	lib, found := e.libraries[name]
+	if !found {
+		return nil, spec.ErrorOf("ERR", "Library not found")
+	}
+
+	delete(e.libraries, name)
+	for _, f := range lib.Functions {
+		delete(e.functions, f.Name)
+	}
+	lib.Close()
+	e.functionsDirty = true
+
+	return spec.SimpleStringOf("OK"), nil
+}
+
+func (e *Executor) functionRestore(payload string, policy spec.FunctionRestorePolicy) (spec.Data, error) {
+	codes, err := script.Restore([]byte(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	libs := make([]*script.Library, 0, len(codes))
+	closeAll := func() {
+		for _, lib := range libs {
+			lib.Close()
+		}
+	}
+
+	for _, code := range codes {
+		lib, err := script.LoadLibrary(code)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		libs = append(libs, lib)
+	}
+
+	if err := e.registerLibraries(libs, policy); err != nil {
+		closeAll()
+		return nil, err
+	}
+
+	return spec.SimpleStringOf("OK"), nil
+}
+
+func (e *Executor) functionFlush() {
+	for _, lib := range e.libraries {
+		lib.Close()
+	}
+
+	e.libraries = make(map[string]*script.Library)
+	e.functions = make(map[string]*script.Function)
+	e.functionsDirty = true
+}
+
+func (e *Executor) functionStats() spec.Data {
+	var running spec.Data = spec.Null()
+	if e.script != nil && e.script.function {
+		running = spec.MapOf(
+			spec.BulkStringOf("name"), spec.BulkStringOf(e.script.name),
+			spec.BulkStringOf("command"), spec.BulkStringArrayOf(e.script.command...),
+			spec.BulkStringOf("duration_ms"), spec.IntegerOf(time.Since(e.script.start).Milliseconds()),
+		)
+	}
+
+	return spec.MapOf(
+		spec.BulkStringOf("running_script"), running,
+		spec.BulkStringOf("engines"), spec.MapOf(
+			spec.BulkStringOf(script.Engine), spec.MapOf(
+				spec.BulkStringOf("libraries_count"), spec.IntegerOf(int64(len(e.libraries))),
+				spec.BulkStringOf("functions_count"), spec.IntegerOf(int64(len(e.functions))),
+			),
+		),
+	)
+}
+
+// registerLibraries registers all the libraries or none of them, when a library or one of
+// its functions already exists. Existing libraries are replaced or flushed depending on the policy.
+func (e *Executor) registerLibraries(libs []*script.Library, policy spec.FunctionRestorePolicy) error {
+	libraries := maps.Clone(e.libraries)
+	var replaced []*script.Library
+
+	if policy == spec.FunctionRestoreFlush {
+		libraries = make(map[string]*script.Library)
+		replaced = slices.Collect(maps.Values(e.libraries))
+	}
+
+	for _, lib := range libs {
+		if old, found := libraries[lib.Name]; found {
+			if policy != spec.FunctionRestoreReplace {
+				return spec.ErrorOf("ERR", "Library '%s' already exists", lib.Name)
+			}
+			replaced = append(replaced, old)
+		}
+		libraries[lib.Name] = lib
+	}
+
+	functions := make(map[string]*script.Function)
+	for _, lib := range libraries {
+		for _, f := range lib.Functions {
+			if _, found := functions[f.Name]; found {
+				return spec.ErrorOf("ERR", "Function %s already exists", f.Name)
+			}
+			functions[f.Name] = f
+		}
+	}
+
+	for _, old := range replaced {
+		old.Close()
+	}
+
+	e.libraries = libraries
+	e.functions = functions
+	e.functionsDirty = true
+	return nil
+}
+
+func (e *Executor) sortedLibraries() []*script.Library {
+	names := slices.Sorted(maps.Keys(e.libraries))
+
+	libs := make([]*script.Library, 0, len(names))
+	for _, name := range names {
+		libs = append(libs, e.libraries[name])
+	}
+	return libs
+}
+
+// SaveFunctions writes the libraries to FunctionsFilename in the working directory, replacing
+// it at once so it is never left half written. The file holds the payload of FUNCTION DUMP,
+// kept apart from the RDB file as the server does not write one.
+func (e *Executor) SaveFunctions() error {
+	tmp, err := os.CreateTemp(".", "temp-*.dump")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(script.Dump(e.sortedLibraries())); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), FunctionsFilename); err != nil {
+		return err
+	}
+
+	e.functionsDirty = false
+	return nil
+}
+
+// SaveFunctionsIfDirty saves the libraries when they changed since they were loaded or
+// saved, as on shutdown.
+func (e *Executor) SaveFunctionsIfDirty() error {
+	if !e.functionsDirty {
+		return nil
+	}
+
+	return e.SaveFunctions()
+}
+
+// LoadFunctions restores the libraries saved to FunctionsFilename, if any.
+func (e *Executor) LoadFunctions() error {
+	payload, err := os.ReadFile(FunctionsFilename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := e.functionRestore(string(payload), spec.FunctionRestoreAppend); err != nil {
+		return err
+	}
+
+	e.functionsDirty = false
+	return nil
+}
@@ -0,0 +1,187 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/script"
+)
+
+const (
+	libA1 = "#!lua name=a\nredis.register_function('fa', function() return 'a1' end)"
+	libA2 = "#!lua name=a\nredis.register_function('fa', function() return 'a2' end)"
+	libB  = "#!lua name=b\nredis.register_function('fb', function() return 'b' end)"
+	// libC registers fa as well, which conflicts with the library a
+	libC = "#!lua name=c\nredis.register_function('fc', function() return 'c' end)\n" +
+		"redis.register_function('fa', function() return 'c' end)"
+	libRO = "#!lua name=ro\n" +
+		"redis.register_function{function_name='ro_get', callback=function(keys) return redis.call('GET', keys[1]) end, flags={'no-writes'}}\n" +
+		"redis.register_function{function_name='ro_set', callback=function(keys) return redis.call('SET', keys[1], 'v') end, flags={'no-writes'}}\n" +
+		"redis.register_function('rw_set', function(keys) return redis.call('SET', keys[1], 'v') end)"
+)
+
+// dumpOf returns the FUNCTION DUMP payload of the libraries.
+func dumpOf(t *testing.T, codes ...string) string {
+	t.Helper()
+
+	libs := make([]*script.Library, 0, len(codes))
+	for _, code := range codes {
+		lib, err := script.LoadLibrary(code)
+		if err != nil {
+			t.Fatalf("LoadLibrary(%q) failed: %v", code, err)
+		}
+		defer lib.Close()
+		libs = append(libs, lib)
+	}
+
+	return string(script.Dump(libs))
+}
+
+func TestFunction(t *testing.T) {
+	const (
+		fa1        = "$2\r\na1\r\n"
+		fa2        = "$2\r\na2\r\n"
+		fb         = "$1\r\nb\r\n"
+		notFound   = "-ERR Function not found\r\n"
+		aExists    = "-ERR Library 'a' already exists\r\n"
+		faExists   = "-ERR Function fa already exists\r\n"
+		badPayload = "-ERR payload version or checksum are wrong\r\n"
+	)
+
+	tests := []struct {
+		name     string
+		commands []command
+	}{
+		{
+			name: "load",
+			commands: []command{
+				{args: []string{"FUNCTION", "LOAD", libA1}, want: "$1\r\na\r\n"},
+				{args: []string{"FCALL", "fa", "0"}, want: fa1},
+				{args: []string{"FUNCTION", "LOAD", libA2}, want: aExists},
+				{args: []string{"FCALL", "fa", "0"}, want: fa1},
+				{args: []string{"FUNCTION", "LOAD", "REPLACE", libA2}, want: "$1\r\na\r\n"},
+				{args: []string{"FCALL", "fa", "0"}, want: fa2},
+				{args: []string{"FUNCTION", "DELETE", "a"}, want: "+OK\r\n"},
+				{args: []string{"FCALL", "fa", "0"}, want: notFound},
+			},
+		},
+		{
+			name: "load with a function of another library",
+			commands: []command{
+				{args: []string{"FUNCTION", "LOAD", libA1}, want: "$1\r\na\r\n"},
+				{args: []string{"FUNCTION", "LOAD", libC}, want: faExists},
+				{args: []string{"FCALL", "fa", "0"}, want: fa1},
+				{args: []string{"FCALL", "fc", "0"}, want: notFound},
+			},
+		},
+		{
+			name: "restore append",
+			commands: []command{
+				{args: []string{"FUNCTION", "LOAD", libA1}, want: "$1\r\na\r\n"},
+				{args: []string{"FUNCTION", "RESTORE", dumpOf(t, libB)}, want: "+OK\r\n"},
+				{args: []string{"FCALL", "fa", "0"}, want: fa1},
+				{args: []string{"FCALL", "fb", "0"}, want: fb},
+				{args: []string{"FUNCTION", "RESTORE", dumpOf(t, libA2), "APPEND"}, want: aExists},
+				{args: []string{"FCALL", "fa", "0"}, want: fa1},
+			},
+		},
+		{
+			name: "restore replace",
+			commands: []command{
+				{args: []string{"FUNCTION", "LOAD", libA1}, want: "$1\r\na\r\n"},
+				{args: []string{"FUNCTION", "LOAD", libB}, want: "$1\r\nb\r\n"},
+				{args: []string{"FUNCTION", "RESTORE", dumpOf(t, libA2), "REPLACE"}, want: "+OK\r\n"},
+				{args: []string{"FCALL", "fa", "0"}, want: fa2},
+				{args: []string{"FCALL", "fb", "0"}, want: fb},
+			},
+		},
+		{
+			name: "restore flush",
+			commands: []command{
+				{args: []string{"FUNCTION", "LOAD", libA1}, want: "$1\r\na\r\n"},
+				{args: []string{"FUNCTION", "RESTORE", dumpOf(t, libB), "FLUSH"}, want: "+OK\r\n"},
+				{args: []string{"FCALL", "fa", "0"}, want: notFound},
+				{args: []string{"FCALL", "fb", "0"}, want: fb},
+			},
+		},
+		{
+			// the libraries are registered all together or not at all
+			name: "restore with a conflict between libraries",
+			commands: []command{
+				{args: []string{"FUNCTION", "LOAD", libA1}, want: "$1\r\na\r\n"},
+				{args: []string{"FUNCTION", "RESTORE", dumpOf(t, libB, libC), "APPEND"}, want: faExists},
+				{args: []string{"FUNCTION", "RESTORE", dumpOf(t, libA2, libB, libC), "REPLACE"}, want: faExists},
+				{args: []string{"FCALL", "fa", "0"}, want: fa1},
+				{args: []string{"FCALL", "fb", "0"}, want: notFound},
+				{args: []string{"FCALL", "fc", "0"}, want: notFound},
+			},
+		},
+		{
+			name: "restore of a corrupted payload",
+			commands: []command{
+				{args: []string{"FUNCTION", "RESTORE", dumpOf(t, libA1)[1:]}, want: badPayload},
+				{args: []string{"FUNCTION", "RESTORE", strings.Replace(dumpOf(t, libA1), "a1", "a2", 1)}, want: badPayload},
+				{args: []string{"FUNCTION", "RESTORE", "short"}, want: badPayload},
+				{args: []string{"FCALL", "fa", "0"}, want: notFound},
+			},
+		},
+		{
+			name: "read-only functions",
+			commands: []command{
+				{args: []string{"FUNCTION", "LOAD", libRO}, want: "$2\r\nro\r\n"},
+				{args: []string{"FCALL_RO", "ro_get", "1", "k"}, want: "$-1\r\n"},
+				{args: []string{"FCALL_RO", "ro_set", "1", "k"}, want: "-ERR Write commands are not allowed from read-only scripts.\r\n"},
+				{args: []string{"FCALL", "ro_set", "1", "k"}, want: "-ERR Write commands are not allowed from read-only scripts.\r\n"},
+				{args: []string{"FCALL_RO", "rw_set", "1", "k"}, want: "-ERR Can not execute a script with write flag using *_ro command.\r\n"},
+				{args: []string{"GET", "k"}, want: "$-1\r\n"},
+				{args: []string{"FCALL", "rw_set", "1", "k"}, want: "+OK\r\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestServer(t).run(1, tt.commands)
+		})
+	}
+}
+
+func TestFunctionDumpRestore(t *testing.T) {
+	src := newTestServer(t)
+	id := src.run(1, []command{
+		{args: []string{"FUNCTION", "LOAD", libA1}, want: "$1\r\na\r\n"},
+		{args: []string{"FUNCTION", "LOAD", libB}, want: "$1\r\nb\r\n"},
+	})[0]
+
+	// the dump is replied as a bulk string
+	reply := src.do(id, "FUNCTION", "DUMP")
+	_, payload, _ := strings.Cut(reply, "\r\n")
+	payload = strings.TrimSuffix(payload, "\r\n")
+
+	newTestServer(t).run(1, []command{
+		{args: []string{"FUNCTION", "RESTORE", payload}, want: "+OK\r\n"},
+		{args: []string{"FCALL", "fa", "0"}, want: "$2\r\na1\r\n"},
+		{args: []string{"FCALL", "fb", "0"}, want: "$1\r\nb\r\n"},
+		{args: []string{"FUNCTION", "DUMP"}, want: reply},
+	})
+}
+
+func TestFunctionsSaveLoad(t *testing.T) {
+	src := newTestServer(t)
+	src.run(1, []command{
+		{args: []string{"FUNCTION", "LOAD", libA1}, want: "$1\r\na\r\n"},
+	})
+	if err := src.executor.SaveFunctionsIfDirty(); err != nil {
+		t.Fatalf("SaveFunctionsIfDirty failed: %v", err)
+	}
+
+	// the new server shares the working directory, where the functions are saved
+	dst := newTestServer(t)
+	t.Chdir(src.cfg.Dir)
+	if err := dst.executor.LoadFunctions(); err != nil {
+		t.Fatalf("LoadFunctions failed: %v", err)
+	}
+	dst.run(1, []command{
+		{args: []string{"FCALL", "fa", "0"}, want: "$2\r\na1\r\n"},
+	})
+}
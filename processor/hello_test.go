@@ -0,0 +1,197 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// helloReply returns the reply to HELLO for the client with the protocol.
+func helloReply(proto int, id uint64) string {
+	header := "*14\r\n"
+	if proto == 3 {
+		header = "%7\r\n"
+	}
+
+	return header +
+		"$6\r\nserver\r\n$5\r\nredis\r\n" +
+		"$7\r\nversion\r\n$5\r\n7.2.0\r\n" +
+		fmt.Sprintf("$5\r\nproto\r\n:%d\r\n", proto) +
+		fmt.Sprintf("$2\r\nid\r\n:%d\r\n", id) +
+		"$4\r\nmode\r\n$10\r\nstandalone\r\n" +
+		"$4\r\nrole\r\n$6\r\nmaster\r\n" +
+		"$7\r\nmodules\r\n*0\r\n"
+}
+
+func TestHelloEncoding(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		resp2 string
+		resp3 string
+	}{
+		{
+			name:  "map and null",
+			args:  []string{"FUNCTION", "STATS"},
+			resp2: "*4\r\n$14\r\nrunning_script\r\n$-1\r\n$7\r\nengines\r\n*2\r\n$3\r\nLUA\r\n*4\r\n$15\r\nlibraries_count\r\n:0\r\n$15\r\nfunctions_count\r\n:0\r\n",
+			resp3: "%2\r\n$14\r\nrunning_script\r\n_\r\n$7\r\nengines\r\n%1\r\n$3\r\nLUA\r\n%2\r\n$15\r\nlibraries_count\r\n:0\r\n$15\r\nfunctions_count\r\n:0\r\n",
+		},
+		{
+			name:  "set",
+			args:  []string{"CLIENT", "TRACKINGINFO"},
+			resp2: "*6\r\n$5\r\nflags\r\n*1\r\n$3\r\noff\r\n$8\r\nredirect\r\n:-1\r\n$8\r\nprefixes\r\n*0\r\n",
+			resp3: "%3\r\n$5\r\nflags\r\n~1\r\n$3\r\noff\r\n$8\r\nredirect\r\n:-1\r\n$8\r\nprefixes\r\n*0\r\n",
+		},
+		{
+			name:  "null bulk string",
+			args:  []string{"CLIENT", "GETNAME"},
+			resp2: "$-1\r\n",
+			resp3: "_\r\n",
+		},
+		{
+			name:  "push",
+			args:  []string{"SUBSCRIBE", "ch"},
+			resp2: "*3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n",
+			resp3: ">3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the protocol is negotiated per client: the first one keeps RESP2, the last one switches back to it
+			s := newTestServer(t)
+			ids := s.run(3, []command{
+				{client: 1, args: []string{"HELLO", "3"}, want: helloReply(3, 2)},
+				{client: 2, args: []string{"HELLO", "3"}, want: helloReply(3, 3)},
+				{client: 2, args: []string{"HELLO", "2"}, want: helloReply(2, 3)},
+			})
+
+			for i, want := range []string{tt.resp2, tt.resp3, tt.resp2} {
+				if got := s.do(ids[i], tt.args...); got != want {
+					t.Errorf("client %d: %q = %q, want %q", i, tt.args, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHelloPushMessage(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.run(3, []command{
+		{args: []string{"HELLO", "3"}, want: helloReply(3, 1)},
+		{args: []string{"SUBSCRIBE", "ch"}, want: ">3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n"},
+		{client: 1, args: []string{"SUBSCRIBE", "ch"}, want: "*3\r\n$9\r\nsubscribe\r\n$2\r\nch\r\n:1\r\n"},
+		{client: 2, args: []string{"PUBLISH", "ch", "m"}, want: ":2\r\n"},
+	})
+
+	tests := []struct {
+		id   uint64
+		want string
+	}{
+		{id: ids[0], want: ">3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$1\r\nm\r\n"},
+		{id: ids[1], want: "*3\r\n$7\r\nmessage\r\n$2\r\nch\r\n$1\r\nm\r\n"},
+	}
+	for _, tt := range tests {
+		if got := s.take(tt.id); len(got) != 1 || got[0] != tt.want {
+			t.Errorf("messages of client %d = %q, want [%q]", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestHelloDouble(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.run(3, []command{
+		// a failed authentication is logged with its age as a double
+		{args: []string{"AUTH", "u", "wrong"}, want: "-WRONGPASS invalid username-password pair or user is disabled.\r\n"},
+		{client: 1, args: []string{"HELLO", "3"}, want: helloReply(3, 2)},
+	})
+
+	tests := []struct {
+		id   uint64
+		want string
+	}{
+		{id: ids[1], want: "$11\r\nage-seconds\r\n,"},
+		{id: ids[2], want: "$11\r\nage-seconds\r\n$"},
+	}
+	for _, tt := range tests {
+		if got := s.do(tt.id, "ACL", "LOG"); !strings.Contains(got, tt.want) {
+			t.Errorf("client %d: ACL LOG = %q, want it to contain %q", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestHelloOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []command
+	}{
+		{
+			name: "current protocol",
+			commands: []command{
+				{args: []string{"HELLO"}, want: helloReply(2, 1)},
+				{args: []string{"HELLO", "3"}, want: helloReply(3, 1)},
+				{args: []string{"HELLO"}, want: helloReply(3, 1)},
+			},
+		},
+		{
+			name: "unsupported protocol",
+			commands: []command{
+				{args: []string{"HELLO", "4"}, want: "-NOPROTO unsupported protocol version\r\n"},
+				{args: []string{"HELLO", "1"}, want: "-NOPROTO unsupported protocol version\r\n"},
+				{args: []string{"HELLO", "x"}, want: "-ERR Protocol version is not an integer or out of range\r\n"},
+				{args: []string{"HELLO"}, want: helloReply(2, 1)},
+			},
+		},
+		{
+			name: "syntax error",
+			commands: []command{
+				{args: []string{"HELLO", "3", "AUTH", "default"}, want: "-ERR Syntax error in HELLO option 'AUTH'\r\n"},
+				{args: []string{"HELLO", "3", "SETNAME"}, want: "-ERR Syntax error in HELLO option 'SETNAME'\r\n"},
+				{args: []string{"HELLO", "3", "FOO"}, want: "-ERR Syntax error in HELLO option 'FOO'\r\n"},
+				{args: []string{"HELLO"}, want: helloReply(2, 1)},
+			},
+		},
+		{
+			name: "setname",
+			commands: []command{
+				{args: []string{"HELLO", "3", "SETNAME", "bad name"}, want: "-ERR Client names cannot contain spaces, newlines or special characters.\r\n"},
+				{args: []string{"CLIENT", "GETNAME"}, want: "$-1\r\n"},
+				{args: []string{"HELLO", "3", "SETNAME", "app"}, want: helloReply(3, 1)},
+				{args: []string{"CLIENT", "GETNAME"}, want: "$3\r\napp\r\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestServer(t).run(1, tt.commands)
+		})
+	}
+}
+
+func TestHelloAuth(t *testing.T) {
+	const (
+		noAuth    = "-NOAUTH Authentication required.\r\n"
+		wrongPass = "-WRONGPASS invalid username-password pair or user is disabled.\r\n"
+	)
+
+	// the clients connected already stay authenticated
+	s := newTestServer(t)
+	if got, want := s.do(s.connect(), "CONFIG", "SET", "requirepass", "secret"), "+OK\r\n"; got != want {
+		t.Fatalf("CONFIG SET requirepass = %q, want %q", got, want)
+	}
+
+	s.run(1, []command{
+		{args: []string{"GET", "k"}, want: noAuth},
+		{args: []string{"HELLO", "3"}, want: "-NOAUTH HELLO must be called with the client already authenticated, " +
+			"otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client " +
+			"and select the RESP protocol version at the same time\r\n"},
+		{args: []string{"HELLO", "3", "AUTH", "default", "wrong"}, want: wrongPass},
+		{args: []string{"GET", "k"}, want: noAuth},
+		{args: []string{"HELLO", "3", "AUTH", "default", "secret", "SETNAME", "app"}, want: helloReply(3, 2)},
+		{args: []string{"GET", "k"}, want: "_\r\n"},
+		{args: []string{"CLIENT", "GETNAME"}, want: "$3\r\napp\r\n"},
+		{args: []string{"HELLO", "2"}, want: helloReply(2, 2)},
+		{args: []string{"GET", "k"}, want: "$-1\r\n"},
+	})
+}
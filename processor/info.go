@@ -0,0 +1,360 @@
+package processor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/acl"
+	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// infoSection is a section of INFO. Sections with fields generated on each call (e.g.
+// a field for each command) report them with fields instead of the registered ones.
+type infoSection struct {
+	name      string
+	title     string
+	isDefault bool // reported without arguments, or with "default"
+	fields    func(e *Executor) []infoField
+}
+
+// infoSections are the sections of INFO in the order they are reported.
+var infoSections = []infoSection{
+	{name: "server", title: "Server", isDefault: true},
+	{name: "clients", title: "Clients", isDefault: true},
+	{name: "memory", title: "Memory", isDefault: true},
+	{name: "persistence", title: "Persistence", isDefault: true},
+	{name: "stats", title: "Stats", isDefault: true},
+	{name: "replication", title: "Replication", isDefault: true},
+	{name: "cpu", title: "CPU", isDefault: true},
+	{name: "commandstats", title: "Commandstats", fields: (*Executor).commandStatsFields},
+	{name: "errorstats", title: "Errorstats", isDefault: true, fields: (*Executor).errorStatsFields},
+	{name: "keyspace", title: "Keyspace", isDefault: true, fields: (*Executor).keyspaceFields},
+}
+
+type infoField struct {
+	name string
+	get  func() string
+}
+
+// commandStat is the statistics of a command reported by INFO commandstats.
+type commandStat struct {
+	calls    int64
+	duration time.Duration
+	rejected int64 // not executed for an error, e.g. a wrong number of arguments
+	failed   int64 // executed and replied an error
+
+	histogram latencyHistogram // of the executed calls
+}
+
+// AddInfoField registers a field owned by another component to the section of INFO.
+// Fields are reported in the order they are added.
+func (e *Executor) AddInfoField(section, name string, get func() string) {
+	e.infoFields[section] = append(e.infoFields[section], infoField{name: name, get: get})
+}
+
+// info formats the requested sections, or the default sections when none or "default"
+// is requested. "all" and "everything" request every section. Unknown sections are ignored.
+func (e *Executor) info(requested []string) spec.Data {
+	all := slices.ContainsFunc(requested, func(s string) bool { return s == "all" || s == "everything" })
+	defaults := len(requested) == 0 || slices.Contains(requested, "default")
+
+	var sb strings.Builder
+	for _, section := range infoSections {
+		if !all && !(defaults && section.isDefault) && !slices.Contains(requested, section.name) {
+			continue
+		}
+
+		fields := e.infoFields[section.name]
+		if section.fields != nil {
+			fields = section.fields(e)
+		}
+
+		if sb.Len() > 0 {
+			sb.WriteString("\r\n")
+		}
+		sb.WriteString("# ")
+		sb.WriteString(section.title)
+		sb.WriteString("\r\n")
+		for _, f := range fields {
+			sb.WriteString(f.name)
+			sb.WriteByte(':')
+			sb.WriteString(f.get())
+			sb.WriteString("\r\n")
+		}
+	}
+
+	return spec.VerbatimStringOf("txt", sb.String())
+}
+
+// initInfoFields registers the fields owned by the executor and the components it holds.
+func (e *Executor) initInfoFields() {
+	add := e.AddInfoField
+	constant := func(value string) func() string { return func() string { return value } }
+
+	add("server", "redis_version", constant(serverVersion))
+	add("server", "redis_git_sha1", constant("00000000"))
+	add("server", "redis_git_dirty", constant("0"))
+	add("server", "redis_mode", constant("standalone"))
+	add("server", "os", constant(runtime.GOOS+" "+runtime.GOARCH))
+	add("server", "arch_bits", constant(strconv.Itoa(strconv.IntSize)))
+	add("server", "go_version", constant(runtime.Version()))
+	add("server", "process_id", constant(strconv.Itoa(os.Getpid())))
+	add("server", "run_id", constant(e.runID))
+	add("server", "tcp_port", func() string { return strconv.Itoa(e.cfg.Port) })
+	add("server", "server_time_usec", func() string { return strconv.FormatInt(time.Now().UnixMicro(), 10) })
+	add("server", "uptime_in_seconds", func() string { return strconv.FormatInt(int64(e.uptime().Seconds()), 10) })
+	add("server", "uptime_in_days", func() string { return strconv.FormatInt(int64(e.uptime().Hours()/24), 10) })
+	add("server", "hz", func() string { return strconv.Itoa(e.cfg.Hz) })
+	add("server", "configured_hz", func() string { return strconv.Itoa(e.cfg.Hz) })
+	add("server", "executable", func() string {
+		path, _ := os.Executable()
+		return path
+	})
+	add("server", "config_file", func() string { return e.cfg.File })
+
+	add("clients", "connected_clients", func() string { return strconv.Itoa(e.clients.Len()) })
+	add("clients", "maxclients", func() string { return strconv.Itoa(e.cfg.MaxClients) })
+	add("clients", "client_recent_max_input_buffer", func() string {
+		return strconv.Itoa(maxOf(e.clients.All(), (*Client).QueryBufferLen))
+	})
+	add("clients", "client_recent_max_output_buffer", func() string {
+		return strconv.Itoa(maxOf(e.clients.All(), (*Client).OutputBufferLen))
+	})
+	add("clients", "blocked_clients", constant("0"))
+	add("clients", "tracking_clients", func() string {
+		return strconv.Itoa(countOf(e.clients.All(), func(c *Client) bool { return c.tracking != nil }))
+	})
+	add("clients", "pubsub_clients", func() string { return strconv.Itoa(e.pubsub.Clients()) })
+	add("clients", "watching_clients", func() string { return strconv.Itoa(len(e.watches)) })
+	add("clients", "total_watched_keys", func() string { return strconv.Itoa(len(e.watchedKeys)) })
+
+	add("memory", "used_memory", func() string { return strconv.FormatUint(e.memStats().Alloc, 10) })
+	add("memory", "used_memory_human", func() string { return bytesToHuman(e.memStats().Alloc) })
+	add("memory", "used_memory_rss", func() string { return strconv.FormatUint(e.memStats().Sys, 10) })
+	add("memory", "used_memory_peak", func() string { return strconv.FormatUint(e.memoryPeak, 10) })
+	add("memory", "used_memory_scripts", func() string { return strconv.Itoa(e.scriptsMemory()) })
+	add("memory", "number_of_cached_scripts", func() string { return strconv.Itoa(len(e.scripts)) })
+	add("memory", "used_memory_dataset", func() string {
+		return strconv.FormatInt(e.storage.Stats().UsedMemory, 10)
+	})
+	add("memory", "maxmemory", func() string { return strconv.FormatInt(e.cfg.MaxMemory, 10) })
+	add("memory", "maxmemory_human", func() string { return bytesToHuman(uint64(e.cfg.MaxMemory)) })
+	add("memory", "maxmemory_policy", func() string { return e.cfg.MaxMemoryPolicy.String() })
+	add("memory", "mem_allocator", constant("go"))
+
+	add("persistence", "loading", constant("0"))
+	add("persistence", "rdb_changes_since_last_save", func() string {
+		return strconv.FormatInt(e.storage.Stats().Dirty, 10)
+	})
+	add("persistence", "rdb_bgsave_in_progress", constant("0"))
+	add("persistence", "rdb_last_save_time", constant(strconv.FormatInt(e.startedAt.Unix(), 10)))
+	add("persistence", "rdb_last_bgsave_status", constant("ok"))
+	add("persistence", "aof_enabled", constant("0"))
+	add("persistence", "aof_rewrite_in_progress", constant("0"))
+
+	add("stats", "total_commands_processed", func() string { return strconv.FormatInt(e.commandsProcessed, 10) })
+	add("stats", "instantaneous_ops_per_sec", func() string { return strconv.FormatInt(int64(e.opsRate.rate()), 10) })
+	add("stats", "expired_keys", func() string { return strconv.FormatInt(e.storage.Stats().ExpiredKeys, 10) })
+	add("stats", "evicted_keys", func() string { return strconv.FormatInt(e.storage.Stats().EvictedKeys, 10) })
+	add("stats", "keyspace_hits", func() string { return strconv.FormatInt(e.storage.Stats().KeyspaceHits, 10) })
+	add("stats", "keyspace_misses", func() string { return strconv.FormatInt(e.storage.Stats().KeyspaceMisses, 10) })
+	add("stats", "pubsub_channels", func() string { return strconv.Itoa(e.pubsub.Channels()) })
+	add("stats", "pubsub_patterns", func() string { return strconv.Itoa(e.pubsub.Patterns()) })
+	add("stats", "tracking_total_keys", func() string { return strconv.Itoa(len(e.trackedKeys)) })
+	add("stats", "tracking_total_prefixes", func() string { return strconv.Itoa(len(e.trackingPrefixes)) })
+	add("stats", "total_error_replies", func() string { return strconv.FormatInt(e.errorReplies, 10) })
+	add("stats", "acl_access_denied_auth", func() string { return strconv.FormatInt(e.aclDenied[acl.ReasonAuth], 10) })
+	add("stats", "acl_access_denied_cmd", func() string { return strconv.FormatInt(e.aclDenied[acl.ReasonCommand], 10) })
+	add("stats", "acl_access_denied_key", func() string { return strconv.FormatInt(e.aclDenied[acl.ReasonKey], 10) })
+	add("stats", "acl_access_denied_channel", func() string { return strconv.FormatInt(e.aclDenied[acl.ReasonChannel], 10) })
+
+	add("replication", "role", constant("master"))
+	add("replication", "connected_slaves", constant("0"))
+	add("replication", "master_replid", constant(e.runID))
+	add("replication", "master_repl_offset", constant("0"))
+	add("replication", "second_repl_offset", constant("-1"))
+	add("replication", "repl_backlog_active", constant("0"))
+	add("replication", "repl_backlog_histlen", constant("0"))
+
+	add("cpu", "used_cpu_sys", func() string { return formatCPUTime(rusage().Stime) })
+	add("cpu", "used_cpu_user", func() string { return formatCPUTime(rusage().Utime) })
+}
+
+// recordCommand updates the statistics of a command replied by the client.
+func (e *Executor) recordCommand(name string, duration time.Duration, executed bool, err error) {
+	stat, found := e.commandStats[name]
+	if !found {
+		stat = &commandStat{}
+		e.commandStats[name] = stat
+	}
+
+	switch {
+	case !executed:
+		stat.rejected++
+	case err != nil:
+		stat.failed++
+	}
+	if executed {
+		stat.calls++
+		stat.duration += duration
+		stat.histogram.record(duration)
+		e.commandsProcessed++
+	}
+}
+
+// recordError counts an error replied to a client by its code, e.g. "ERR" or "WRONGTYPE".
+func (e *Executor) recordError(reply *spec.SimpleErrorData) {
+	code := "ERR"
+	var replyErr *spec.ReplyError
+	if errors.As(reply.Err, &replyErr) {
+		code = replyErr.Code
+	}
+
+	e.errorStats[code]++
+	e.errorReplies++
+}
+
+// StatsCron samples the processed commands for instantaneous_ops_per_sec.
+func (e *Executor) StatsCron(now time.Time, _ func(event.Event)) {
+	e.opsRate.track(now, e.commandsProcessed)
+}
+
+func (e *Executor) resetStats() {
+	e.commandStats = make(map[string]*commandStat)
+	e.errorStats = make(map[string]int64)
+	e.commandsProcessed = 0
+	e.errorReplies = 0
+	e.aclDenied = make(map[acl.Reason]int64)
+	e.opsRate.reset()
+	e.storage.ResetStats()
+}
+
+func (e *Executor) commandStatsFields() []infoField {
+	names := slices.Sorted(maps.Keys(e.commandStats))
+	fields := make([]infoField, 0, len(names))
+	for _, name := range names {
+		stat := e.commandStats[name]
+		usec := stat.duration.Microseconds()
+		perCall := 0.0
+		if stat.calls > 0 {
+			perCall = float64(usec) / float64(stat.calls)
+		}
+
+		value := fmt.Sprintf("calls=%d,usec=%d,usec_per_call=%.2f,rejected_calls=%d,failed_calls=%d",
+			stat.calls, usec, perCall, stat.rejected, stat.failed)
+		fields = append(fields, infoField{name: "cmdstat_" + name, get: func() string { return value }})
+	}
+
+	return fields
+}
+
+func (e *Executor) errorStatsFields() []infoField {
+	codes := slices.Sorted(maps.Keys(e.errorStats))
+	fields := make([]infoField, 0, len(codes))
+	for _, code := range codes {
+		value := "count=" + strconv.FormatInt(e.errorStats[code], 10)
+		fields = append(fields, infoField{name: "errorstat_" + code, get: func() string { return value }})
+	}
+
+	return fields
+}
+
+// keyspaceFields reports the only database, unless it is empty.
+func (e *Executor) keyspaceFields() []infoField {
+	stats := e.storage.Stats()
+	if stats.Keys == 0 {
+		return nil
+	}
+
+	value := fmt.Sprintf("keys=%d,expires=%d,avg_ttl=%d", stats.Keys, stats.Expires, stats.AvgTTL.Milliseconds())
+	return []infoField{{name: "db0", get: func() string { return value }}}
+}
+
+func (e *Executor) uptime() time.Duration {
+	return time.Since(e.startedAt)
+}
+
+// memStats reads the memory statistics of the runtime, keeping the peak of used_memory.
+func (e *Executor) memStats() *runtime.MemStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	e.memoryPeak = max(e.memoryPeak, ms.Alloc)
+	return &ms
+}
+
+func (e *Executor) scriptsMemory() int {
+	n := 0
+	for _, s := range e.scripts {
+		n += len(s.Body)
+	}
+
+	return n
+}
+
+// newRunID returns a random identifier of the server, which changes on every start.
+func newRunID() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func rusage() *syscall.Rusage {
+	var ru syscall.Rusage
+	_ = syscall.Getrusage(syscall.RUSAGE_SELF, &ru)
+	return &ru
+}
+
+func formatCPUTime(tv syscall.Timeval) string {
+	return fmt.Sprintf("%d.%06d", tv.Sec, tv.Usec)
+}
+
+// bytesToHuman formats an amount of memory as Redis does, e.g. "1.50M".
+func bytesToHuman(n uint64) string {
+	units := []struct {
+		suffix string
+		size   uint64
+	}{
+		{"T", 1 << 40},
+		{"G", 1 << 30},
+		{"M", 1 << 20},
+		{"K", 1 << 10},
+	}
+	for _, u := range units {
+		if n >= u.size {
+			return fmt.Sprintf("%.2f%s", float64(n)/float64(u.size), u.suffix)
+		}
+	}
+
+	return strconv.FormatUint(n, 10) + "B"
+}
+
+func maxOf[T any](items []T, value func(T) int) int {
+	m := 0
+	for _, item := range items {
+		m = max(m, value(item))
+	}
+
+	return m
+}
+
+func countOf[T any](items []T, match func(T) bool) int {
+	n := 0
+	for _, item := range items {
+		if match(item) {
+			n++
+		}
+	}
+
+	return n
+}
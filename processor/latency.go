@@ -0,0 +1,419 @@
+package processor
+
+import (
+	"fmt"
+	"maps"
+	"math/bits"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/config"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+const (
+	latencySeriesLen = 160 // samples kept for each event, as in Redis
+
+	// LATENCY GRAPH draws the samples in columns of the width, with rows of 3 levels each
+	latencyGraphColumns = 80
+	latencyGraphRows    = 4
+)
+
+// LatencyMonitor keeps the latency spikes of events taking at least latency-monitor-threshold
+// milliseconds, such as slow commands or expire cycles, for the LATENCY command.
+// It is only accessed from the event loop, so no locking is needed.
+type LatencyMonitor struct {
+	cfg    *config.Config
+	series map[string]*latencySeries
+}
+
+// latencySeries is a ring of the latest samples of an event, keeping a sample per second.
+type latencySeries struct {
+	samples [latencySeriesLen]latencySample
+	idx     int   // of the next sample
+	max     int64 // all time highest latency in milliseconds
+}
+
+type latencySample struct {
+	time    int64 // unix seconds, 0 for an unused sample
+	latency int64 // milliseconds
+}
+
+func NewLatencyMonitor(cfg *config.Config) *LatencyMonitor {
+	return &LatencyMonitor{
+		cfg:    cfg,
+		series: make(map[string]*latencySeries),
+	}
+}
+
+// Add records a sample of the event when the latency monitor is enabled and the duration
+// reaches its threshold. Samples within the same second are merged, keeping the highest.
+func (m *LatencyMonitor) Add(event string, duration time.Duration) {
+	threshold := m.cfg.LatencyMonitorThreshold
+	latency := duration.Milliseconds()
+	if threshold == 0 || latency < threshold {
+		return
+	}
+
+	s, found := m.series[event]
+	if !found {
+		s = &latencySeries{}
+		m.series[event] = s
+	}
+	s.max = max(s.max, latency)
+
+	now := time.Now().Unix()
+	prev := &s.samples[(s.idx+latencySeriesLen-1)%latencySeriesLen]
+	if prev.time == now {
+		prev.latency = max(prev.latency, latency)
+		return
+	}
+
+	s.samples[s.idx] = latencySample{time: now, latency: latency}
+	s.idx = (s.idx + 1) % latencySeriesLen
+}
+
+// Measure records the time taken by f as a sample of the event.
+func (m *LatencyMonitor) Measure(event string, f func()) {
+	start := time.Now()
+	f()
+	m.Add(event, time.Since(start))
+}
+
+// history returns the samples of the series from the oldest.
+func (s *latencySeries) history() []latencySample {
+	samples := make([]latencySample, 0, latencySeriesLen)
+	for i := range latencySeriesLen {
+		sample := s.samples[(s.idx+i)%latencySeriesLen]
+		if sample.time != 0 {
+			samples = append(samples, sample)
+		}
+	}
+
+	return samples
+}
+
+func (s *latencySeries) latest() latencySample {
+	return s.samples[(s.idx+latencySeriesLen-1)%latencySeriesLen]
+}
+
+// Latest replies the event names with the time and latency of their latest sample and
+// their all time highest latency.
+func (m *LatencyMonitor) Latest() spec.Data {
+	events := slices.Sorted(maps.Keys(m.series))
+	replies := make([]spec.Data, 0, len(events))
+	for _, event := range events {
+		s := m.series[event]
+		latest := s.latest()
+		replies = append(replies, spec.ArrayOf(
+			spec.BulkStringOf(event),
+			spec.IntegerOf(latest.time),
+			spec.IntegerOf(latest.latency),
+			spec.IntegerOf(s.max),
+		))
+	}
+
+	return spec.ArrayOf(replies...)
+}
+
+// History replies the time and latency of the samples of the event, from the oldest.
+func (m *LatencyMonitor) History(event string) spec.Data {
+	s, found := m.series[event]
+	if !found {
+		return spec.ArrayOf()
+	}
+
+	samples := s.history()
+	replies := make([]spec.Data, 0, len(samples))
+	for _, sample := range samples {
+		replies = append(replies, spec.ArrayOf(
+			spec.IntegerOf(sample.time),
+			spec.IntegerOf(sample.latency),
+		))
+	}
+
+	return spec.ArrayOf(replies...)
+}
+
+// Reset drops the samples of the events, or of every event when none is given, returning
+// the number of events reset.
+func (m *LatencyMonitor) Reset(events []string) int {
+	if len(events) == 0 {
+		n := len(m.series)
+		clear(m.series)
+		return n
+	}
+
+	n := 0
+	for _, event := range events {
+		if _, found := m.series[event]; found {
+			delete(m.series, event)
+			n++
+		}
+	}
+
+	return n
+}
+
+// Graph draws the samples of the event as an ASCII art graph, labeled with the time elapsed
+// since each sample.
+func (m *LatencyMonitor) Graph(event string) (spec.Data, error) {
+	s, found := m.series[event]
+	if !found {
+		return nil, spec.ErrorOf("ERR", "No samples available for event '%s'", event)
+	}
+
+	samples := s.history()
+	low, high := samples[0].latency, samples[0].latency
+	now := time.Now().Unix()
+	seq := make([]sparklineSample, 0, len(samples))
+	for _, sample := range samples {
+		low, high = min(low, sample.latency), max(high, sample.latency)
+		seq = append(seq, sparklineSample{value: sample.latency, label: elapsedLabel(now - sample.time)})
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s - high %d ms, low %d ms (all time high %d ms)\n", event, high, low, s.max)
+	sb.WriteString(strings.Repeat("-", latencyGraphColumns))
+	sb.WriteString("\n")
+	for i := 0; i < len(seq); i += latencyGraphColumns {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		renderSparkline(&sb, seq[i:min(i+latencyGraphColumns, len(seq))], low, high)
+	}
+
+	return spec.VerbatimStringOf("txt", sb.String()), nil
+}
+
+// elapsedLabel formats the seconds elapsed in the largest unit, e.g. "12s" or "3h".
+func elapsedLabel(elapsed int64) string {
+	switch {
+	case elapsed < 60:
+		return fmt.Sprintf("%ds", elapsed)
+	case elapsed < 3600:
+		return fmt.Sprintf("%dm", elapsed/60)
+	case elapsed < 3600*24:
+		return fmt.Sprintf("%dh", elapsed/3600)
+	default:
+		return fmt.Sprintf("%dd", elapsed/(3600*24))
+	}
+}
+
+type sparklineSample struct {
+	value int64
+	label string
+}
+
+// sparklineLevels are the characters of the levels within a row, filling the rows below.
+const sparklineLevels = "_o#"
+
+// renderSparkline draws the samples as columns of rows of levels scaled between low and high,
+// followed by their labels written vertically, as the sparklines of Redis.
+func renderSparkline(sb *strings.Builder, seq []sparklineSample, low, high int64) {
+	steps := len(sparklineLevels) * latencyGraphRows
+	line := make([]byte, len(seq))
+	for row := range latencyGraphRows {
+		for i, sample := range seq {
+			step := 0
+			if high > low {
+				step = min(int((sample.value-low)*int64(steps)/(high-low)), steps-1)
+			}
+
+			level := step - (latencyGraphRows-row-1)*len(sparklineLevels)
+			switch {
+			case level < 0:
+				line[i] = ' '
+			case level < len(sparklineLevels):
+				line[i] = sparklineLevels[level]
+			default:
+				line[i] = '|'
+			}
+		}
+		sb.Write(line)
+		sb.WriteString("\n")
+	}
+
+	// the labels are separated from the graph by an empty line
+	sb.WriteString(strings.Repeat(" ", len(seq)))
+	sb.WriteString("\n")
+	for row := 0; ; row++ {
+		written := false
+		for i, sample := range seq {
+			line[i] = ' '
+			if row < len(sample.label) {
+				line[i] = sample.label[row]
+				written = true
+			}
+		}
+		if !written {
+			return
+		}
+		sb.Write(line)
+		sb.WriteString("\n")
+	}
+}
+
+// latencyAnalysis is the summary of the samples of an event reported by LATENCY DOCTOR.
+type latencyAnalysis struct {
+	samples int
+	avg     int64
+	mad     int64 // mean absolute deviation
+	period  int64 // seconds since the oldest sample
+}
+
+func (s *latencySeries) analyze(now int64) latencyAnalysis {
+	samples := s.history()
+	if len(samples) == 0 {
+		return latencyAnalysis{}
+	}
+
+	var sum, oldest int64
+	for _, sample := range samples {
+		sum += sample.latency
+		if oldest == 0 || sample.time < oldest {
+			oldest = sample.time
+		}
+	}
+
+	a := latencyAnalysis{
+		samples: len(samples),
+		avg:     sum / int64(len(samples)),
+		period:  max(now-oldest, 1),
+	}
+
+	var deviation int64
+	for _, sample := range samples {
+		deviation += max(sample.latency-a.avg, a.avg-sample.latency)
+	}
+	a.mad = deviation / int64(len(samples))
+
+	return a
+}
+
+// Doctor reports the latency spikes of every event along with advices, as Redis does.
+func (m *LatencyMonitor) Doctor(slowlogLogSlowerThan int64) spec.Data {
+	if len(m.series) == 0 {
+		if m.cfg.LatencyMonitorThreshold == 0 {
+			return spec.VerbatimStringOf("txt", "I'm sorry, Dave, I can't do that. Latency monitoring is "+
+				"disabled in this Redis instance. You may use \"CONFIG SET latency-monitor-threshold "+
+				"<milliseconds>.\" in order to enable it. If we weren't in a deep space mission I'd "+
+				"suggest to take a look at https://redis.io/topics/latency-monitor.\n")
+		}
+
+		return spec.VerbatimStringOf("txt", "Dave, no latency spike was observed during the lifetime of "+
+			"this Redis instance, not in the slightest bit. I honestly think you ought to sleep soundly "+
+			"tonight.\n")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Dave, I have observed latency spikes in this Redis instance. " +
+		"You don't mind talking about it, do you Dave?\n\n")
+
+	var advices []string
+	advise := func(advice string) {
+		if !slices.Contains(advices, advice) {
+			advices = append(advices, advice)
+		}
+	}
+
+	now := time.Now().Unix()
+	for i, event := range slices.Sorted(maps.Keys(m.series)) {
+		s := m.series[event]
+		a := s.analyze(now)
+		fmt.Fprintf(&sb, "%d. %s: %d latency spikes (average %dms, mean deviation %dms, period %.2f sec). "+
+			"Worst all time event %dms.\n",
+			i+1, event, a.samples, a.avg, a.mad, float64(a.period)/float64(a.samples), s.max)
+
+		switch event {
+		case latencyEventCommand:
+			threshold := m.cfg.LatencyMonitorThreshold * 1000
+			switch {
+			case slowlogLogSlowerThan < 0:
+				advise("- The slow log is disabled in your instance. Please enable it with " +
+					"'CONFIG SET slowlog-log-slower-than <microseconds>' to track the slow commands.\n")
+			case slowlogLogSlowerThan > threshold:
+				advise(fmt.Sprintf("- Your current Slow Log configuration only logs events that are slower "+
+					"than your configured latency monitor threshold. Please use "+
+					"'CONFIG SET slowlog-log-slower-than %d'.\n", threshold))
+			}
+			advise("- Check your Slow Log to understand what are the commands you are running which are too " +
+				"slow to execute. Please check https://redis.io/commands/slowlog for more information.\n")
+		case latencyEventExpireCycle, latencyEventEvictionCycle:
+			advise("- Deleting, expiring or evicting (because of maxmemory policy) large objects is a " +
+				"blocking operation. If you have very large objects that are often deleted, expired, or " +
+				"evicted, try to fragment those objects into multiple smaller objects.\n")
+		}
+	}
+
+	if len(advices) == 0 {
+		sb.WriteString("\nWhile there are latency events logged, I'm not able to suggest any easy fix. " +
+			"Please use the Redis community to get some help, providing this report in your help request.\n")
+		return spec.VerbatimStringOf("txt", sb.String())
+	}
+
+	sb.WriteString("\nI have a few advices for you:\n\n")
+	for _, advice := range advices {
+		sb.WriteString(advice)
+	}
+
+	return spec.VerbatimStringOf("txt", sb.String())
+}
+
+// the events of the latency monitor
+const (
+	latencyEventCommand       = "command"
+	latencyEventExpireCycle   = "expire-cycle"
+	latencyEventEvictionCycle = "eviction-cycle"
+)
+
+// latencyHistogram counts the calls of a command by their duration in buckets of powers of
+// two microseconds, as the HDR histograms of Redis reported by LATENCY HISTOGRAM.
+type latencyHistogram [64]int64
+
+// record counts the duration in the bucket of the lowest power of two not below it.
+func (h *latencyHistogram) record(duration time.Duration) {
+	usec := max(duration.Microseconds(), 1)
+	h[bits.Len64(uint64(usec-1))]++
+}
+
+// cumulative replies the count of calls up to every bucket, skipping buckets without calls.
+func (h *latencyHistogram) cumulative() spec.Data {
+	var replies []spec.Data
+	var count int64
+	for i, n := range h {
+		if n == 0 {
+			continue
+		}
+
+		count += n
+		replies = append(replies, spec.IntegerOf(1<<i), spec.IntegerOf(count))
+	}
+
+	return spec.MapOf(replies...)
+}
+
+// latencyHistograms replies the calls of the commands and their histograms, or of every
+// command called when none is given. Commands never called are skipped.
+func (e *Executor) latencyHistograms(commands []string) spec.Data {
+	if len(commands) == 0 {
+		commands = slices.Sorted(maps.Keys(e.commandStats))
+	}
+
+	var replies []spec.Data
+	for _, name := range commands {
+		name = strings.ToLower(name)
+		stat, found := e.commandStats[name]
+		if !found || stat.calls == 0 {
+			continue
+		}
+
+		replies = append(replies, spec.BulkStringOf(name), spec.MapOf(
+			spec.BulkStringOf("calls"), spec.IntegerOf(stat.calls),
+			spec.BulkStringOf("histogram_usec"), stat.histogram.cumulative(),
+		))
+	}
+
+	return spec.MapOf(replies...)
+}
@@ -4,14 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/big"
 	"strconv"
 
 	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/pkg"
 	"github.com/codecrafters-io/redis-starter-go/spec"
 )
 
 type Lexer struct {
-	stateMap map[uint64]*lexingState // lexing is only executed once at a time, so no need for concurrency
+	stateMap map[uint64]*lexingState // keyed by client ID; lexing is only executed once at a time, so no need for concurrency
 }
 
 func NewLexer() *Lexer {
@@ -26,6 +28,11 @@ func (p *Lexer) LexingHandler() *lexingHandler {
 	}
 }
 
+// RemoveClient drops the partially lexed data of a closed client.
+func (p *Lexer) RemoveClient(id uint64) {
+	delete(p.stateMap, id)
+}
+
 func (p *Lexer) Lexing(id uint64, data []byte) (*lexingState, error) {
 	var err error
 
@@ -55,15 +62,17 @@ func (p *Lexer) Lexing(id uint64, data []byte) (*lexingState, error) {
 }
 
 func (p *Lexer) beginLexing(data []byte) (*lexingState, error) {
-	typeId := data[0]
-	body := data[1:]
+	// like Redis, a request not starting with an array is an inline command
+	if len(data) == 0 || data[0] != ArrayPrefix {
+		return p.lexingInline(data)
+	}
 
-	redisData, err := p.lexingOf(typeId, body)
+	redisData, err := p.lexingLine(data)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create data from type %c: %w", typeId, err)
+		return nil, err
 	}
 
-	if redisData.Incomplete() {
+	if awaitingPayload(redisData) {
 		return &lexingState{
 			incompleteDataStack: []spec.Data{redisData},
 		}, nil
@@ -72,6 +81,21 @@ func (p *Lexer) beginLexing(data []byte) (*lexingState, error) {
 	}
 }
 
+// lexingInline lexes a space separated command sent by telnet-like clients into an
+// array of bulk strings. Empty lines are skipped, leaving no complete data.
+func (p *Lexer) lexingInline(data []byte) (*lexingState, error) {
+	args, err := pkg.SplitArgs(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("protocol error in inline command: %w", err)
+	}
+
+	if len(args) == 0 {
+		return &lexingState{}, nil
+	}
+
+	return &lexingState{completeData: spec.BulkStringArrayOf(args...)}, nil
+}
+
 func (p *Lexer) continueLexing(data []byte, state *lexingState) (*lexingState, error) {
 	if state.complete() {
 		return state, nil
@@ -92,27 +116,52 @@ func (p *Lexer) continueLexing(data []byte, state *lexingState) (*lexingState, e
 		bulkString.S = string(data)
 		state.completeData = bulkString
 
-	case *spec.ArrayData:
-		array := continueData.(*spec.ArrayData)
-		elem, err := p.lexingOf(data[0], data[1:])
+	case *spec.BulkErrorData:
+		bulkError := continueData.(*spec.BulkErrorData)
+		if bulkError.Len != len(data) {
+			return nil, fmt.Errorf("bulk error length mismatch: expected %d, got %d", bulkError.Len, len(data))
+		}
+
+		bulkError.S = string(data)
+		state.completeData = bulkError
+
+	case *spec.VerbatimStringData:
+		verbatimString := continueData.(*spec.VerbatimStringData)
+		if verbatimString.Len != len(data) {
+			return nil, fmt.Errorf("verbatim string length mismatch: expected %d, got %d", verbatimString.Len, len(data))
+		}
+
+		if len(data) < 4 || data[3] != ':' {
+			return nil, errors.New("verbatim string must start with a 3 characters format and ':'")
+		}
+
+		verbatimString.Format = string(data[:3])
+		verbatimString.S = string(data[4:])
+		state.completeData = verbatimString
+
+	case *spec.ArrayData, *spec.MapData, *spec.SetData, *spec.AttributeData, *spec.PushData:
+		elem, err := p.lexingLine(data)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create array element from data: %w", err)
+			return nil, fmt.Errorf("failed to create aggregate element from data: %w", err)
 		}
-		array.A = append(array.A, elem)
+		appendElement(continueData, elem)
 
 		switch {
-		case elem.Incomplete():
-			state.incompleteDataStack = append(state.incompleteDataStack, array)
+		case awaitingPayload(elem):
+			state.incompleteDataStack = append(state.incompleteDataStack, continueData)
 			state.incompleteDataStack = append(state.incompleteDataStack, elem)
-		case len(array.A) < array.Len:
-			state.incompleteDataStack = append(state.incompleteDataStack, array)
+		case continueData.Incomplete():
+			state.incompleteDataStack = append(state.incompleteDataStack, continueData)
+		default:
+			// completed by an element without payload, such as an integer
+			state.completeData = continueData
 		}
 	}
 
 	// handle nested completion case (ex. array)
 	for !state.complete() {
 		lastData := state.incompleteDataStack[len(state.incompleteDataStack)-1]
-		if lastData.Incomplete() {
+		if awaitingPayload(lastData) {
 			break
 		}
 
@@ -123,6 +172,36 @@ func (p *Lexer) continueLexing(data []byte, state *lexingState) (*lexingState, e
 	return state, nil
 }
 
+// awaitingPayload reports whether the data lexed from a line is followed by more lines.
+// A bulk header on the stack is always followed by its payload, even an empty one, which
+// would be taken as complete already by its length.
+func awaitingPayload(data spec.Data) bool {
+	switch d := data.(type) {
+	case *spec.BulkStringData:
+		return d.Len >= 0
+	case *spec.BulkErrorData:
+		return d.Len >= 0
+	case *spec.VerbatimStringData:
+		return d.Len >= 0
+	default:
+		return data.Incomplete()
+	}
+}
+
+func (p *Lexer) lexingLine(data []byte) (spec.Data, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty line")
+	}
+
+	typeId := data[0]
+	redisData, err := p.lexingOf(typeId, data[1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create data from type %c: %w", typeId, err)
+	}
+
+	return redisData, nil
+}
+
 func (p *Lexer) lexingOf(typeId byte, body []byte) (spec.Data, error) {
 	switch typeId {
 	case SimpleStringPrefix:
@@ -153,13 +232,95 @@ func (p *Lexer) lexingOf(typeId byte, body []byte) (spec.Data, error) {
 			return nil, fmt.Errorf("failed to read arrays length: %w", err)
 		}
 
-		return &spec.ArrayData{Len: l, A: make([]spec.Data, 0, l)}, nil
+		return &spec.ArrayData{Len: l, A: make([]spec.Data, 0, max(l, 0))}, nil
+
+	case NullPrefix:
+		if len(body) != 0 {
+			return nil, errors.New("null must not have a body")
+		}
+
+		return spec.Null(), nil
+
+	case BooleanPrefix:
+		switch string(body) {
+		case "t":
+			return spec.BooleanOf(true), nil
+		case "f":
+			return spec.BooleanOf(false), nil
+		default:
+			return nil, fmt.Errorf("invalid boolean: %s", body)
+		}
+
+	case DoublePrefix:
+		f, err := strconv.ParseFloat(string(body), 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read double: %w", err)
+		}
+
+		return spec.DoubleOf(f), nil
+
+	case BigNumberPrefix:
+		n, ok := new(big.Int).SetString(string(body), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid big number: %s", body)
+		}
+
+		return &spec.BigNumberData{N: n}, nil
+
+	case BulkErrorPrefix, VerbatimStringPrefix:
+		l, err := strconv.Atoi(string(body))
+		if err != nil || l < 0 {
+			return nil, fmt.Errorf("invalid length: %s", body)
+		}
+
+		if typeId == BulkErrorPrefix {
+			return &spec.BulkErrorData{Len: l}, nil
+		} else {
+			return &spec.VerbatimStringData{Len: l}, nil
+		}
+
+	case MapPrefix, SetPrefix, AttributePrefix, PushPrefix:
+		l, err := strconv.Atoi(string(body))
+		if err != nil || l < 0 {
+			return nil, fmt.Errorf("invalid aggregate length: %s", body)
+		}
+
+		switch typeId {
+		case MapPrefix:
+			return &spec.MapData{Len: l, A: make([]spec.Data, 0, 2*l)}, nil
+		case SetPrefix:
+			return &spec.SetData{Len: l, A: make([]spec.Data, 0, l)}, nil
+		case AttributePrefix:
+			return &spec.AttributeData{Len: l, A: make([]spec.Data, 0, 2*l)}, nil
+		default:
+			return &spec.PushData{Len: l, A: make([]spec.Data, 0, l)}, nil
+		}
 
 	default:
 		return nil, errors.New("invalid first byte: " + string(typeId))
 	}
 }
 
+func appendElement(aggregate spec.Data, elem spec.Data) {
+	switch aggregate.(type) {
+	case *spec.ArrayData:
+		array := aggregate.(*spec.ArrayData)
+		array.A = append(array.A, elem)
+	case *spec.MapData:
+		m := aggregate.(*spec.MapData)
+		m.A = append(m.A, elem)
+	case *spec.SetData:
+		set := aggregate.(*spec.SetData)
+		set.A = append(set.A, elem)
+	case *spec.AttributeData:
+		attribute := aggregate.(*spec.AttributeData)
+		attribute.A = append(attribute.A, elem)
+	case *spec.PushData:
+		push := aggregate.(*spec.PushData)
+		push.A = append(push.A, elem)
+	}
+}
+
 type lexingState struct {
 	incompleteDataStack []spec.Data
 	completeData        spec.Data
@@ -189,7 +350,11 @@ func (h *lexingHandler) Handle(e event.Event, push func(event.Event)) error {
 		return fmt.Errorf("fail to handle lexing event: %w", err)
 	}
 
-	if state.complete() {
+	switch {
+	case state.complete() && state.completeData == nil:
+		// an empty inline command is skipped
+
+	case state.complete():
 		slog.Info(
 			"lexing complete",
 			slog.Uint64("id", lexingEvent.ID()),
@@ -199,12 +364,17 @@ func (h *lexingHandler) Handle(e event.Event, push func(event.Event)) error {
 			ID_:  lexingEvent.ID(),
 			Data: state.completeData,
 		})
-	} else {
+
+	default:
 		slog.Info(
 			"lexing in progress",
 			slog.Uint64("id", lexingEvent.ID()),
 			slog.Any("incompleteDataStackTop", state.incompleteDataStack[len(state.incompleteDataStack)-1]),
 		)
+	}
+
+	// commands are handled in order, so reading can go on before their replies are written
+	if lexingEvent.Last {
 		push(&event.ReadEvent{ID_: lexingEvent.ID()})
 	}
 
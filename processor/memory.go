@@ -0,0 +1,186 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+const (
+	// MEMORY DOCTOR only reports on instances using more memory than this
+	doctorMinMemory = 5 * 1024 * 1024
+
+	doctorPeakRatio          = 1.5
+	doctorFragmentationRatio = 1.4
+	doctorClientBufferAvg    = 200 * 1024
+	doctorScriptsCount       = 1000
+)
+
+// memoryUsage returns the memory used by the key. Values have no nested elements, so
+// they are measured as a whole whatever the number of samples.
+func (e *Executor) memoryUsage(key string) spec.Data {
+	obj, found := e.storage.Object(key)
+	if !found {
+		return spec.NullBulkString()
+	}
+
+	return spec.IntegerOf(obj.Size)
+}
+
+// memoryOverview is the breakdown of the memory reported by MEMORY STATS and MEMORY DOCTOR.
+type memoryOverview struct {
+	peak         uint64
+	total        uint64
+	startup      uint64
+	clients      uint64 // query and output buffers
+	clientsCount int
+	scripts      uint64
+	functions    uint64
+	mainOverhead uint64 // of the keyspace
+	keys         int
+	dataset      uint64
+	heapInUse    uint64
+}
+
+func (e *Executor) memoryOverview() memoryOverview {
+	ms := e.memStats()
+	stats := e.storage.Stats()
+
+	var clients uint64
+	all := e.clients.All()
+	for _, client := range all {
+		clients += uint64(client.QueryBufferLen() + client.OutputBufferLen())
+	}
+
+	var functions uint64
+	for _, lib := range e.libraries {
+		functions += uint64(len(lib.Code))
+	}
+
+	return memoryOverview{
+		peak:         e.memoryPeak,
+		total:        ms.Alloc,
+		startup:      e.startupMemory,
+		clients:      clients,
+		clientsCount: len(all),
+		scripts:      uint64(e.scriptsMemory()),
+		functions:    functions,
+		mainOverhead: uint64(stats.Overhead),
+		keys:         stats.Keys,
+		dataset:      uint64(stats.UsedMemory - stats.Overhead),
+		heapInUse:    ms.HeapInuse,
+	}
+}
+
+func (m memoryOverview) overhead() uint64 {
+	return m.startup + m.clients + m.scripts + m.functions + m.mainOverhead
+}
+
+// fragmentation is the ratio of the heap in use by the allocator to the allocated memory.
+func (m memoryOverview) fragmentation() float64 {
+	if m.total == 0 {
+		return 0
+	}
+
+	return float64(m.heapInUse) / float64(m.total)
+}
+
+func (e *Executor) memoryStats() spec.Data {
+	m := e.memoryOverview()
+
+	net := max(m.total, m.startup) - m.startup
+	var bytesPerKey uint64
+	var datasetPercentage float64
+	if m.keys > 0 {
+		bytesPerKey = net / uint64(m.keys)
+	}
+	if net > 0 {
+		datasetPercentage = float64(m.dataset) * 100 / float64(net)
+	}
+	var peakPercentage float64
+	if m.peak > 0 {
+		peakPercentage = float64(m.total) * 100 / float64(m.peak)
+	}
+
+	integer := func(n uint64) spec.Data { return spec.IntegerOf(int64(n)) }
+	return spec.MapOf(
+		spec.BulkStringOf("peak.allocated"), integer(m.peak),
+		spec.BulkStringOf("total.allocated"), integer(m.total),
+		spec.BulkStringOf("startup.allocated"), integer(m.startup),
+		spec.BulkStringOf("replication.backlog"), integer(0),
+		spec.BulkStringOf("clients.slaves"), integer(0),
+		spec.BulkStringOf("clients.normal"), integer(m.clients),
+		spec.BulkStringOf("aof.buffer"), integer(0),
+		spec.BulkStringOf("lua.caches"), integer(m.scripts),
+		spec.BulkStringOf("functions.caches"), integer(m.functions),
+		spec.BulkStringOf("db.0"), spec.MapOf(
+			spec.BulkStringOf("overhead.hashtable.main"), integer(m.mainOverhead),
+		),
+		spec.BulkStringOf("overhead.total"), integer(m.overhead()),
+		spec.BulkStringOf("keys.count"), integer(uint64(m.keys)),
+		spec.BulkStringOf("keys.bytes-per-key"), integer(bytesPerKey),
+		spec.BulkStringOf("dataset.bytes"), integer(m.dataset),
+		spec.BulkStringOf("dataset.percentage"), spec.DoubleOf(datasetPercentage),
+		spec.BulkStringOf("peak.percentage"), spec.DoubleOf(peakPercentage),
+		spec.BulkStringOf("allocator.allocated"), integer(m.total),
+		spec.BulkStringOf("allocator.active"), integer(m.heapInUse),
+		spec.BulkStringOf("fragmentation"), spec.DoubleOf(m.fragmentation()),
+		spec.BulkStringOf("fragmentation.bytes"), integer(max(m.heapInUse, m.total)-m.total),
+	)
+}
+
+// memoryDoctor reports the issues found in the memory usage, as Redis does.
+func (e *Executor) memoryDoctor() spec.Data {
+	m := e.memoryOverview()
+	if m.total < doctorMinMemory {
+		return spec.VerbatimStringOf("txt", "Hi Sam, this instance is empty or is using very little memory, "+
+			"my issues detector can't be used in these conditions. Please, leave for your mission on Earth "+
+			"and fill it with some data. The new Sam and I will be back to our programming as soon as I "+
+			"finished rebooting.")
+	}
+
+	var issues []string
+	if float64(m.peak) > float64(m.total)*doctorPeakRatio {
+		issues = append(issues, "Peak memory: In the past this instance used more than 150% the memory "+
+			"that is currently using. The allocator is normally not able to release memory after a peak, "+
+			"so you can expect to see a big fragmentation ratio, however this is actually harmless and is "+
+			"only due to the memory peak. If the memory peak was only occasional and you want to try to "+
+			"reclaim memory, please try the MEMORY PURGE command.")
+	}
+	if m.fragmentation() > doctorFragmentationRatio {
+		issues = append(issues, fmt.Sprintf("High fragmentation: This instance has a memory fragmentation "+
+			"greater than %.1f (this means that the heap in use by the allocator is much larger than the "+
+			"sum of the logical allocations Redis performed). This problem is usually due either to a large "+
+			"peak memory (check if there is a peak memory entry above in the report) or may result from a "+
+			"workload that causes the allocator to fragment memory a lot.", doctorFragmentationRatio))
+	}
+	if m.clientsCount > 0 && m.clients/uint64(m.clientsCount) > doctorClientBufferAvg {
+		issues = append(issues, "Big client buffers: The clients output buffers are in general too big "+
+			"(average > 200kb). This instance is probably serving clients which are not able to consume "+
+			"the replies as fast as they are produced. You can use CLIENT LIST to inspect the omem field "+
+			"of the clients, and client-output-buffer-limit to bound them.")
+	}
+	if len(e.scripts) > doctorScriptsCount {
+		issues = append(issues, "Many scripts: There seem to be many cached scripts in this instance "+
+			"(more than 1000). This may be because scripts are generated and `EVAL`ed, instead of being "+
+			"parameterized (with KEYS and ARGV), `SCRIPT LOAD`ed and `EVALSHA`ed. Unless `SCRIPT FLUSH` "+
+			"is called periodically, the scripts' caches may end up consuming most of your memory.")
+	}
+
+	if len(issues) == 0 {
+		return spec.VerbatimStringOf("txt", "Hi Sam, I can't find any memory issue in your instance. "+
+			"I can only account for what occurs on this base.")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Sam, I detected a few issues in this Redis instance memory implants:\n\n")
+	for _, issue := range issues {
+		sb.WriteString(" * ")
+		sb.WriteString(issue)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("I'm here to keep you safe, Sam. I want to help you.\n")
+
+	return spec.VerbatimStringOf("txt", sb.String())
+}
@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// monitorSkipped are the administrative commands not fed to monitors, as in Redis.
+var monitorSkipped = []string{"config", "debug", "slowlog", "latency", "monitor", "shutdown"}
+
+// monitor turns the connection into a monitor, which is fed with every command processed
+// later until it is closed.
+func (e *Executor) monitor(id uint64) (spec.Data, error) {
+	if _, inMulti := e.transactions[id]; inMulti {
+		return nil, spec.ErrorOf("ERR", "Command not allowed inside a transaction")
+	}
+
+	client, found := e.clients.Get(id)
+	if !found || client.monitor {
+		return nil, nil // a monitor is not replied again
+	}
+
+	client.monitor = true
+	e.monitors[id] = struct{}{}
+	return spec.SimpleStringOf("OK"), nil
+}
+
+// feedMonitors pushes the command to every monitor along with the time it is processed and
+// its source, the address of the client or "lua" for commands called from scripts.
+// Monitors are fed through their output buffers, so a slow monitor does not hold up others.
+func (e *Executor) feedMonitors(source string, args []string) {
+	if len(e.monitors) == 0 || len(args) == 0 || slices.Contains(monitorSkipped, strings.ToLower(args[0])) {
+		return
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d.%06d [0 %s]", now.Unix(), now.Nanosecond()/int(time.Microsecond), source)
+	for _, arg := range args {
+		sb.WriteByte(' ')
+		sb.WriteString(pkg.Repr(arg))
+	}
+
+	line := spec.SimpleStringOf(sb.String())
+	for id := range e.monitors {
+		e.push(&event.FormatEvent{
+			ID_:  id,
+			Data: line,
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package processor
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/config"
+	"github.com/codecrafters-io/redis-starter-go/storage"
+)
+
+const (
+	keyspaceChannelPrefix = "__keyspace@0__:"
+	keyeventChannelPrefix = "__keyevent@0__:"
+)
+
+// KeyspaceNotifier publishes storage key events to the keyspace and keyevent
+// channels, filtered by the notify-keyspace-events flags.
+type KeyspaceNotifier struct {
+	flags  storage.NotifyFlag
+	pubsub *PubSub
+}
+
+func NewKeyspaceNotifier(cfg *config.Config, pubsub *PubSub) *KeyspaceNotifier {
+	n := &KeyspaceNotifier{
+		pubsub: pubsub,
+	}
+	n.SetFlags(cfg.NotifyKeyspaceEvents)
+
+	return n
+}
+
+func (n *KeyspaceNotifier) Flags() storage.NotifyFlag {
+	return n.flags
+}
+
+func (n *KeyspaceNotifier) SetFlags(flags storage.NotifyFlag) {
+	// without K or E nothing would be published at all
+	if flags&(storage.NotifyKeyspace|storage.NotifyKeyevent) == 0 {
+		flags = 0
+	}
+
+	n.flags = flags
+}
+
+func (n *KeyspaceNotifier) Notify(e storage.KeyEvent) {
+	if n.flags&e.Class == 0 {
+		return
+	}
+
+	if n.flags&storage.NotifyKeyspace != 0 {
+		n.pubsub.Publish(keyspaceChannelPrefix+e.Key, e.Event)
+	}
+
+	if n.flags&storage.NotifyKeyevent != 0 {
+		n.pubsub.Publish(keyeventChannelPrefix+e.Event, e.Key)
+	}
+}
@@ -0,0 +1,49 @@
+package processor
+
+import (
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+func (e *Executor) objectEncoding(key string) spec.Data {
+	obj, found := e.storage.Object(key)
+	if !found {
+		return spec.NullBulkString()
+	}
+
+	return spec.BulkStringOf(obj.Encoding)
+}
+
+func (e *Executor) objectRefCount(key string) spec.Data {
+	obj, found := e.storage.Object(key)
+	if !found {
+		return spec.NullBulkString()
+	}
+
+	return spec.IntegerOf(obj.RefCount)
+}
+
+func (e *Executor) objectIdleTime(key string) (spec.Data, error) {
+	if e.cfg.MaxMemoryPolicy.LFU() {
+		return nil, spec.ErrorOf("ERR", "An LFU maxmemory policy is selected, idle time not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+	}
+
+	obj, found := e.storage.Object(key)
+	if !found {
+		return spec.NullBulkString(), nil
+	}
+
+	return spec.IntegerOf(int64(obj.Idle.Seconds())), nil
+}
+
+func (e *Executor) objectFreq(key string) (spec.Data, error) {
+	if !e.cfg.MaxMemoryPolicy.LFU() {
+		return nil, spec.ErrorOf("ERR", "An LFU maxmemory policy is not selected, access frequency not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+	}
+
+	obj, found := e.storage.Object(key)
+	if !found {
+		return spec.NullBulkString(), nil
+	}
+
+	return spec.IntegerOf(int64(obj.Freq)), nil
+}
@@ -0,0 +1,27 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/config"
+)
+
+// outputLimitReached reports whether the client with the output buffer of the given size
+// exceeds the limit, keeping the time the soft limit is first reached in the client.
+func outputLimitReached(l config.OutputBufferLimit, client *Client, used int64, now time.Time) bool {
+	if l.Hard > 0 && used >= l.Hard {
+		return true
+	}
+
+	if l.Soft == 0 || used < l.Soft {
+		client.softLimitReachedAt = time.Time{}
+		return false
+	}
+
+	if client.softLimitReachedAt.IsZero() {
+		client.softLimitReachedAt = now
+		return false
+	}
+
+	return now.Sub(client.softLimitReachedAt) > time.Duration(l.SoftSeconds)*time.Second
+}
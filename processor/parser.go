@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -13,6 +14,16 @@ import (
 	"github.com/codecrafters-io/redis-starter-go/spec"
 )
 
+// errUnknownCommand is wrapped by the errors of commands not implemented, which are not
+// reported by INFO commandstats.
+var errUnknownCommand = errors.New("unknown command")
+
+// defaultMemorySamples is the number of nested values sampled by MEMORY USAGE by default.
+const defaultMemorySamples = 5
+
+// defaultSlowlogCount is the number of entries replied by SLOWLOG GET by default.
+const defaultSlowlogCount = 10
+
 type Parser struct{}
 
 func NewParser() *Parser {
@@ -59,9 +70,290 @@ func (p *Parser) Parse(data spec.Data) (spec.Command, error) {
 
 		return setCmd, nil
 
+	case "SUBSCRIBE", "PSUBSCRIBE":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for %s command: %w", cmdStr, err)
+		}
+
+		if len(args) == 0 {
+			return nil, fmt.Errorf("invalid %s command format: expected at least 1 argument", cmdStr)
+		}
+
+		if cmdStr == "SUBSCRIBE" {
+			return &spec.SubscribeCommand{Channels: args}, nil
+		} else {
+			return &spec.PSubscribeCommand{Patterns: args}, nil
+		}
+
+	case "UNSUBSCRIBE", "PUNSUBSCRIBE":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for %s command: %w", cmdStr, err)
+		}
+
+		if cmdStr == "UNSUBSCRIBE" {
+			return &spec.UnsubscribeCommand{Channels: args}, nil
+		} else {
+			return &spec.PUnsubscribeCommand{Patterns: args}, nil
+		}
+
+	case "PUBLISH":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for PUBLISH command: %w", err)
+		}
+
+		if len(args) != 2 {
+			return nil, fmt.Errorf("invalid PUBLISH command format: expected 2 arguments, got %d", len(args))
+		}
+
+		return &spec.PublishCommand{Channel: args[0], Message: args[1]}, nil
+
+	case "MULTI", "EXEC", "DISCARD", "MONITOR":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for %s command: %w", cmdStr, err)
+		}
+
+		if len(args) != 0 {
+			return nil, fmt.Errorf("invalid %s command format: expected no arguments, got %d", cmdStr, len(args))
+		}
+
+		switch cmdStr {
+		case "MULTI":
+			return &spec.MultiCommand{}, nil
+		case "EXEC":
+			return &spec.ExecCommand{}, nil
+		case "MONITOR":
+			return &spec.MonitorCommand{}, nil
+		default:
+			return &spec.DiscardCommand{}, nil
+		}
+
+	case "WATCH":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for WATCH command: %w", err)
+		}
+
+		if len(args) == 0 {
+			return nil, errors.New("invalid WATCH command format: expected at least 1 argument")
+		}
+
+		return &spec.WatchCommand{Keys: args}, nil
+
+	case "UNWATCH":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for UNWATCH command: %w", err)
+		}
+
+		if len(args) != 0 {
+			return nil, fmt.Errorf("invalid UNWATCH command format: expected no arguments, got %d", len(args))
+		}
+
+		return &spec.UnwatchCommand{}, nil
+
+	case "FLUSHDB":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for FLUSHDB command: %w", err)
+		}
+
+		// flushing is always synchronous, but the modifiers are accepted for compatibility
+		if len(args) > 1 || (len(args) == 1 && !slices.Contains([]string{"ASYNC", "SYNC"}, strings.ToUpper(args[0]))) {
+			return nil, errors.New("invalid FLUSHDB command format: expected optional ASYNC or SYNC")
+		}
+
+		return &spec.FlushDBCommand{}, nil
+
+	case "EVAL", "EVALSHA":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for %s command: %w", cmdStr, err)
+		}
+
+		if len(args) < 2 {
+			return nil, fmt.Errorf("invalid %s command format: expected at least 2 arguments, got %d", cmdStr, len(args))
+		}
+
+		keys, scriptArgs, err := p.parseKeysAndArgs(args[1], args[2:])
+		if err != nil {
+			return nil, err
+		}
+
+		if cmdStr == "EVAL" {
+			return &spec.EvalCommand{Script: args[0], Keys: keys, Args: scriptArgs}, nil
+		} else {
+			return &spec.EvalShaCommand{SHA: strings.ToLower(args[0]), Keys: keys, Args: scriptArgs}, nil
+		}
+
+	case "FCALL", "FCALL_RO":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for %s command: %w", cmdStr, err)
+		}
+
+		if len(args) < 2 {
+			return nil, fmt.Errorf("invalid %s command format: expected at least 2 arguments, got %d", cmdStr, len(args))
+		}
+
+		keys, fnArgs, err := p.parseKeysAndArgs(args[1], args[2:])
+		if err != nil {
+			return nil, err
+		}
+
+		return &spec.FCallCommand{Function: args[0], Keys: keys, Args: fnArgs, ReadOnly: cmdStr == "FCALL_RO"}, nil
+
+	case "FUNCTION":
+		functionCmd, err := p.parseFunctionCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for FUNCTION command: %w", err)
+		}
+
+		return functionCmd, nil
+
+	case "SCRIPT":
+		scriptCmd, err := p.parseScriptCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for SCRIPT command: %w", err)
+		}
+
+		return scriptCmd, nil
+
+	case "AUTH":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for AUTH command: %w", err)
+		}
+
+		switch len(args) {
+		case 1:
+			return &spec.AuthCommand{Password: args[0]}, nil
+		case 2:
+			return &spec.AuthCommand{Username: &args[0], Password: args[1]}, nil
+		default:
+			return nil, fmt.Errorf("invalid AUTH command format: expected a password and an optional username, got %d arguments", len(args))
+		}
+
+	case "HELLO":
+		helloCmd, err := p.parseHelloCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for HELLO command: %w", err)
+		}
+
+		return helloCmd, nil
+
+	case "CLIENT":
+		clientCmd, err := p.parseClientCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for CLIENT command: %w", err)
+		}
+
+		return clientCmd, nil
+
+	case "INFO":
+		args, err := p.parseArguments(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for INFO command: %w", err)
+		}
+
+		sections := make([]string, 0, len(args))
+		for _, arg := range args {
+			sections = append(sections, strings.ToLower(arg))
+		}
+
+		return &spec.InfoCommand{Sections: sections}, nil
+
+	case "CONFIG":
+		configCmd, err := p.parseConfigCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for CONFIG command: %w", err)
+		}
+
+		return configCmd, nil
+
+	case "LATENCY":
+		latencyCmd, err := p.parseLatencyCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for LATENCY command: %w", err)
+		}
+
+		return latencyCmd, nil
+
+	case "ACL":
+		aclCmd, err := p.parseACLCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for ACL command: %w", err)
+		}
+
+		return aclCmd, nil
+
+	case "SLOWLOG":
+		slowlogCmd, err := p.parseSlowlogCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for SLOWLOG command: %w", err)
+		}
+
+		return slowlogCmd, nil
+
+	case "MEMORY":
+		memoryCmd, err := p.parseMemoryCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for MEMORY command: %w", err)
+		}
+
+		return memoryCmd, nil
+
+	case "OBJECT":
+		objectCmd, err := p.parseObjectCommand(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid format for OBJECT command: %w", err)
+		}
+
+		return objectCmd, nil
+
 	default:
-		return nil, fmt.Errorf("invalid command: %s", cmdStr)
+		return nil, fmt.Errorf("%w: %w", errUnknownCommand, spec.ErrorOf("ERR", "unknown command '%s'", cmdStr))
+	}
+}
+
+// parseArguments returns the arguments following the command name as strings.
+func (p *Parser) parseArguments(data spec.Data) ([]string, error) {
+	arrData, isType := data.(*spec.ArrayData)
+	if !isType {
+		return nil, fmt.Errorf("data type %T is not array data type", data)
+	}
+
+	args := make([]string, 0, len(arrData.A))
+	for i, argData := range arrData.A[1:] {
+		arg, err := spec.Value[string](argData)
+		if err != nil {
+			return nil, fmt.Errorf("invalid type for argument %d: %w", i+1, err)
+		}
+		args = append(args, arg)
 	}
+
+	return args, nil
+}
+
+// parseCommandArgs returns the command name and arguments as sent, skipping
+// anything that is not a string, to be reported by introspection commands.
+func (p *Parser) parseCommandArgs(data spec.Data) []string {
+	arr, err := spec.Value[[]spec.Data](data)
+	if err != nil {
+		return nil
+	}
+
+	args := make([]string, 0, len(arr))
+	for _, argData := range arr {
+		if arg, err := spec.Value[string](argData); err == nil {
+			args = append(args, arg)
+		}
+	}
+
+	return args
 }
 
 func (p *Parser) parseCommandString(data spec.Data) (string, error) {
@@ -138,7 +430,7 @@ func (p *Parser) parseSetCommand(data spec.Data) (*spec.SetCommand, error) {
 	}
 
 	// NOTE: after supporting extra options, this should be changed
-	if arrData.Len < 2 {
+	if arrData.Len < 3 {
 		return nil, fmt.Errorf(
 			"invalid SET command format: expected at least 2 arguments, got %d",
 			arrData.Len-1,
@@ -203,6 +495,811 @@ func (p *Parser) parseSetCommand(data spec.Data) (*spec.SetCommand, error) {
 	return setCmd, nil
 }
 
+func (p *Parser) parseHelloCommand(data spec.Data) (*spec.HelloCommand, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	helloCmd := &spec.HelloCommand{}
+	if len(args) == 0 {
+		return helloCmd, nil
+	}
+
+	protocol, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, spec.ErrorOf("ERR", "Protocol version is not an integer or out of range")
+	}
+	helloCmd.Protocol = &protocol
+
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "AUTH":
+			if i+2 >= len(args) {
+				return nil, spec.ErrorOf("ERR", "Syntax error in HELLO option '%s'", args[i])
+			}
+
+			helloCmd.Auth = &spec.HelloAuth{Username: args[i+1], Password: args[i+2]}
+			i += 2
+
+		case "SETNAME":
+			if i+1 >= len(args) {
+				return nil, spec.ErrorOf("ERR", "Syntax error in HELLO option '%s'", args[i])
+			}
+
+			helloCmd.SetName = &args[i+1]
+			i++
+
+		default:
+			return nil, spec.ErrorOf("ERR", "Syntax error in HELLO option '%s'", args[i])
+		}
+	}
+
+	return helloCmd, nil
+}
+
+func (p *Parser) parseClientCommand(data spec.Data) (spec.Command, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("expected subcommand")
+	}
+
+	subArgs := args[1:]
+	subcommand := strings.ToUpper(args[0])
+	switch subcommand {
+	case "ID", "INFO", "GETNAME", "UNPAUSE", "GETREDIR", "TRACKINGINFO":
+		if len(subArgs) != 0 {
+			return nil, fmt.Errorf("invalid CLIENT %s format: expected no arguments", subcommand)
+		}
+
+		switch subcommand {
+		case "ID":
+			return &spec.ClientIDCommand{}, nil
+		case "INFO":
+			return &spec.ClientInfoCommand{}, nil
+		case "GETNAME":
+			return &spec.ClientGetNameCommand{}, nil
+		case "GETREDIR":
+			return &spec.ClientGetRedirCommand{}, nil
+		case "TRACKINGINFO":
+			return &spec.ClientTrackingInfoCommand{}, nil
+		default:
+			return &spec.ClientUnpauseCommand{}, nil
+		}
+
+	case "SETNAME":
+		if len(subArgs) != 1 {
+			return nil, errors.New("invalid CLIENT SETNAME format: expected 1 argument")
+		}
+
+		return &spec.ClientSetNameCommand{Name: subArgs[0]}, nil
+
+	case "LIST":
+		listCmd := &spec.ClientListCommand{}
+		for i := 0; i < len(subArgs); i++ {
+			switch strings.ToUpper(subArgs[i]) {
+			case "TYPE":
+				if i+1 >= len(subArgs) {
+					return nil, errors.New("client type argument was not given")
+				}
+				i++
+
+				clientType, err := parseClientType(subArgs[i])
+				if err != nil {
+					return nil, err
+				}
+				listCmd.Type = clientType
+
+			case "ID":
+				if i+1 >= len(subArgs) {
+					return nil, errors.New("client id argument was not given")
+				}
+
+				// IDs continue until the end of the arguments
+				for _, idStr := range subArgs[i+1:] {
+					id, err := strconv.ParseUint(idStr, 10, 64)
+					if err != nil {
+						return nil, fmt.Errorf("invalid client ID: %s", idStr)
+					}
+					listCmd.IDs = append(listCmd.IDs, id)
+				}
+				i = len(subArgs)
+
+			default:
+				return nil, fmt.Errorf("unknown argument %s", subArgs[i])
+			}
+		}
+
+		return listCmd, nil
+
+	case "KILL":
+		return p.parseClientKillCommand(subArgs)
+
+	case "PAUSE":
+		if len(subArgs) != 1 && len(subArgs) != 2 {
+			return nil, errors.New("invalid CLIENT PAUSE format: expected timeout [WRITE | ALL]")
+		}
+
+		ms, err := strconv.ParseInt(subArgs[0], 10, 64)
+		if err != nil || ms < 0 {
+			return nil, errors.New("timeout is not an integer or out of range")
+		}
+
+		pauseCmd := &spec.ClientPauseCommand{Timeout: time.Duration(ms) * time.Millisecond}
+		if len(subArgs) == 2 {
+			switch strings.ToUpper(subArgs[1]) {
+			case "WRITE":
+				pauseCmd.Write = true
+			case "ALL":
+			default:
+				return nil, fmt.Errorf("unknown pause mode %s", subArgs[1])
+			}
+		}
+
+		return pauseCmd, nil
+
+	case "NO-EVICT", "NO-TOUCH":
+		if len(subArgs) != 1 {
+			return nil, fmt.Errorf("invalid CLIENT %s format: expected ON or OFF", subcommand)
+		}
+
+		var on bool
+		switch strings.ToUpper(subArgs[0]) {
+		case "ON":
+			on = true
+		case "OFF":
+		default:
+			return nil, fmt.Errorf("invalid CLIENT %s format: expected ON or OFF", subcommand)
+		}
+
+		if subcommand == "NO-EVICT" {
+			return &spec.ClientNoEvictCommand{On: on}, nil
+		} else {
+			return &spec.ClientNoTouchCommand{On: on}, nil
+		}
+
+	case "REPLY":
+		if len(subArgs) != 1 {
+			return nil, errors.New("invalid CLIENT REPLY format: expected ON, OFF or SKIP")
+		}
+
+		mode := spec.ClientReplyMode(strings.ToUpper(subArgs[0]))
+		switch mode {
+		case spec.ClientReplyOn, spec.ClientReplyOff, spec.ClientReplySkip:
+			return &spec.ClientReplyCommand{Mode: mode}, nil
+		default:
+			return nil, errors.New("invalid CLIENT REPLY format: expected ON, OFF or SKIP")
+		}
+
+	case "TRACKING":
+		return p.parseClientTrackingCommand(subArgs)
+
+	case "CACHING":
+		if len(subArgs) != 1 {
+			return nil, errors.New("invalid CLIENT CACHING format: expected YES or NO")
+		}
+
+		switch strings.ToUpper(subArgs[0]) {
+		case "YES":
+			return &spec.ClientCachingCommand{Yes: true}, nil
+		case "NO":
+			return &spec.ClientCachingCommand{Yes: false}, nil
+		default:
+			return nil, errors.New("invalid CLIENT CACHING format: expected YES or NO")
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+}
+
+func (p *Parser) parseClientTrackingCommand(args []string) (*spec.ClientTrackingCommand, error) {
+	if len(args) == 0 {
+		return nil, errors.New("invalid CLIENT TRACKING format: expected ON or OFF")
+	}
+
+	trackingCmd := &spec.ClientTrackingCommand{}
+	switch strings.ToUpper(args[0]) {
+	case "ON":
+		trackingCmd.On = true
+	case "OFF":
+	default:
+		return nil, errors.New("invalid CLIENT TRACKING format: expected ON or OFF")
+	}
+
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "REDIRECT":
+			if i+1 >= len(args) {
+				return nil, errors.New("redirect client id was not given")
+			}
+			i++
+
+			id, err := strconv.ParseUint(args[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client ID: %s", args[i])
+			}
+			trackingCmd.Redirect = id
+
+		case "PREFIX":
+			if i+1 >= len(args) {
+				return nil, errors.New("prefix was not given")
+			}
+			i++
+
+			trackingCmd.Prefixes = append(trackingCmd.Prefixes, args[i])
+
+		case "BCAST":
+			trackingCmd.BCast = true
+		case "OPTIN":
+			trackingCmd.OptIn = true
+		case "OPTOUT":
+			trackingCmd.OptOut = true
+		case "NOLOOP":
+			trackingCmd.NoLoop = true
+		default:
+			return nil, fmt.Errorf("unknown argument %s", args[i])
+		}
+	}
+
+	return trackingCmd, nil
+}
+
+func (p *Parser) parseClientKillCommand(args []string) (*spec.ClientKillCommand, error) {
+	if len(args) == 1 {
+		return &spec.ClientKillCommand{Addr: args[0], Legacy: true}, nil
+	}
+
+	if len(args) == 0 || len(args)%2 != 0 {
+		return nil, errors.New("invalid CLIENT KILL format: expected filter value pairs")
+	}
+
+	killCmd := &spec.ClientKillCommand{SkipMe: true}
+	for i := 0; i < len(args); i += 2 {
+		value := args[i+1]
+		switch strings.ToUpper(args[i]) {
+		case "ID":
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("client-id should be greater than 0")
+			}
+			killCmd.ID = &id
+
+		case "TYPE":
+			clientType, err := parseClientType(value)
+			if err != nil {
+				return nil, err
+			}
+			killCmd.Type = clientType
+
+		case "USER":
+			killCmd.User = value
+
+		case "ADDR":
+			killCmd.Addr = value
+
+		case "LADDR":
+			killCmd.LAddr = value
+
+		case "SKIPME":
+			switch strings.ToLower(value) {
+			case "yes":
+				killCmd.SkipMe = true
+			case "no":
+				killCmd.SkipMe = false
+			default:
+				return nil, fmt.Errorf("invalid SKIPME value %s: expected yes or no", value)
+			}
+
+		case "MAXAGE":
+			maxAge, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || maxAge <= 0 {
+				return nil, fmt.Errorf("invalid MAXAGE value %s", value)
+			}
+			killCmd.MaxAge = maxAge
+
+		default:
+			return nil, fmt.Errorf("unknown filter %s", args[i])
+		}
+	}
+
+	return killCmd, nil
+}
+
+// parseClientType parses the type of clients to filter, where slave is an alias of replica.
+func parseClientType(s string) (string, error) {
+	switch t := strings.ToLower(s); t {
+	case "normal", "master", "replica", "pubsub":
+		return t, nil
+	case "slave":
+		return "replica", nil
+	default:
+		return "", fmt.Errorf("unknown client type '%s'", s)
+	}
+}
+
+func (p *Parser) parseConfigCommand(data spec.Data) (spec.Command, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("expected subcommand")
+	}
+
+	subArgs := args[1:]
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if len(subArgs) == 0 {
+			return nil, errors.New("invalid CONFIG GET format: expected at least 1 argument")
+		}
+
+		return &spec.ConfigGetCommand{Patterns: subArgs}, nil
+
+	case "SET":
+		if len(subArgs) == 0 || len(subArgs)%2 != 0 {
+			return nil, errors.New("invalid CONFIG SET format: expected parameter value pairs")
+		}
+
+		params := make([][2]string, 0, len(subArgs)/2)
+		for i := 0; i < len(subArgs); i += 2 {
+			params = append(params, [2]string{strings.ToLower(subArgs[i]), subArgs[i+1]})
+		}
+
+		return &spec.ConfigSetCommand{Params: params}, nil
+
+	case "REWRITE", "RESETSTAT":
+		if len(subArgs) != 0 {
+			return nil, fmt.Errorf("invalid CONFIG %s format: expected no arguments", strings.ToUpper(args[0]))
+		}
+
+		if strings.EqualFold(args[0], "REWRITE") {
+			return &spec.ConfigRewriteCommand{}, nil
+		}
+		return &spec.ConfigResetStatCommand{}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+}
+
+func (p *Parser) parseObjectCommand(data spec.Data) (spec.Command, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("expected subcommand")
+	}
+
+	subArgs := args[1:]
+	switch strings.ToUpper(args[0]) {
+	case "ENCODING", "REFCOUNT", "IDLETIME", "FREQ":
+		if len(subArgs) != 1 {
+			return nil, fmt.Errorf("invalid OBJECT %s format: expected 1 argument", strings.ToUpper(args[0]))
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "ENCODING":
+			return &spec.ObjectEncodingCommand{Key: subArgs[0]}, nil
+		case "REFCOUNT":
+			return &spec.ObjectRefCountCommand{Key: subArgs[0]}, nil
+		case "IDLETIME":
+			return &spec.ObjectIdleTimeCommand{Key: subArgs[0]}, nil
+		default:
+			return &spec.ObjectFreqCommand{Key: subArgs[0]}, nil
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+}
+
+func (p *Parser) parseMemoryCommand(data spec.Data) (spec.Command, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("expected subcommand")
+	}
+
+	subArgs := args[1:]
+	switch strings.ToUpper(args[0]) {
+	case "USAGE":
+		if len(subArgs) != 1 && len(subArgs) != 3 {
+			return nil, errors.New("invalid MEMORY USAGE format: expected a key and optional SAMPLES count")
+		}
+
+		cmd := &spec.MemoryUsageCommand{Key: subArgs[0], Samples: defaultMemorySamples}
+		if len(subArgs) == 3 {
+			if !strings.EqualFold(subArgs[1], "SAMPLES") {
+				return nil, fmt.Errorf("invalid MEMORY USAGE option %s", subArgs[1])
+			}
+
+			samples, err := strconv.Atoi(subArgs[2])
+			if err != nil || samples < 0 {
+				return nil, errors.New("invalid MEMORY USAGE format: SAMPLES must be a non-negative integer")
+			}
+			cmd.Samples = samples
+		}
+
+		return cmd, nil
+
+	case "STATS", "DOCTOR", "PURGE":
+		if len(subArgs) != 0 {
+			return nil, fmt.Errorf("invalid MEMORY %s format: expected no arguments", strings.ToUpper(args[0]))
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "STATS":
+			return &spec.MemoryStatsCommand{}, nil
+		case "DOCTOR":
+			return &spec.MemoryDoctorCommand{}, nil
+		default:
+			return &spec.MemoryPurgeCommand{}, nil
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+}
+
+func (p *Parser) parseSlowlogCommand(data spec.Data) (spec.Command, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("expected subcommand")
+	}
+
+	subArgs := args[1:]
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if len(subArgs) > 1 {
+			return nil, errors.New("invalid SLOWLOG GET format: expected at most 1 argument")
+		}
+
+		count := defaultSlowlogCount
+		if len(subArgs) == 1 {
+			count, err = strconv.Atoi(subArgs[0])
+			if err != nil || count < -1 {
+				return nil, errors.New("count should be greater than or equal to -1")
+			}
+		}
+
+		return &spec.SlowlogGetCommand{Count: count}, nil
+
+	case "LEN", "RESET":
+		if len(subArgs) != 0 {
+			return nil, fmt.Errorf("invalid SLOWLOG %s format: expected no arguments", strings.ToUpper(args[0]))
+		}
+
+		if strings.EqualFold(args[0], "LEN") {
+			return &spec.SlowlogLenCommand{}, nil
+		}
+		return &spec.SlowlogResetCommand{}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+}
+
+func (p *Parser) parseLatencyCommand(data spec.Data) (spec.Command, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("expected subcommand")
+	}
+
+	subArgs := args[1:]
+	switch strings.ToUpper(args[0]) {
+	case "LATEST", "DOCTOR":
+		if len(subArgs) != 0 {
+			return nil, fmt.Errorf("invalid LATENCY %s format: expected no arguments", strings.ToUpper(args[0]))
+		}
+
+		if strings.EqualFold(args[0], "LATEST") {
+			return &spec.LatencyLatestCommand{}, nil
+		}
+		return &spec.LatencyDoctorCommand{}, nil
+
+	case "HISTORY", "GRAPH":
+		if len(subArgs) != 1 {
+			return nil, fmt.Errorf("invalid LATENCY %s format: expected an event", strings.ToUpper(args[0]))
+		}
+
+		if strings.EqualFold(args[0], "HISTORY") {
+			return &spec.LatencyHistoryCommand{Event: subArgs[0]}, nil
+		}
+		return &spec.LatencyGraphCommand{Event: subArgs[0]}, nil
+
+	case "RESET":
+		return &spec.LatencyResetCommand{Events: subArgs}, nil
+
+	case "HISTOGRAM":
+		return &spec.LatencyHistogramCommand{Commands: subArgs}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+}
+
+// defaultACLLogCount is the number of entries returned by ACL LOG without a count.
+const defaultACLLogCount = 10
+
+func (p *Parser) parseACLCommand(data spec.Data) (spec.Command, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("expected subcommand")
+	}
+
+	subArgs := args[1:]
+	switch strings.ToUpper(args[0]) {
+	case "SETUSER":
+		if len(subArgs) == 0 {
+			return nil, errors.New("invalid ACL SETUSER format: expected a username")
+		}
+
+		return &spec.AclSetUserCommand{Username: subArgs[0], Rules: subArgs[1:]}, nil
+
+	case "GETUSER":
+		if len(subArgs) != 1 {
+			return nil, errors.New("invalid ACL GETUSER format: expected a username")
+		}
+
+		return &spec.AclGetUserCommand{Username: subArgs[0]}, nil
+
+	case "DELUSER":
+		if len(subArgs) == 0 {
+			return nil, errors.New("invalid ACL DELUSER format: expected usernames")
+		}
+
+		return &spec.AclDelUserCommand{Usernames: subArgs}, nil
+
+	case "LIST", "USERS", "WHOAMI", "SAVE", "LOAD":
+		if len(subArgs) != 0 {
+			return nil, fmt.Errorf("invalid ACL %s format: expected no arguments", strings.ToUpper(args[0]))
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "LIST":
+			return &spec.AclListCommand{}, nil
+		case "USERS":
+			return &spec.AclUsersCommand{}, nil
+		case "WHOAMI":
+			return &spec.AclWhoAmICommand{}, nil
+		case "SAVE":
+			return &spec.AclSaveCommand{}, nil
+		default:
+			return &spec.AclLoadCommand{}, nil
+		}
+
+	case "CAT":
+		switch len(subArgs) {
+		case 0:
+			return &spec.AclCatCommand{}, nil
+		case 1:
+			return &spec.AclCatCommand{Category: &subArgs[0]}, nil
+		default:
+			return nil, errors.New("invalid ACL CAT format: expected at most 1 category")
+		}
+
+	case "LOG":
+		if len(subArgs) > 1 {
+			return nil, errors.New("invalid ACL LOG format: expected a count or RESET")
+		}
+		if len(subArgs) == 0 {
+			return &spec.AclLogCommand{Count: defaultACLLogCount}, nil
+		}
+		if strings.EqualFold(subArgs[0], "RESET") {
+			return &spec.AclLogCommand{Reset: true}, nil
+		}
+
+		count, err := strconv.Atoi(subArgs[0])
+		if err != nil || count < 0 {
+			return nil, spec.ErrorOf("ERR", "value is out of range, must be positive")
+		}
+
+		return &spec.AclLogCommand{Count: count}, nil
+
+	case "DRYRUN":
+		if len(subArgs) < 2 {
+			return nil, errors.New("invalid ACL DRYRUN format: expected a username and a command")
+		}
+
+		return &spec.AclDryRunCommand{Username: subArgs[0], Args: subArgs[1:]}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+}
+
+// parseKeysAndArgs splits arguments after numkeys into keys and the other arguments.
+func (p *Parser) parseKeysAndArgs(numKeysStr string, rest []string) ([]string, []string, error) {
+	numKeys, err := strconv.Atoi(numKeysStr)
+	if err != nil {
+		return nil, nil, spec.ErrorOf("ERR", "value is not an integer or out of range")
+	}
+
+	if numKeys < 0 {
+		return nil, nil, spec.ErrorOf("ERR", "Number of keys can't be negative")
+	}
+
+	if numKeys > len(rest) {
+		return nil, nil, spec.ErrorOf("ERR", "Number of keys can't be greater than number of args")
+	}
+
+	return rest[:numKeys], rest[numKeys:], nil
+}
+
+func (p *Parser) parseScriptCommand(data spec.Data) (spec.Command, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("expected subcommand")
+	}
+
+	subArgs := args[1:]
+	switch strings.ToUpper(args[0]) {
+	case "LOAD":
+		if len(subArgs) != 1 {
+			return nil, errors.New("invalid SCRIPT LOAD format: expected 1 argument")
+		}
+
+		return &spec.ScriptLoadCommand{Script: subArgs[0]}, nil
+
+	case "EXISTS":
+		if len(subArgs) == 0 {
+			return nil, errors.New("invalid SCRIPT EXISTS format: expected at least 1 argument")
+		}
+
+		shas := make([]string, 0, len(subArgs))
+		for _, sha := range subArgs {
+			shas = append(shas, strings.ToLower(sha))
+		}
+
+		return &spec.ScriptExistsCommand{SHAs: shas}, nil
+
+	case "FLUSH":
+		// flushing is always synchronous, but the modifiers are accepted for compatibility
+		if len(subArgs) > 1 || (len(subArgs) == 1 && !slices.Contains([]string{"ASYNC", "SYNC"}, strings.ToUpper(subArgs[0]))) {
+			return nil, errors.New("invalid SCRIPT FLUSH format: expected optional ASYNC or SYNC")
+		}
+
+		return &spec.ScriptFlushCommand{}, nil
+
+	case "KILL":
+		if len(subArgs) != 0 {
+			return nil, errors.New("invalid SCRIPT KILL format: expected no arguments")
+		}
+
+		return &spec.ScriptKillCommand{}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+}
+
+func (p *Parser) parseFunctionCommand(data spec.Data) (spec.Command, error) {
+	args, err := p.parseArguments(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		return nil, errors.New("expected subcommand")
+	}
+
+	subArgs := args[1:]
+	switch strings.ToUpper(args[0]) {
+	case "LOAD":
+		switch {
+		case len(subArgs) == 1:
+			return &spec.FunctionLoadCommand{Code: subArgs[0]}, nil
+		case len(subArgs) == 2 && strings.ToUpper(subArgs[0]) == "REPLACE":
+			return &spec.FunctionLoadCommand{Code: subArgs[1], Replace: true}, nil
+		default:
+			return nil, errors.New("invalid FUNCTION LOAD format: expected [REPLACE] function-code")
+		}
+
+	case "LIST":
+		listCmd := &spec.FunctionListCommand{}
+		for i := 0; i < len(subArgs); i++ {
+			switch strings.ToUpper(subArgs[i]) {
+			case "WITHCODE":
+				listCmd.WithCode = true
+			case "LIBRARYNAME":
+				if i+1 >= len(subArgs) {
+					return nil, errors.New("library name argument was not given")
+				}
+				i++
+				listCmd.LibraryPattern = subArgs[i]
+			default:
+				return nil, fmt.Errorf("unknown argument %s", subArgs[i])
+			}
+		}
+
+		return listCmd, nil
+
+	case "DELETE":
+		if len(subArgs) != 1 {
+			return nil, errors.New("invalid FUNCTION DELETE format: expected 1 argument")
+		}
+
+		return &spec.FunctionDeleteCommand{Library: subArgs[0]}, nil
+
+	case "DUMP":
+		if len(subArgs) != 0 {
+			return nil, errors.New("invalid FUNCTION DUMP format: expected no arguments")
+		}
+
+		return &spec.FunctionDumpCommand{}, nil
+
+	case "RESTORE":
+		if len(subArgs) != 1 && len(subArgs) != 2 {
+			return nil, errors.New("invalid FUNCTION RESTORE format: expected serialized-value [FLUSH | APPEND | REPLACE]")
+		}
+
+		restoreCmd := &spec.FunctionRestoreCommand{Payload: subArgs[0], Policy: spec.FunctionRestoreAppend}
+		if len(subArgs) == 2 {
+			policy := spec.FunctionRestorePolicy(strings.ToUpper(subArgs[1]))
+			switch policy {
+			case spec.FunctionRestoreAppend, spec.FunctionRestoreReplace, spec.FunctionRestoreFlush:
+				restoreCmd.Policy = policy
+			default:
+				return nil, fmt.Errorf("wrong restore policy given, value should be either FLUSH, APPEND or REPLACE")
+			}
+		}
+
+		return restoreCmd, nil
+
+	case "FLUSH":
+		// flushing is always synchronous, but the modifiers are accepted for compatibility
+		if len(subArgs) > 1 || (len(subArgs) == 1 && !slices.Contains([]string{"ASYNC", "SYNC"}, strings.ToUpper(subArgs[0]))) {
+			return nil, errors.New("invalid FUNCTION FLUSH format: expected optional ASYNC or SYNC")
+		}
+
+		return &spec.FunctionFlushCommand{}, nil
+
+	case "KILL":
+		if len(subArgs) != 0 {
+			return nil, errors.New("invalid FUNCTION KILL format: expected no arguments")
+		}
+
+		return &spec.FunctionKillCommand{}, nil
+
+	case "STATS":
+		if len(subArgs) != 0 {
+			return nil, errors.New("invalid FUNCTION STATS format: expected no arguments")
+		}
+
+		return &spec.FunctionStatsCommand{}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown subcommand %s", args[0])
+	}
+}
+
 type parseHandler struct {
 	parser *Parser
 }
@@ -219,7 +1316,18 @@ func (h *parseHandler) Handle(e event.Event, push func(event.Event)) error {
 
 	cmd, err := h.parser.Parse(parseEvent.Data)
 	if err != nil {
-		return fmt.Errorf("parse error for data: %+v, err: %w", parseEvent.Data, err)
+		slog.Warn("parse error",
+			slog.Uint64("id", parseEvent.ID()),
+			slog.Any("data", parseEvent.Data),
+			slog.Any("error", err),
+		)
+		// executor replies the error, as it may affect the state of the connection (e.g. transaction)
+		push(&event.ExecuteEvent{
+			ID_:  parseEvent.ID(),
+			Args: h.parser.parseCommandArgs(parseEvent.Data),
+			Err:  err,
+		})
+		return nil
 	}
 	slog.Info("parsed to...",
 		slog.Uint64("id", parseEvent.ID()),
@@ -229,6 +1337,7 @@ func (h *parseHandler) Handle(e event.Event, push func(event.Event)) error {
 	push(&event.ExecuteEvent{
 		ID_:     parseEvent.ID(),
 		Command: cmd,
+		Args:    h.parser.parseCommandArgs(parseEvent.Data),
 	})
 
 	return nil
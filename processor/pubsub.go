@@ -0,0 +1,290 @@
+package processor
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/pkg"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+var _ event.Pusher = (*PubSub)(nil)
+
+// PubSub keeps channel and pattern subscriptions of connections and delivers
+// published messages to them as pushed format events.
+// It is only accessed from the event loop, so no locking is needed.
+type PubSub struct {
+	channels map[string]map[uint64]struct{}
+	patterns map[string]map[uint64]struct{}
+	clients  map[uint64]*subscriptions
+
+	push func(event.Event)
+}
+
+type subscriptions struct {
+	channels map[string]struct{}
+	patterns map[string]struct{}
+}
+
+func (s *subscriptions) count() int {
+	return len(s.channels) + len(s.patterns)
+}
+
+func NewPubSub() *PubSub {
+	return &PubSub{
+		channels: make(map[string]map[uint64]struct{}),
+		patterns: make(map[string]map[uint64]struct{}),
+		clients:  make(map[uint64]*subscriptions),
+	}
+}
+
+func (ps *PubSub) InitPushing(push func(event.Event)) {
+	ps.push = push
+}
+
+func (ps *PubSub) ShutdownPushing() {}
+
+// SubscriptionCount returns the number of channels and patterns the connection is subscribed to.
+func (ps *PubSub) SubscriptionCount(id uint64) int {
+	subs, found := ps.clients[id]
+	if !found {
+		return 0
+	}
+
+	return subs.count()
+}
+
+// ChannelCount returns the number of channels the connection is subscribed to.
+func (ps *PubSub) ChannelCount(id uint64) int {
+	if subs, found := ps.clients[id]; found {
+		return len(subs.channels)
+	}
+
+	return 0
+}
+
+// PatternCount returns the number of patterns the connection is subscribed to.
+func (ps *PubSub) PatternCount(id uint64) int {
+	if subs, found := ps.clients[id]; found {
+		return len(subs.patterns)
+	}
+
+	return 0
+}
+
+// Channels returns the number of channels with subscribers.
+func (ps *PubSub) Channels() int {
+	return len(ps.channels)
+}
+
+// Patterns returns the number of patterns with subscribers.
+func (ps *PubSub) Patterns() int {
+	return len(ps.patterns)
+}
+
+// Clients returns the number of connections subscribed to any channel or pattern.
+func (ps *PubSub) Clients() int {
+	return len(ps.clients)
+}
+
+func (ps *PubSub) Subscribe(id uint64, channels []string) spec.Data {
+	subs := ps.subscriptionsOf(id)
+
+	replies := make([]spec.Data, 0, len(channels))
+	for _, ch := range channels {
+		subscribe(ps.channels, ch, id)
+		subs.channels[ch] = struct{}{}
+
+		replies = append(replies, subscriptionReply("subscribe", &ch, subs.count()))
+	}
+
+	return spec.MultiOf(replies...)
+}
+
+func (ps *PubSub) Unsubscribe(id uint64, channels []string) spec.Data {
+	subs := ps.subscriptionsOf(id)
+	if len(channels) == 0 {
+		channels = slices.Sorted(maps.Keys(subs.channels))
+	}
+
+	replies := make([]spec.Data, 0, len(channels))
+	for _, ch := range channels {
+		unsubscribe(ps.channels, ch, id)
+		delete(subs.channels, ch)
+
+		replies = append(replies, subscriptionReply("unsubscribe", &ch, subs.count()))
+	}
+
+	if len(replies) == 0 {
+		replies = append(replies, subscriptionReply("unsubscribe", nil, subs.count()))
+	}
+
+	ps.cleanupClient(id)
+	return spec.MultiOf(replies...)
+}
+
+func (ps *PubSub) PSubscribe(id uint64, patterns []string) spec.Data {
+	subs := ps.subscriptionsOf(id)
+
+	replies := make([]spec.Data, 0, len(patterns))
+	for _, pattern := range patterns {
+		subscribe(ps.patterns, pattern, id)
+		subs.patterns[pattern] = struct{}{}
+
+		replies = append(replies, subscriptionReply("psubscribe", &pattern, subs.count()))
+	}
+
+	return spec.MultiOf(replies...)
+}
+
+func (ps *PubSub) PUnsubscribe(id uint64, patterns []string) spec.Data {
+	subs := ps.subscriptionsOf(id)
+	if len(patterns) == 0 {
+		patterns = slices.Sorted(maps.Keys(subs.patterns))
+	}
+
+	replies := make([]spec.Data, 0, len(patterns))
+	for _, pattern := range patterns {
+		unsubscribe(ps.patterns, pattern, id)
+		delete(subs.patterns, pattern)
+
+		replies = append(replies, subscriptionReply("punsubscribe", &pattern, subs.count()))
+	}
+
+	if len(replies) == 0 {
+		replies = append(replies, subscriptionReply("punsubscribe", nil, subs.count()))
+	}
+
+	ps.cleanupClient(id)
+	return spec.MultiOf(replies...)
+}
+
+// Publish delivers the message to every subscriber of the channel and of the
+// patterns matching it, returning the number of receivers.
+func (ps *PubSub) Publish(channel string, message string) int {
+	receivers := 0
+
+	for id := range ps.channels[channel] {
+		ps.deliver(id, channelMessage(channel, spec.BulkStringOf(message)))
+		receivers++
+	}
+
+	for pattern, ids := range ps.patterns {
+		if !pkg.MatchGlob(pattern, channel) {
+			continue
+		}
+
+		for id := range ids {
+			ps.deliver(id, spec.PushOf(
+				spec.BulkStringOf("pmessage"),
+				spec.BulkStringOf(pattern),
+				spec.BulkStringOf(channel),
+				spec.BulkStringOf(message),
+			))
+			receivers++
+		}
+	}
+
+	return receivers
+}
+
+// PublishTo delivers the message to the connection alone, when it is subscribed to the
+// channel itself, reporting whether it is. The message may be any data, such as the keys
+// of invalidation messages redirected to a RESP2 connection.
+func (ps *PubSub) PublishTo(id uint64, channel string, message spec.Data) bool {
+	if _, subscribed := ps.channels[channel][id]; !subscribed {
+		return false
+	}
+
+	ps.deliver(id, channelMessage(channel, message))
+	return true
+}
+
+func channelMessage(channel string, message spec.Data) spec.Data {
+	return spec.PushOf(
+		spec.BulkStringOf("message"),
+		spec.BulkStringOf(channel),
+		message,
+	)
+}
+
+// RemoveClient drops every subscription of a closed connection.
+func (ps *PubSub) RemoveClient(id uint64) {
+	subs, found := ps.clients[id]
+	if !found {
+		return
+	}
+
+	for ch := range subs.channels {
+		unsubscribe(ps.channels, ch, id)
+	}
+	for pattern := range subs.patterns {
+		unsubscribe(ps.patterns, pattern, id)
+	}
+	delete(ps.clients, id)
+}
+
+func (ps *PubSub) deliver(id uint64, data spec.Data) {
+	if ps.push == nil {
+		return
+	}
+
+	ps.push(&event.FormatEvent{
+		ID_:  id,
+		Data: data,
+	})
+}
+
+func (ps *PubSub) subscriptionsOf(id uint64) *subscriptions {
+	subs, found := ps.clients[id]
+	if !found {
+		subs = &subscriptions{
+			channels: make(map[string]struct{}),
+			patterns: make(map[string]struct{}),
+		}
+		ps.clients[id] = subs
+	}
+
+	return subs
+}
+
+func (ps *PubSub) cleanupClient(id uint64) {
+	if subs, found := ps.clients[id]; found && subs.count() == 0 {
+		delete(ps.clients, id)
+	}
+}
+
+func subscribe(m map[string]map[uint64]struct{}, name string, id uint64) {
+	ids, found := m[name]
+	if !found {
+		ids = make(map[uint64]struct{})
+		m[name] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func unsubscribe(m map[string]map[uint64]struct{}, name string, id uint64) {
+	ids, found := m[name]
+	if !found {
+		return
+	}
+
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(m, name)
+	}
+}
+
+func subscriptionReply(kind string, name *string, count int) *spec.PushData {
+	nameData := spec.NullBulkString()
+	if name != nil {
+		nameData = spec.BulkStringOf(*name)
+	}
+
+	return spec.PushOf(
+		spec.BulkStringOf(kind),
+		nameData,
+		spec.IntegerOf(int64(count)),
+	)
+}
@@ -0,0 +1,234 @@
+package processor
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/acl"
+	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/script"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+const (
+	// interval to check whether a running script exceeded the time limit
+	scriptBusyCheckInterval = 100 * time.Millisecond
+	// interval to serve other events once the script is busy
+	scriptBusyServeInterval = time.Millisecond
+)
+
+// runningScript is the script or function currently executed by the executor.
+type runningScript struct {
+	id       uint64
+	name     string // name of the function, empty for scripts
+	command  []string
+	function bool
+	readOnly bool // declared with the no-writes flag
+	allowOOM bool // declared with the allow-oom flag
+
+	start  time.Time
+	cancel context.CancelFunc
+	wrote  bool // a write command was called, so the script cannot be killed
+	busy   bool // the time limit is exceeded
+	killed bool
+}
+
+type scriptCall struct {
+	args  []string
+	reply chan scriptReply
+}
+
+type scriptReply struct {
+	data spec.Data
+	err  error
+}
+
+// SetYield sets the function to serve other events while a script is busy, usually event.Loop.Yield.
+func (e *Executor) SetYield(yield func(accept func(event.Event) bool)) {
+	e.yield = yield
+}
+
+func (e *Executor) loadScript(body string) (*script.Script, error) {
+	s, found := e.scripts[script.SHA1Hex(body)]
+	if found {
+		return s, nil
+	}
+
+	s, err := script.Compile(body)
+	if err != nil {
+		return nil, err
+	}
+
+	e.scripts[s.SHA] = s
+	return s, nil
+}
+
+func (e *Executor) evalSha(id uint64, sha string, keys []string, args []string) (spec.Data, error) {
+	s, found := e.scripts[sha]
+	if !found {
+		return nil, spec.ErrorOf("NOSCRIPT", "No matching script. Please use EVAL.")
+	}
+
+	rs := &runningScript{
+		id:      id,
+		command: append([]string{"EVALSHA", sha, strconv.Itoa(len(keys))}, append(keys, args...)...),
+	}
+	return e.runScript(rs, func(ctx context.Context, call script.Caller) (spec.Data, error) {
+		return script.Run(ctx, s, keys, args, call)
+	})
+}
+
+// runScript runs the script in its own goroutine while commands called from it are executed here,
+// so the script is atomic for the other clients. Once the script exceeds the time limit, other
+// events are served in busy state, where only SCRIPT KILL and FUNCTION KILL are accepted.
+func (e *Executor) runScript(
+	rs *runningScript,
+	run func(ctx context.Context, call script.Caller) (spec.Data, error),
+) (spec.Data, error) {
+	if e.script != nil {
+		return nil, spec.ErrorOf("ERR", "This Redis command is not allowed from script")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rs.start = time.Now()
+	rs.cancel = cancel
+	e.script = rs
+	defer func() { e.script = nil }()
+
+	calls := make(chan scriptCall)
+	done := make(chan scriptReply, 1)
+	go func() {
+		data, err := run(ctx, func(args []string) (spec.Data, error) {
+			call := scriptCall{args: args, reply: make(chan scriptReply, 1)}
+			select {
+			case calls <- call:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+
+			reply := <-call.reply
+			return reply.data, reply.err
+		})
+		done <- scriptReply{data: data, err: err}
+	}()
+
+	ticker := time.NewTicker(scriptBusyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case call := <-calls:
+			data, err := e.executeFromScript(call.args)
+			call.reply <- scriptReply{data: data, err: err}
+
+		case reply := <-done:
+			if e.script.killed {
+				return nil, spec.ErrorOf("ERR", "Script killed by user with SCRIPT KILL...")
+			}
+
+			return reply.data, reply.err
+
+		case <-ticker.C:
+			if time.Since(e.script.start) >= e.scriptTimeLimit {
+				if !e.script.busy {
+					slog.Warn("script exceeded time limit, serving other events in busy state",
+						slog.Uint64("id", e.script.id),
+						slog.Duration("elapsed", time.Since(e.script.start)),
+					)
+					e.script.busy = true
+				}
+				ticker.Reset(scriptBusyServeInterval)
+				e.serveBusy()
+			}
+		}
+	}
+}
+
+// executeFromScript executes a command called by the running script.
+func (e *Executor) executeFromScript(args []string) (spec.Data, error) {
+	cmd, err := e.parser.Parse(spec.BulkStringArrayOf(args...))
+	if err != nil {
+		return nil, err
+	}
+
+	switch cmd.(type) {
+	case *spec.MultiCommand, *spec.ExecCommand, *spec.DiscardCommand,
+		*spec.WatchCommand, *spec.UnwatchCommand,
+		*spec.SubscribeCommand, *spec.UnsubscribeCommand, *spec.PSubscribeCommand, *spec.PUnsubscribeCommand,
+		*spec.EvalCommand, *spec.EvalShaCommand, *spec.ScriptLoadCommand, *spec.ScriptExistsCommand,
+		*spec.ScriptFlushCommand, *spec.ScriptKillCommand,
+		*spec.FCallCommand, *spec.FunctionLoadCommand, *spec.FunctionDeleteCommand, *spec.FunctionRestoreCommand,
+		*spec.FunctionFlushCommand, *spec.FunctionKillCommand, *spec.MonitorCommand, *spec.AuthCommand:
+		return nil, spec.ErrorOf("ERR", "This Redis command is not allowed from script")
+	}
+	if client, found := e.clients.Get(e.script.id); found {
+		if denial := e.checkPermissions(client, args, acl.ContextLua); denial != "" {
+			return nil, spec.ErrorOf("ERR", "ACL failure in script: %s", denial)
+		}
+	}
+	e.feedMonitors("lua", args)
+
+	if e.outOfMemory && !e.script.allowOOM && e.denyOOM(cmd) {
+		return nil, errOutOfMemory
+	}
+
+	if isWriteCommand(cmd) {
+		if e.script.readOnly {
+			return nil, spec.ErrorOf("ERR", "Write commands are not allowed from read-only scripts.")
+		}
+		e.script.wrote = true
+	}
+
+	return e.Execute(e.script.id, cmd)
+}
+
+// serveBusy serves the events queued while the script is running, keeping expiration
+// and cron jobs on hold so the dataset does not change under the script. The events of the
+// client running the script are kept on hold as well, so its pipelined commands are replied
+// after the script.
+func (e *Executor) serveBusy() {
+	if e.yield == nil {
+		return
+	}
+
+	e.yielding = true
+	defer func() { e.yielding = false }()
+
+	id := e.script.id
+	e.yield(func(ev event.Event) bool {
+		return ev.ID() != id && ev.Type() != event.ExpireEventType && ev.Type() != event.CronEventType
+	})
+}
+
+// executeBusy executes commands from other clients while a script is busy.
+func (e *Executor) executeBusy(cmd spec.Command) (spec.Data, error) {
+	switch cmd.(type) {
+	case *spec.ScriptKillCommand:
+		return e.killScript(false)
+	case *spec.FunctionKillCommand:
+		return e.killScript(true)
+	case *spec.FunctionStatsCommand:
+		return e.functionStats(), nil
+	default:
+		return nil, spec.ErrorOf("BUSY", "Redis is busy running a script. You can only call SCRIPT KILL or SHUTDOWN NOSAVE.")
+	}
+}
+
+// killScript kills the running script, or the running function if function is set.
+func (e *Executor) killScript(function bool) (spec.Data, error) {
+	if e.script == nil || e.script.function != function {
+		return nil, spec.ErrorOf("NOTBUSY", "No scripts in execution right now.")
+	}
+
+	if e.script.wrote {
+		return nil, spec.ErrorOf("UNKILLABLE", "Sorry the script already executed write commands against the dataset. You can either wait the script termination or kill the server in a hard way using the SHUTDOWN NOSAVE command.")
+	}
+
+	e.script.killed = true
+	e.script.cancel()
+	return spec.SimpleStringOf("OK"), nil
+}
@@ -0,0 +1,148 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/script"
+)
+
+func TestEval(t *testing.T) {
+	const body = "return redis.call('GET', KEYS[1]) .. ARGV[1]"
+	sha := script.SHA1Hex(body)
+
+	tests := []struct {
+		name     string
+		commands []command
+	}{
+		{
+			name: "evalsha after eval",
+			commands: []command{
+				{args: []string{"SET", "k", "v"}, want: "+OK\r\n"},
+				{args: []string{"EVALSHA", sha, "1", "k", "1"}, want: "-NOSCRIPT No matching script. Please use EVAL.\r\n"},
+				{args: []string{"EVAL", body, "1", "k", "1"}, want: "$2\r\nv1\r\n"},
+				{args: []string{"EVALSHA", sha, "1", "k", "2"}, want: "$2\r\nv2\r\n"},
+				{client: 1, args: []string{"EVALSHA", sha, "1", "k", "3"}, want: "$2\r\nv3\r\n"},
+			},
+		},
+		{
+			name: "script load",
+			commands: []command{
+				{args: []string{"SCRIPT", "LOAD", body}, want: "$40\r\n" + sha + "\r\n"},
+				{args: []string{"SCRIPT", "EXISTS", sha, "0000"}, want: "*2\r\n:1\r\n:0\r\n"},
+				{args: []string{"SET", "k", "v"}, want: "+OK\r\n"},
+				{args: []string{"EVALSHA", sha, "1", "k", "1"}, want: "$2\r\nv1\r\n"},
+			},
+		},
+		{
+			name: "script flush",
+			commands: []command{
+				{args: []string{"SCRIPT", "LOAD", body}, want: "$40\r\n" + sha + "\r\n"},
+				{args: []string{"SCRIPT", "FLUSH"}, want: "+OK\r\n"},
+				{args: []string{"EVALSHA", sha, "1", "k", "1"}, want: "-NOSCRIPT No matching script. Please use EVAL.\r\n"},
+			},
+		},
+		{
+			name: "script kill without script",
+			commands: []command{
+				{args: []string{"SCRIPT", "KILL"}, want: "-NOTBUSY No scripts in execution right now.\r\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestServer(t).run(2, tt.commands)
+		})
+	}
+}
+
+func TestScriptKill(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string // reply to SCRIPT KILL
+	}{
+		{
+			name: "killable",
+			body: "while true do end",
+			want: "+OK\r\n",
+		},
+		{
+			name: "after a write",
+			body: "redis.call('SET', 'k', 'v') while true do end",
+			want: "-UNKILLABLE Sorry the script already executed write commands against the dataset. You can either wait the script termination or kill the server in a hard way using the SHUTDOWN NOSAVE command.\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			s.executor.scriptTimeLimit = 0
+
+			runner, other := s.connect(), s.connect()
+			served := false
+			s.executor.SetYield(func(accept func(event.Event) bool) {
+				if served {
+					return
+				}
+				served = true
+
+				if accept(&event.ExecuteEvent{ID_: runner}) {
+					t.Errorf("the events of the client running the script are accepted")
+				}
+				if !accept(&event.ExecuteEvent{ID_: other}) {
+					t.Errorf("the events of other clients are not accepted")
+				}
+
+				busy := "-BUSY Redis is busy running a script. You can only call SCRIPT KILL or SHUTDOWN NOSAVE.\r\n"
+				if got := s.do(other, "GET", "k"); got != busy {
+					t.Errorf("GET = %q, want %q", got, busy)
+				}
+				if got := s.do(other, "SCRIPT", "KILL"); got != tt.want {
+					t.Errorf("SCRIPT KILL = %q, want %q", got, tt.want)
+				}
+
+				// stop the script in any case, as the unkillable one would run forever
+				s.executor.script.cancel()
+			})
+
+			got := s.do(runner, "EVAL", tt.body, "0")
+			if !served {
+				t.Fatalf("the busy script did not serve other events")
+			}
+			if want := "-ERR Script killed by user with SCRIPT KILL...\r\n"; tt.want == "+OK\r\n" && got != want {
+				t.Errorf("EVAL = %q, want %q", got, want)
+			}
+
+			if got, want := s.do(other, "SCRIPT", "KILL"), "-NOTBUSY No scripts in execution right now.\r\n"; got != want {
+				t.Errorf("SCRIPT KILL after the script = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestScriptOutOfMemory(t *testing.T) {
+	const (
+		oom = "-OOM command not allowed when used memory > 'maxmemory'.\r\n"
+		lib = "#!lua name=lib\n" +
+			"redis.register_function('rw', function(keys) return redis.call('SET', keys[1], 'v') end)\n" +
+			"redis.register_function{function_name='ro', callback=function(keys) return redis.call('GET', keys[1]) end, flags={'no-writes'}}\n" +
+			"redis.register_function{function_name='oom', callback=function(keys) return redis.call('SET', keys[1], 'v') end, flags={'allow-oom'}}"
+	)
+
+	newTestServer(t).run(1, []command{
+		{args: []string{"FUNCTION", "LOAD", lib}, want: "$3\r\nlib\r\n"},
+		{args: []string{"SET", "k", "v"}, want: "+OK\r\n"},
+		{args: []string{"CONFIG", "SET", "maxmemory", "1"}, want: "+OK\r\n"},
+
+		{args: []string{"SET", "k", "v"}, want: oom},
+		{args: []string{"EVAL", "return 1", "0"}, want: oom},
+		{args: []string{"EVALSHA", "e0e1f9fabfc9d4800c877a703b823ac0578ff8db", "0"}, want: oom},
+		{args: []string{"FCALL", "rw", "1", "k"}, want: oom},
+		{args: []string{"FCALL", "ro", "1", "k"}, want: "$1\r\nv\r\n"},
+		{args: []string{"FCALL_RO", "ro", "1", "k"}, want: "$1\r\nv\r\n"},
+		{args: []string{"FCALL", "oom", "1", "j"}, want: "+OK\r\n"},
+		{args: []string{"FCALL", "nosuchfunction", "0"}, want: "-ERR Function not found\r\n"},
+	})
+}
@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+const (
+	// arguments and their lengths kept for an entry, as Redis does
+	slowlogMaxArgc   = 32
+	slowlogMaxString = 128
+)
+
+// slowlogEntry is a command executed slower than slowlog-log-slower-than.
+type slowlogEntry struct {
+	id         int64
+	time       time.Time
+	duration   time.Duration
+	args       []string // truncated
+	clientAddr string
+	clientName string
+}
+
+// slowlog keeps the latest slow commands, the newest first.
+type slowlog struct {
+	entries []slowlogEntry
+	nextID  int64
+}
+
+// recordSlowCommand adds the command to the slow log when it took longer than the threshold.
+func (e *Executor) recordSlowCommand(id uint64, args []string, duration time.Duration) {
+	threshold := e.cfg.SlowlogLogSlowerThan
+	if threshold < 0 || duration.Microseconds() < threshold {
+		return
+	}
+
+	entry := slowlogEntry{
+		id:       e.slowlog.nextID,
+		time:     time.Now(),
+		duration: duration,
+		args:     truncateSlowlogArgs(args),
+	}
+	if client, found := e.clients.Get(id); found {
+		entry.clientAddr = client.Addr()
+		entry.clientName = client.Name
+	}
+	e.slowlog.nextID++
+
+	e.slowlog.entries = append([]slowlogEntry{entry}, e.slowlog.entries...)
+	if len(e.slowlog.entries) > e.cfg.SlowlogMaxLen {
+		e.slowlog.entries = e.slowlog.entries[:e.cfg.SlowlogMaxLen]
+	}
+}
+
+// truncateSlowlogArgs keeps the first arguments and their first bytes, noting how many
+// were left out.
+func truncateSlowlogArgs(args []string) []string {
+	n := min(len(args), slowlogMaxArgc)
+	truncated := make([]string, 0, n)
+	for i, arg := range args[:n] {
+		if i == slowlogMaxArgc-1 && len(args) > slowlogMaxArgc {
+			truncated = append(truncated, fmt.Sprintf("... (%d more arguments)", len(args)-slowlogMaxArgc+1))
+			break
+		}
+
+		if len(arg) > slowlogMaxString {
+			arg = fmt.Sprintf("%s... (%d more bytes)", arg[:slowlogMaxString], len(arg)-slowlogMaxString)
+		}
+		truncated = append(truncated, arg)
+	}
+
+	return truncated
+}
+
+// slowlogGet replies the newest entries, or every entry when count is negative.
+func (e *Executor) slowlogGet(count int) spec.Data {
+	entries := e.slowlog.entries
+	if count >= 0 && count < len(entries) {
+		entries = entries[:count]
+	}
+
+	replies := make([]spec.Data, 0, len(entries))
+	for _, entry := range entries {
+		replies = append(replies, spec.ArrayOf(
+			spec.IntegerOf(entry.id),
+			spec.IntegerOf(entry.time.Unix()),
+			spec.IntegerOf(entry.duration.Microseconds()),
+			spec.BulkStringArrayOf(entry.args...),
+			spec.BulkStringOf(entry.clientAddr),
+			spec.BulkStringOf(entry.clientName),
+		))
+	}
+
+	return spec.ArrayOf(replies...)
+}
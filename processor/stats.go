@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// instantaneous metrics are the average of the samples taken in the last seconds
+	metricSamples        = 16
+	metricSamplingPeriod = 100 * time.Millisecond
+)
+
+// instantaneousMetric samples a counter periodically to report its rate per second,
+// as the instantaneous_* fields of INFO.
+type instantaneousMetric struct {
+	lastTime  time.Time
+	lastValue int64
+	samples   [metricSamples]float64
+	idx       int
+}
+
+// track samples the counter, unless the last sample is more recent than the sampling period.
+func (m *instantaneousMetric) track(now time.Time, value int64) {
+	if m.lastTime.IsZero() {
+		m.lastTime, m.lastValue = now, value
+		return
+	}
+
+	elapsed := now.Sub(m.lastTime)
+	if elapsed < metricSamplingPeriod {
+		return
+	}
+
+	m.samples[m.idx] = float64(value-m.lastValue) / elapsed.Seconds()
+	m.idx = (m.idx + 1) % metricSamples
+	m.lastTime, m.lastValue = now, value
+}
+
+// rate returns the average of the samples per second.
+func (m *instantaneousMetric) rate() float64 {
+	var sum float64
+	for _, s := range m.samples {
+		sum += s
+	}
+
+	return sum / metricSamples
+}
+
+func (m *instantaneousMetric) reset() {
+	*m = instantaneousMetric{}
+}
+
+// countingReader counts the bytes read from a connection into a counter shared by the
+// reading goroutines.
+type countingReader struct {
+	r     io.Reader
+	count *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.count.Add(int64(n))
+	return n, err
+}
@@ -1,54 +1,237 @@
 package processor
 
 import (
-	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
-
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/config"
 	"github.com/codecrafters-io/redis-starter-go/event"
 	"github.com/codecrafters-io/redis-starter-go/id"
 	"github.com/codecrafters-io/redis-starter-go/pkg"
 )
 
-type TCPProcessor struct {
-	listener net.Listener
-	idIssuer id.IDIssuer[uint64]
+const (
+	somaxconnPath = "/proc/sys/net/core/somaxconn"
+
+	// output buffers bigger than this are not kept for reuse once written
+	maxSpareOutputCap = 64 * 1024
+)
 
-	connMap        *pkg.ConcurrentMap[uint64, *connInfo]
+type TCPProcessor struct {
+	listeners  []net.Listener
+	idIssuer   id.IDIssuer[uint64]
+	backlog    int
+	maxBulkLen atomic.Int64 // read from the reading goroutines
+	maxClients atomic.Int64 // read from the accepting goroutines
+	keepAlive  atomic.Int64 // as time.Duration, 0 to disable
+
+	// counters updated from the accepting, reading and writing goroutines
+	connectionsReceived atomic.Int64
+	connectionsRejected atomic.Int64
+	netInputBytes       atomic.Int64
+	netOutputBytes      atomic.Int64
+	readsProcessed      atomic.Int64
+	writesProcessed     atomic.Int64
+	inputRate           instantaneousMetric // sampled on the event loop
+	outputRate          instantaneousMetric
+
+	clients        *Clients
+	pendingWrites  map[uint64]struct{} // clients with output to write on the next flush
+	closeListeners []func(id uint64)
 	pushStopSignal chan struct{}
 }
 
-type connInfo struct {
-	conn    net.Conn
-	scanner *bufio.Scanner
+// NewTCPProcessor listens on the addresses of the config. Failing to listen on an optional
+// address is only logged.
+func NewTCPProcessor(cfg *config.Config, idIssuer id.IDIssuer[uint64], clients *Clients) (*TCPProcessor, error) {
+	t := &TCPProcessor{
+		idIssuer: idIssuer,
+		backlog:  cfg.TCPBacklog,
+
+		clients:       clients,
+		pendingWrites: make(map[uint64]struct{}),
+	}
+	t.maxBulkLen.Store(cfg.ProtoMaxBulkLen)
+	t.maxClients.Store(int64(cfg.MaxClients))
+	t.keepAlive.Store(int64(cfg.TCPKeepAlive))
+
+	for _, address := range cfg.Addresses() {
+		address, optional := strings.CutPrefix(address, "-")
+
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			if optional {
+				slog.Warn("failed to bind to optional address", "address", address, "error", err)
+				continue
+			}
+
+			_ = t.Close()
+			return nil, fmt.Errorf("failed to bind to address %s: %w", address, err)
+		}
+
+		if err := setBacklog(l, t.backlog); err != nil {
+			_ = l.Close()
+			_ = t.Close()
+			return nil, fmt.Errorf("failed to set the backlog of address %s: %w", address, err)
+		}
+
+		slog.Info("listening", "address", l.Addr())
+		t.listeners = append(t.listeners, l)
+	}
+
+	if len(t.listeners) == 0 {
+		return nil, errors.New("failed to bind to any address")
+	}
+
+	t.checkBacklog()
+	return t, nil
 }
 
-func NewTCPProcessor(address string, idIssuer id.IDIssuer[uint64]) (*TCPProcessor, error) {
-	l, err := net.Listen("tcp", "0.0.0.0:6379")
+// setBacklog applies tcp-backlog to the listener. Go listens with the backlog of
+// net.core.somaxconn, and listening again on the socket only changes its backlog.
+func setBacklog(l net.Listener, backlog int) error {
+	sc, ok := l.(syscall.Conn)
+	if !ok {
+		return errors.New("listener without file descriptor")
+	}
+
+	rc, err := sc.SyscallConn()
 	if err != nil {
-		return nil, fmt.Errorf("failed to bind to address %s", address)
+		return err
 	}
 
-	return &TCPProcessor{
-		listener: l,
-		idIssuer: idIssuer,
+	var listenErr error
+	if err := rc.Control(func(fd uintptr) { listenErr = syscall.Listen(int(fd), backlog) }); err != nil {
+		return err
+	}
+	return listenErr
+}
+
+// checkBacklog warns when the kernel limits the backlog below tcp-backlog, as it caps the
+// backlog to net.core.somaxconn.
+func (t *TCPProcessor) checkBacklog() {
+	data, err := os.ReadFile(somaxconnPath)
+	if err != nil {
+		return
+	}
+
+	somaxconn, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || somaxconn >= t.backlog {
+		return
+	}
+
+	slog.Warn("the TCP backlog setting cannot be enforced because somaxconn is set to the lower value",
+		slog.Int("tcp-backlog", t.backlog),
+		slog.Int("somaxconn", somaxconn),
+	)
+}
+
+// MaxClients returns the maximum number of connected clients.
+func (t *TCPProcessor) MaxClients() int64 {
+	return t.maxClients.Load()
+}
+
+// SetMaxClients sets the maximum number of connected clients. Clients connected
+// over the limit are not closed.
+func (t *TCPProcessor) SetMaxClients(n int64) {
+	t.maxClients.Store(n)
+}
+
+// KeepAlive returns the period of TCP keepalive probes, 0 if they are disabled.
+func (t *TCPProcessor) KeepAlive() time.Duration {
+	return time.Duration(t.keepAlive.Load())
+}
+
+// SetKeepAlive sets the period of TCP keepalive probes for the connections accepted
+// from now on, 0 to disable them.
+func (t *TCPProcessor) SetKeepAlive(d time.Duration) {
+	t.keepAlive.Store(int64(d))
+}
+
+// MaxBulkLen returns the maximum length of a bulk string accepted from clients.
+func (t *TCPProcessor) MaxBulkLen() int64 {
+	return t.maxBulkLen.Load()
+}
+
+// SetMaxBulkLen sets the maximum length of a bulk string accepted from clients.
+func (t *TCPProcessor) SetMaxBulkLen(n int64) {
+	t.maxBulkLen.Store(n)
+}
+
+// TCPStats are the counters of the connections reported by INFO.
+type TCPStats struct {
+	ConnectionsReceived int64
+	ConnectionsRejected int64
+	NetInputBytes       int64
+	NetOutputBytes      int64
+	ReadsProcessed      int64
+	WritesProcessed     int64
+	InputKbps           float64 // instantaneous
+	OutputKbps          float64
+}
+
+// Stats returns the counters of the connections.
+func (t *TCPProcessor) Stats() TCPStats {
+	return TCPStats{
+		ConnectionsReceived: t.connectionsReceived.Load(),
+		ConnectionsRejected: t.connectionsRejected.Load(),
+		NetInputBytes:       t.netInputBytes.Load(),
+		NetOutputBytes:      t.netOutputBytes.Load(),
+		ReadsProcessed:      t.readsProcessed.Load(),
+		WritesProcessed:     t.writesProcessed.Load(),
+		InputKbps:           t.inputRate.rate() / 1024,
+		OutputKbps:          t.outputRate.rate() / 1024,
+	}
+}
 
-		connMap: pkg.NewConcurrentMap[uint64, *connInfo](),
-	}, nil
+// ResetStats resets the counters on CONFIG RESETSTAT.
+func (t *TCPProcessor) ResetStats() {
+	t.connectionsReceived.Store(0)
+	t.connectionsRejected.Store(0)
+	t.netInputBytes.Store(0)
+	t.netOutputBytes.Store(0)
+	t.readsProcessed.Store(0)
+	t.writesProcessed.Store(0)
+	t.inputRate.reset()
+	t.outputRate.reset()
+}
+
+// StatsCron samples the network traffic for the instantaneous rates.
+func (t *TCPProcessor) StatsCron(now time.Time, _ func(event.Event)) {
+	t.inputRate.track(now, t.netInputBytes.Load())
+	t.outputRate.track(now, t.netOutputBytes.Load())
 }
 
 func (t *TCPProcessor) Close() error {
-	if err := t.listener.Close(); err != nil {
-		return fmt.Errorf("failed to close listener: %w", err)
+	for _, l := range t.listeners {
+		if err := l.Close(); err != nil {
+			return fmt.Errorf("failed to close listener: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// AddCloseListener registers a function called from the event loop after a connection is closed.
+func (t *TCPProcessor) AddCloseListener(listener func(id uint64)) {
+	t.closeListeners = append(t.closeListeners, listener)
+}
+
 func (t *TCPProcessor) InitPushing(push func(event.Event)) {
 	t.pushStopSignal = make(chan struct{})
-	go t.loop(push)
+	for _, l := range t.listeners {
+		go t.loop(l, push)
+	}
 }
 
 func (t *TCPProcessor) ShutdownPushing() {
@@ -57,29 +240,61 @@ func (t *TCPProcessor) ShutdownPushing() {
 	}
 }
 
-func (t *TCPProcessor) loop(push func(event.Event)) {
+func (t *TCPProcessor) loop(l net.Listener, push func(event.Event)) {
 	for {
 		select {
 		case <-t.pushStopSignal:
 			return
 		default:
-			conn, err := t.listener.Accept()
+			conn, err := l.Accept()
 			if err != nil {
 				slog.Error("failed to accept connection", "error", err)
 				continue
 			}
 
-			id := t.idIssuer.Issue()
+			t.connectionsReceived.Add(1)
+			if t.clients.Len() >= int(t.MaxClients()) {
+				t.connectionsRejected.Add(1)
+				t.reject(conn)
+				continue
+			}
+			t.setKeepAlive(conn)
 
-			t.connMap.Store(id, &connInfo{
-				conn: conn,
-			})
+			client := newClient(t.idIssuer.Issue(), conn)
+			t.clients.add(client)
 
-			push(&event.ReadEvent{ID_: id})
+			push(&event.ReadEvent{ID_: client.ID})
 		}
 	}
 }
 
+// reject replies an error to a connection over maxclients and closes it.
+func (t *TCPProcessor) reject(conn net.Conn) {
+	slog.Warn("rejected connection: max number of clients reached",
+		slog.Any("conn", conn.RemoteAddr()),
+	)
+
+	_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_, _ = conn.Write([]byte("-ERR max number of clients reached\r\n"))
+	_ = conn.Close()
+}
+
+func (t *TCPProcessor) setKeepAlive(conn net.Conn) {
+	tcpConn, isTCP := conn.(*net.TCPConn)
+	if !isTCP {
+		return
+	}
+
+	period := t.KeepAlive()
+	if err := tcpConn.SetKeepAlive(period > 0); err != nil {
+		slog.Warn("failed to set keepalive", slog.Any("conn", conn.RemoteAddr()), slog.Any("error", err))
+		return
+	}
+	if period > 0 {
+		_ = tcpConn.SetKeepAlivePeriod(period)
+	}
+}
+
 func (t *TCPProcessor) ReadHandler() *tcpReadHandler {
 	return &tcpReadHandler{
 		tcpProcessor: t,
@@ -110,39 +325,56 @@ func (h *tcpReadHandler) Handle(e event.Event, push func(event.Event)) error {
 		return event.ErrInvalidEventType
 	}
 
-	connInfo, ok := h.tcpProcessor.connMap.Load(readEvent.ID())
+	client, ok := h.tcpProcessor.clients.Get(readEvent.ID())
 	if !ok {
-		return fmt.Errorf("connection does not exists for id %d", readEvent.ID())
+		return fmt.Errorf("client does not exists for id %d", readEvent.ID())
 	}
 
-	if connInfo.scanner == nil {
-		connInfo.scanner = pkg.NewCRLFScanner(connInfo.conn)
+	if client.reader == nil {
+		input := &countingReader{r: client.Conn, count: &h.tcpProcessor.netInputBytes}
+		client.reader = pkg.NewRESPReader(input, func() int {
+			return int(h.tcpProcessor.MaxBulkLen())
+		})
 	}
 
 	go func() {
-		scanned := connInfo.scanner.Scan()
-		if !scanned {
-			if err := connInfo.scanner.Err(); err != nil {
+		// block for the first line, then take every line already read, so
+		// pipelined commands are handled without a read round trip for each
+		line, err := client.reader.Next()
+		h.tcpProcessor.readsProcessed.Add(1)
+		lines := make([][]byte, 0, 1)
+		for err == nil {
+			lines = append(lines, bytes.Clone(line))
+
+			var buffered bool
+			line, buffered, err = client.reader.NextBuffered()
+			if !buffered {
+				break
+			}
+		}
+
+		for i, data := range lines {
+			slog.Info("read from",
+				slog.Uint64("id", readEvent.ID()),
+				slog.Any("conn", client.Conn.RemoteAddr()),
+				slog.Any("data", data),
+			)
+			push(&event.LexingEvent{
+				ID_:  readEvent.ID(),
+				Data: data,
+				Last: i == len(lines)-1 && err == nil,
+			})
+		}
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
 				push(&event.ErrorEvent{
 					Event: readEvent,
 					Err:   err,
 				})
 			}
-			connInfo.scanner = nil
 			push(&event.CloseEvent{ID_: readEvent.ID()})
-			return
 		}
-
-		data := connInfo.scanner.Bytes()
-		slog.Info("read from",
-			slog.Uint64("id", readEvent.ID()),
-			slog.Any("conn", connInfo.conn.RemoteAddr()),
-			slog.Any("data", data),
-		)
-		push(&event.LexingEvent{
-			ID_:  readEvent.ID(),
-			Data: data,
-		})
 	}()
 
 	return nil
@@ -162,50 +394,75 @@ func (h *tcpWriteHandler) Handle(e event.Event, push func(event.Event)) error {
 		return event.ErrInvalidEventType
 	}
 
-	ci, ok := h.tcpProcessor.connMap.Load(writeEvent.ID())
+	client, ok := h.tcpProcessor.clients.Get(writeEvent.ID())
 	if !ok {
-		return fmt.Errorf("connection does not exists for id %d", writeEvent.ID())
+		return nil // the client is closed while the write was in flight
 	}
 
-	go func() {
-		slog.Info("write to",
-			slog.Uint64("id", writeEvent.ID()),
-			slog.Any("conn", ci.conn.RemoteAddr()),
-			slog.Any("data", writeEvent.Data),
-		)
-
-		writer := bufio.NewWriter(ci.conn)
-		_, err := writer.Write(writeEvent.Data)
-		if err != nil {
-			push(&event.ErrorEvent{Event: writeEvent, Err: err})
-			return
+	if writeEvent.Done {
+		client.writing.Reset()
+		if client.writing.Cap() <= maxSpareOutputCap {
+			client.spare = client.writing
 		}
+		client.writing = nil
+	}
 
-		err = writer.Flush()
-		if err != nil {
-			push(&event.ErrorEvent{Event: writeEvent, Err: err})
-			return
+	if client.output.Len() > 0 {
+		h.tcpProcessor.pendingWrites[writeEvent.ID()] = struct{}{}
+	}
+
+	return nil
+}
+
+// Flush starts writing the output of the connections that received replies since the last flush.
+// Only one write is in progress for a connection at a time, and the output buffered meanwhile is
+// written on the first flush after it is done.
+func (t *TCPProcessor) Flush(push func(event.Event)) {
+	for id := range t.pendingWrites {
+		client, found := t.clients.Get(id)
+		if !found || client.writing != nil {
+			continue
 		}
+		delete(t.pendingWrites, id)
 
-		// maybe more data is available to read, so we always publish ReadEvent
-		id := h.tcpProcessor.idIssuer.Issue()
-		h.tcpProcessor.connMap.Store(
-			id,
-			&connInfo{
-				conn:    ci.conn,
-				scanner: ci.scanner,
-			},
-		)
-
-		slog.Info("pushing read event after write",
-			slog.Uint64("from_id", writeEvent.ID()),
-			slog.Uint64("to_id", id),
-			slog.Any("conn", ci.conn.RemoteAddr()),
-		)
-		push(&event.ReadEvent{ID_: id})
-	}()
+		if client.output.Len() == 0 {
+			continue
+		}
 
-	return nil
+		client.writing, client.output = client.output, client.spare
+		if client.output == nil {
+			client.output = new(bytes.Buffer)
+		}
+		client.spare = nil
+
+		data := client.writing.Bytes()
+		closeAfterWrite := client.closeAfterReply
+		go func() {
+			slog.Info("write to",
+				slog.Uint64("id", id),
+				slog.Any("conn", client.Conn.RemoteAddr()),
+				slog.Int("bytes", len(data)),
+			)
+
+			n, err := client.Conn.Write(data)
+			t.netOutputBytes.Add(int64(n))
+			t.writesProcessed.Add(1)
+			if err != nil {
+				// the write is never done, so the connection is closed and cleaned up once the
+				// reading goroutine sees it closed, as for a client killed
+				push(&event.ErrorEvent{Event: &event.WriteEvent{ID_: id}, Err: err})
+				_ = client.Conn.Close()
+				return
+			}
+
+			if closeAfterWrite {
+				_ = client.Conn.Close()
+				return
+			}
+
+			push(&event.WriteEvent{ID_: id, Done: true})
+		}()
+	}
 }
 
 type tcpCloseHandler struct {
@@ -222,16 +479,21 @@ func (h *tcpCloseHandler) Handle(e event.Event, push func(event.Event)) error {
 		return event.ErrInvalidEventType
 	}
 
-	connInfo, loaded := h.tcpProcessor.connMap.LoadAndDelete(closeEvent.ID())
+	client, loaded := h.tcpProcessor.clients.remove(closeEvent.ID())
 	if !loaded {
 		return nil // when connection is already closed, do nothing
 	}
 
 	slog.Info("closing....",
 		slog.Uint64("id", closeEvent.ID()),
-		slog.Any("conn", connInfo.conn.RemoteAddr()),
+		slog.Any("conn", client.Conn.RemoteAddr()),
 	)
-	if err := connInfo.conn.Close(); err != nil {
+	delete(h.tcpProcessor.pendingWrites, closeEvent.ID())
+	for _, l := range h.tcpProcessor.closeListeners {
+		l(closeEvent.ID())
+	}
+
+	if err := client.Conn.Close(); err != nil {
 		push(&event.ErrorEvent{Event: closeEvent, Err: fmt.Errorf("failed to close connection: %w", err)})
 		return nil
 	}
@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/event"
+)
+
+// TimeoutCron closes the clients idle for longer than the timeout. Subscribed clients
+// only wait for messages, and clients postponed by a pause are not idle by themselves,
+// so both are kept.
+func (e *Executor) TimeoutCron(now time.Time, _ func(event.Event)) {
+	if e.idleTimeout <= 0 {
+		return
+	}
+
+	for _, client := range e.clients.All() {
+		if now.Sub(client.LastInteraction) <= e.idleTimeout {
+			continue
+		}
+		if e.pubsub.SubscriptionCount(client.ID) > 0 || e.postponed(client.ID) {
+			continue
+		}
+
+		slog.Info("closing idle client",
+			slog.Uint64("id", client.ID),
+			slog.Any("conn", client.Conn.RemoteAddr()),
+		)
+		_ = client.Conn.Close()
+	}
+}
+
+// postponed reports whether the client has commands postponed by CLIENT PAUSE.
+func (e *Executor) postponed(id uint64) bool {
+	if e.pause == nil {
+		return false
+	}
+
+	return slices.ContainsFunc(e.pause.deferred, func(ev *event.ExecuteEvent) bool {
+		return ev.ID() == id
+	})
+}
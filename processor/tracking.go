@@ -0,0 +1,334 @@
+package processor
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/event"
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// invalidationChannel is the channel RESP2 clients subscribe to for the invalidation
+// messages redirected to them.
+const invalidationChannel = "__redis__:invalidate"
+
+// clientTracking is the state of CLIENT TRACKING of a client.
+type clientTracking struct {
+	redirect       uint64 // 0 for no redirection
+	bcast          bool
+	optIn          bool
+	optOut         bool
+	noLoop         bool
+	prefixes       []string // registered in BCAST mode
+	caching        bool     // CLIENT CACHING was given for the next command
+	brokenRedirect bool     // the redirection client is closed
+}
+
+func (e *Executor) clientTracking(id uint64, cmd *spec.ClientTrackingCommand) (spec.Data, error) {
+	client, found := e.clients.Get(id)
+	if !found {
+		return nil, spec.ErrorOf("ERR", "client is already closed")
+	}
+
+	if !cmd.On {
+		e.disableTracking(client)
+		return spec.SimpleStringOf("OK"), nil
+	}
+
+	if cmd.Redirect != 0 {
+		if _, found := e.clients.Get(cmd.Redirect); !found {
+			return nil, spec.ErrorOf("ERR", "The client ID you want redirect to does not exist")
+		}
+	}
+
+	if len(cmd.Prefixes) > 0 && !cmd.BCast {
+		return nil, spec.ErrorOf("ERR", "PREFIX option requires BCAST mode to be enabled")
+	}
+	if cmd.OptIn && cmd.OptOut {
+		return nil, spec.ErrorOf("ERR", "You can't use both OPTIN and OPTOUT")
+	}
+	if cmd.BCast && (cmd.OptIn || cmd.OptOut) {
+		return nil, spec.ErrorOf("ERR", "OPTIN and OPTOUT are not compatible with BCAST")
+	}
+
+	if t := client.tracking; t != nil {
+		if t.bcast != cmd.BCast {
+			return nil, spec.ErrorOf("ERR", "You can't switch BCAST mode on/off before disabling tracking for this client, and then re-enabling it with a different mode.")
+		}
+		if t.optIn != cmd.OptIn || t.optOut != cmd.OptOut {
+			return nil, spec.ErrorOf("ERR", "You can't switch OPTIN/OPTOUT mode before disabling tracking for this client, and then re-enabling it with a different mode.")
+		}
+	}
+
+	prefixes := cmd.Prefixes
+	if cmd.BCast && len(prefixes) == 0 && client.tracking == nil {
+		prefixes = []string{""} // every key
+	}
+	if err := e.checkPrefixes(client, prefixes); err != nil {
+		return nil, err
+	}
+
+	t := client.tracking
+	if t == nil {
+		t = &clientTracking{}
+		client.tracking = t
+	}
+	t.redirect = cmd.Redirect
+	t.bcast = cmd.BCast
+	t.optIn = cmd.OptIn
+	t.optOut = cmd.OptOut
+	t.noLoop = cmd.NoLoop
+	t.brokenRedirect = false
+
+	for _, prefix := range prefixes {
+		if slices.Contains(t.prefixes, prefix) {
+			continue
+		}
+		t.prefixes = append(t.prefixes, prefix)
+		subscribe(e.trackingPrefixes, prefix, client.ID)
+	}
+
+	return spec.SimpleStringOf("OK"), nil
+}
+
+// checkPrefixes rejects prefixes overlapping with each other or with the ones already
+// registered by the client, since a key would be invalidated more than once.
+func (e *Executor) checkPrefixes(client *Client, prefixes []string) error {
+	var registered []string
+	if client.tracking != nil {
+		registered = client.tracking.prefixes
+	}
+
+	for i, prefix := range prefixes {
+		others := append(slices.Clone(registered), prefixes[i+1:]...)
+		for _, other := range others {
+			if prefix == other {
+				continue
+			}
+			if strings.HasPrefix(prefix, other) || strings.HasPrefix(other, prefix) {
+				return spec.ErrorOf("ERR", "Prefix '%s' overlaps with an existing prefix '%s'. Prefixes for a single client must not overlap.", prefix, other)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *Executor) disableTracking(client *Client) {
+	if client.tracking == nil {
+		return
+	}
+
+	for _, prefix := range client.tracking.prefixes {
+		unsubscribe(e.trackingPrefixes, prefix, client.ID)
+	}
+	client.tracking = nil
+}
+
+func (e *Executor) clientCaching(id uint64, yes bool) (spec.Data, error) {
+	client, found := e.clients.Get(id)
+	if !found {
+		return nil, spec.ErrorOf("ERR", "client is already closed")
+	}
+
+	t := client.tracking
+	if t == nil || (!t.optIn && !t.optOut) {
+		return nil, spec.ErrorOf("ERR", "CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled")
+	}
+	if yes && !t.optIn {
+		return nil, spec.ErrorOf("ERR", "CLIENT CACHING YES is only valid when tracking is enabled in OPTIN mode.")
+	}
+	if !yes && !t.optOut {
+		return nil, spec.ErrorOf("ERR", "CLIENT CACHING NO is only valid when tracking is enabled in OPTOUT mode.")
+	}
+
+	t.caching = true
+	return spec.SimpleStringOf("OK"), nil
+}
+
+// trackingRedirect returns the redirection reported by CLIENT GETREDIR and CLIENT LIST,
+// which is -1 when tracking is off.
+func trackingRedirect(client *Client) int64 {
+	if client.tracking == nil {
+		return -1
+	}
+
+	return int64(client.tracking.redirect)
+}
+
+func (e *Executor) clientTrackingInfo(id uint64) (spec.Data, error) {
+	client, found := e.clients.Get(id)
+	if !found {
+		return nil, spec.ErrorOf("ERR", "client is already closed")
+	}
+
+	t := client.tracking
+	if t == nil {
+		return spec.MapOf(
+			spec.BulkStringOf("flags"), spec.SetOf(spec.BulkStringOf("off")),
+			spec.BulkStringOf("redirect"), spec.IntegerOf(-1),
+			spec.BulkStringOf("prefixes"), spec.ArrayOf(),
+		), nil
+	}
+
+	flags := []string{"on"}
+	switch {
+	case t.bcast:
+		flags = append(flags, "bcast")
+	case t.optIn:
+		flags = append(flags, "optin")
+		if t.caching {
+			flags = append(flags, "caching-yes")
+		}
+	case t.optOut:
+		flags = append(flags, "optout")
+		if t.caching {
+			flags = append(flags, "caching-no")
+		}
+	}
+	if t.noLoop {
+		flags = append(flags, "noloop")
+	}
+	if t.brokenRedirect {
+		flags = append(flags, "broken_redirect")
+	}
+
+	flagData := make([]spec.Data, 0, len(flags))
+	for _, flag := range flags {
+		flagData = append(flagData, spec.BulkStringOf(flag))
+	}
+
+	return spec.MapOf(
+		spec.BulkStringOf("flags"), spec.SetOf(flagData...),
+		spec.BulkStringOf("redirect"), spec.IntegerOf(int64(t.redirect)),
+		spec.BulkStringOf("prefixes"), spec.BulkStringArrayOf(t.prefixes...),
+	), nil
+}
+
+// trackRead remembers that the client read the key, so it is invalidated once the key
+// is modified.
+func (e *Executor) trackRead(id uint64, key string) {
+	client, found := e.clients.Get(id)
+	if !found || client.tracking == nil {
+		return
+	}
+
+	t := client.tracking
+	switch {
+	case t.bcast:
+		return
+	case t.optIn && !t.caching, t.optOut && t.caching:
+		return
+	}
+
+	subscribe(e.trackedKeys, key, id)
+}
+
+// endCaching resets CLIENT CACHING once the command after it is executed. A transaction
+// keeps it until EXEC, so every command of the transaction is affected.
+func (e *Executor) endCaching(id uint64, cmd spec.Command) {
+	client, found := e.clients.Get(id)
+	if !found || client.tracking == nil {
+		return
+	}
+
+	if _, isCaching := cmd.(*spec.ClientCachingCommand); isCaching {
+		return
+	}
+	if _, inMulti := e.transactions[id]; inMulti {
+		return
+	}
+
+	client.tracking.caching = false
+}
+
+// invalidateKey sends invalidation messages to the clients which read the modified key
+// or registered a prefix of it.
+func (e *Executor) invalidateKey(key string) {
+	if e.flushing {
+		return // every client is invalidated at once after a flush
+	}
+
+	keyData := spec.BulkStringArrayOf(key)
+
+	ids := e.trackedKeys[key]
+	delete(e.trackedKeys, key)
+	for id := range ids {
+		client, found := e.clients.Get(id)
+		if !found || client.tracking == nil || client.tracking.bcast {
+			continue
+		}
+		e.sendInvalidation(client, keyData)
+	}
+
+	for prefix, ids := range e.trackingPrefixes {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		for id := range ids {
+			if client, found := e.clients.Get(id); found {
+				e.sendInvalidation(client, keyData)
+			}
+		}
+	}
+}
+
+// invalidateAll sends an invalidation message with a null key list to every tracking
+// client, which means all the keys are invalidated.
+func (e *Executor) invalidateAll() {
+	clear(e.trackedKeys)
+
+	for _, client := range e.clients.All() {
+		if client.tracking != nil {
+			e.sendInvalidation(client, spec.NullArray())
+		}
+	}
+}
+
+// sendInvalidation sends the invalidation message to the client or the client it is
+// redirected to. RESP2 clients only receive them redirected to a client subscribed to
+// __redis__:invalidate.
+func (e *Executor) sendInvalidation(client *Client, keys spec.Data) {
+	t := client.tracking
+	if t.noLoop && client.ID == e.caller {
+		return
+	}
+
+	target := client
+	if t.redirect != 0 {
+		redirected, found := e.clients.Get(t.redirect)
+		if !found {
+			if !t.brokenRedirect && client.Protocol >= spec.RESP3 {
+				e.push(&event.FormatEvent{
+					ID_: client.ID,
+					Data: spec.PushOf(
+						spec.BulkStringOf("tracking-redir-broken"),
+						spec.IntegerOf(int64(t.redirect)),
+					),
+				})
+			}
+			t.brokenRedirect = true
+			return
+		}
+		target = redirected
+	}
+
+	switch {
+	case target.Protocol >= spec.RESP3:
+		e.push(&event.FormatEvent{
+			ID_:  target.ID,
+			Data: spec.PushOf(spec.BulkStringOf("invalidate"), keys),
+		})
+	case t.redirect != 0:
+		e.pubsub.PublishTo(target.ID, invalidationChannel, keys)
+	}
+}
+
+// untrack drops the BCAST prefixes of a closed client. Its tracked keys are dropped
+// lazily once they are invalidated.
+func (e *Executor) untrack(id uint64) {
+	for prefix := range e.trackingPrefixes {
+		unsubscribe(e.trackingPrefixes, prefix, id)
+	}
+}
@@ -0,0 +1,238 @@
+package processor
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// invalidate returns the invalidation message of the key pushed to a RESP3 client.
+func invalidate(key string) string {
+	return fmt.Sprintf(">2\r\n$10\r\ninvalidate\r\n*1\r\n$%d\r\n%s\r\n", len(key), key)
+}
+
+// invalidateMessage returns the invalidation message of the key published to a RESP2
+// client subscribed to __redis__:invalidate.
+func invalidateMessage(key string) string {
+	return fmt.Sprintf("*3\r\n$7\r\nmessage\r\n$20\r\n__redis__:invalidate\r\n*1\r\n$%d\r\n%s\r\n", len(key), key)
+}
+
+func TestTracking(t *testing.T) {
+	// clients: 0 tracks keys, 1 writes them and 2 receives the invalidations redirected to it
+	const (
+		ok   = "+OK\r\n"
+		null = "_\r\n"
+	)
+	hello := command{args: []string{"HELLO", "3"}, want: helloReply(3, 1)}
+	subscribe := command{
+		client: 2,
+		args:   []string{"SUBSCRIBE", "__redis__:invalidate"},
+		want:   "*3\r\n$9\r\nsubscribe\r\n$20\r\n__redis__:invalidate\r\n:1\r\n",
+	}
+
+	tests := []struct {
+		name     string
+		commands []command
+		messages [][]string // pushed to each client
+	}{
+		{
+			name: "default",
+			commands: []command{
+				hello,
+				{args: []string{"CLIENT", "TRACKING", "ON"}, want: ok},
+				{args: []string{"GET", "k"}, want: null},
+				{args: []string{"GET", "j"}, want: null},
+				{client: 1, args: []string{"SET", "k", "1"}, want: ok},
+				// the key is invalidated once until it is read again
+				{client: 1, args: []string{"SET", "k", "2"}, want: ok},
+				{client: 1, args: []string{"SET", "other", "1"}, want: ok},
+				{args: []string{"SET", "j", "1"}, want: ok},
+			},
+			messages: [][]string{{invalidate("k"), invalidate("j")}, nil, nil},
+		},
+		{
+			name: "bcast",
+			commands: []command{
+				hello,
+				{args: []string{"CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "a:", "PREFIX", "b:"}, want: ok},
+				{client: 1, args: []string{"SET", "a:1", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "c:1", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "b:1", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "a:1", "v"}, want: ok},
+			},
+			messages: [][]string{{invalidate("a:1"), invalidate("b:1"), invalidate("a:1")}, nil, nil},
+		},
+		{
+			name: "bcast without prefix",
+			commands: []command{
+				hello,
+				{args: []string{"CLIENT", "TRACKING", "ON", "BCAST"}, want: ok},
+				{client: 1, args: []string{"SET", "k", "v"}, want: ok},
+			},
+			messages: [][]string{{invalidate("k")}, nil, nil},
+		},
+		{
+			name: "optin",
+			commands: []command{
+				hello,
+				{args: []string{"CLIENT", "TRACKING", "ON", "OPTIN"}, want: ok},
+				{args: []string{"GET", "k"}, want: null},
+				{args: []string{"CLIENT", "CACHING", "YES"}, want: ok},
+				{args: []string{"GET", "j"}, want: null},
+				{args: []string{"GET", "other"}, want: null},
+				{client: 1, args: []string{"SET", "k", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "j", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "other", "v"}, want: ok},
+			},
+			messages: [][]string{{invalidate("j")}, nil, nil},
+		},
+		{
+			name: "optout",
+			commands: []command{
+				hello,
+				{args: []string{"CLIENT", "TRACKING", "ON", "OPTOUT"}, want: ok},
+				{args: []string{"CLIENT", "CACHING", "NO"}, want: ok},
+				{args: []string{"GET", "k"}, want: null},
+				{args: []string{"GET", "j"}, want: null},
+				{client: 1, args: []string{"SET", "k", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "j", "v"}, want: ok},
+			},
+			messages: [][]string{{invalidate("j")}, nil, nil},
+		},
+		{
+			name: "noloop",
+			commands: []command{
+				hello,
+				{args: []string{"CLIENT", "TRACKING", "ON", "NOLOOP"}, want: ok},
+				{args: []string{"GET", "k"}, want: null},
+				{args: []string{"GET", "j"}, want: null},
+				{args: []string{"SET", "k", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "j", "v"}, want: ok},
+			},
+			messages: [][]string{{invalidate("j")}, nil, nil},
+		},
+		{
+			name: "bcast noloop",
+			commands: []command{
+				hello,
+				{args: []string{"CLIENT", "TRACKING", "ON", "BCAST", "NOLOOP"}, want: ok},
+				{args: []string{"SET", "k", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "j", "v"}, want: ok},
+			},
+			messages: [][]string{{invalidate("j")}, nil, nil},
+		},
+		{
+			// RESP2 clients only receive the invalidations redirected to another client
+			name: "resp2 without redirect",
+			commands: []command{
+				{args: []string{"CLIENT", "TRACKING", "ON"}, want: ok},
+				{args: []string{"GET", "k"}, want: "$-1\r\n"},
+				{client: 1, args: []string{"SET", "k", "v"}, want: ok},
+			},
+			messages: [][]string{nil, nil, nil},
+		},
+		{
+			name: "redirect",
+			commands: []command{
+				subscribe,
+				{args: []string{"CLIENT", "TRACKING", "ON", "REDIRECT", "3"}, want: ok},
+				{args: []string{"GET", "k"}, want: "$-1\r\n"},
+				{client: 1, args: []string{"SET", "k", "v"}, want: ok},
+			},
+			messages: [][]string{nil, nil, {invalidateMessage("k")}},
+		},
+		{
+			name: "redirect bcast",
+			commands: []command{
+				subscribe,
+				{args: []string{"CLIENT", "TRACKING", "ON", "REDIRECT", "3", "BCAST", "PREFIX", "a:"}, want: ok},
+				{client: 1, args: []string{"SET", "a:1", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "b:1", "v"}, want: ok},
+			},
+			messages: [][]string{nil, nil, {invalidateMessage("a:1")}},
+		},
+		{
+			name: "redirect optin",
+			commands: []command{
+				subscribe,
+				{args: []string{"CLIENT", "TRACKING", "ON", "REDIRECT", "3", "OPTIN"}, want: ok},
+				{args: []string{"GET", "k"}, want: "$-1\r\n"},
+				{args: []string{"CLIENT", "CACHING", "YES"}, want: ok},
+				{args: []string{"GET", "j"}, want: "$-1\r\n"},
+				{client: 1, args: []string{"SET", "k", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "j", "v"}, want: ok},
+			},
+			messages: [][]string{nil, nil, {invalidateMessage("j")}},
+		},
+		{
+			name: "redirect optout",
+			commands: []command{
+				subscribe,
+				{args: []string{"CLIENT", "TRACKING", "ON", "REDIRECT", "3", "OPTOUT"}, want: ok},
+				{args: []string{"CLIENT", "CACHING", "NO"}, want: ok},
+				{args: []string{"GET", "k"}, want: "$-1\r\n"},
+				{args: []string{"GET", "j"}, want: "$-1\r\n"},
+				{client: 1, args: []string{"SET", "k", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "j", "v"}, want: ok},
+			},
+			messages: [][]string{nil, nil, {invalidateMessage("j")}},
+		},
+		{
+			name: "redirect noloop",
+			commands: []command{
+				subscribe,
+				{args: []string{"CLIENT", "TRACKING", "ON", "REDIRECT", "3", "NOLOOP"}, want: ok},
+				{args: []string{"GET", "k"}, want: "$-1\r\n"},
+				{args: []string{"GET", "j"}, want: "$-1\r\n"},
+				{args: []string{"SET", "k", "v"}, want: ok},
+				{client: 1, args: []string{"SET", "j", "v"}, want: ok},
+			},
+			messages: [][]string{nil, nil, {invalidateMessage("j")}},
+		},
+		{
+			name: "flushdb",
+			commands: []command{
+				hello,
+				{args: []string{"CLIENT", "TRACKING", "ON"}, want: ok},
+				{client: 1, args: []string{"FLUSHDB"}, want: ok},
+			},
+			messages: [][]string{{">2\r\n$10\r\ninvalidate\r\n_\r\n"}, nil, nil},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			ids := s.run(3, tt.commands)
+
+			for i, want := range tt.messages {
+				if got := s.take(ids[i]); !slices.Equal(got, want) {
+					t.Errorf("client %d: messages = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTrackingBrokenRedirect(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.run(3, []command{
+		{args: []string{"HELLO", "3"}, want: helloReply(3, 1)},
+		{args: []string{"CLIENT", "TRACKING", "ON", "REDIRECT", "3"}, want: "+OK\r\n"},
+		{args: []string{"GET", "k"}, want: "_\r\n"},
+	})
+	s.disconnect(ids[2])
+
+	// the broken redirection is reported once
+	for _, args := range [][]string{{"SET", "k", "1"}, {"GET", "k"}, {"SET", "k", "2"}} {
+		s.do(ids[1], args...)
+	}
+	want := []string{">2\r\n$21\r\ntracking-redir-broken\r\n:3\r\n"}
+	if got := s.take(ids[0]); !slices.Equal(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+
+	if got, want := s.do(ids[0], "CLIENT", "TRACKINGINFO"), "%3\r\n$5\r\nflags\r\n~2\r\n$2\r\non\r\n$15\r\nbroken_redirect\r\n$8\r\nredirect\r\n:3\r\n$8\r\nprefixes\r\n*0\r\n"; got != want {
+		t.Errorf("CLIENT TRACKINGINFO = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// transaction is the state of a connection between MULTI and EXEC/DISCARD.
+type transaction struct {
+	queue  []queuedCommand
+	failed bool // a command failed to be queued, so EXEC must abort
+}
+
+// queuedCommand is a command queued in a transaction along with its redacted arguments,
+// fed to monitors once EXEC runs the command.
+type queuedCommand struct {
+	cmd  spec.Command
+	args []string
+}
+
+// watch is the set of keys watched by a connection for optimistic locking.
+type watch struct {
+	keys  map[string]struct{}
+	dirty bool // a watched key was touched, so EXEC must fail
+}
+
+func (e *Executor) multi(id uint64) (spec.Data, error) {
+	if _, inMulti := e.transactions[id]; inMulti {
+		return nil, spec.ErrorOf("ERR", "MULTI calls can not be nested")
+	}
+
+	e.transactions[id] = &transaction{}
+	return spec.SimpleStringOf("OK"), nil
+}
+
+func (e *Executor) exec(id uint64) (spec.Data, error) {
+	tx, inMulti := e.transactions[id]
+	if !inMulti {
+		return nil, spec.ErrorOf("ERR", "EXEC without MULTI")
+	}
+	delete(e.transactions, id)
+
+	if tx.failed {
+		e.unwatch(id)
+		return nil, spec.ErrorOf("EXECABORT", "Transaction discarded because of previous errors.")
+	}
+
+	// watched keys which expired but are not removed yet must abort the transaction as well
+	e.storage.ExpireAllUntil(time.Now())
+	if w, watching := e.watches[id]; watching && w.dirty {
+		e.unwatch(id)
+		return spec.NullArray(), nil
+	}
+	e.unwatch(id)
+
+	// every queued command runs within this single event, so no other command can interleave
+	client, found := e.clients.Get(id)
+	replies := make([]spec.Data, 0, len(tx.queue))
+	for _, queued := range tx.queue {
+		if found {
+			e.feedMonitors(client.Addr(), queued.args)
+		}
+		reply, err := e.Execute(id, queued.cmd)
+		if err != nil {
+			reply = errorReplyOf(err)
+		}
+		replies = append(replies, reply)
+	}
+
+	return spec.ArrayOf(replies...), nil
+}
+
+func (e *Executor) discard(id uint64) (spec.Data, error) {
+	if _, inMulti := e.transactions[id]; !inMulti {
+		return nil, spec.ErrorOf("ERR", "DISCARD without MULTI")
+	}
+
+	delete(e.transactions, id)
+	e.unwatch(id)
+	return spec.SimpleStringOf("OK"), nil
+}
+
+func (e *Executor) watch(id uint64, keys []string) (spec.Data, error) {
+	if _, inMulti := e.transactions[id]; inMulti {
+		return nil, spec.ErrorOf("ERR", "WATCH inside MULTI is not allowed")
+	}
+
+	// remove keys already expired, so that their removal does not touch the watched keys later
+	e.storage.ExpireAllUntil(time.Now())
+
+	w, watching := e.watches[id]
+	if !watching {
+		w = &watch{keys: make(map[string]struct{})}
+		e.watches[id] = w
+	}
+
+	for _, key := range keys {
+		if _, found := w.keys[key]; found {
+			continue
+		}
+
+		w.keys[key] = struct{}{}
+
+		ids, found := e.watchedKeys[key]
+		if !found {
+			ids = make(map[uint64]struct{})
+			e.watchedKeys[key] = ids
+		}
+		ids[id] = struct{}{}
+	}
+
+	return spec.SimpleStringOf("OK"), nil
+}
+
+func (e *Executor) unwatch(id uint64) {
+	w, watching := e.watches[id]
+	if !watching {
+		return
+	}
+
+	for key := range w.keys {
+		ids := e.watchedKeys[key]
+		delete(ids, id)
+		if len(ids) == 0 {
+			delete(e.watchedKeys, key)
+		}
+	}
+	delete(e.watches, id)
+}
+
+// touchWatchedKey is called by the storage for every modified key, invalidating the watches on it.
+func (e *Executor) touchWatchedKey(key string) {
+	for id := range e.watchedKeys[key] {
+		e.watches[id].dirty = true
+	}
+}
+
+// queue queues the command if the connection is in a transaction.
+func (e *Executor) queue(id uint64, cmd spec.Command, args []string) (spec.Data, bool) {
+	tx, inMulti := e.transactions[id]
+	// a busy script rejects the command rather than letting it be queued
+	if !inMulti || e.yielding {
+		return nil, false
+	}
+
+	switch cmd.(type) {
+	case *spec.MultiCommand, *spec.ExecCommand, *spec.DiscardCommand, *spec.WatchCommand, *spec.MonitorCommand:
+		return nil, false
+	}
+
+	tx.queue = append(tx.queue, queuedCommand{cmd: cmd, args: args})
+	return spec.SimpleStringOf("QUEUED"), true
+}
+
+// failTransaction marks the transaction of the connection, if any, to be aborted on EXEC.
+func (e *Executor) failTransaction(id uint64) {
+	if tx, inMulti := e.transactions[id]; inMulti {
+		tx.failed = true
+	}
+}
@@ -0,0 +1,237 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []command
+	}{
+		{
+			name: "exec",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "v"}, want: "+QUEUED\r\n"},
+				{args: []string{"GET", "k"}, want: "+QUEUED\r\n"},
+				{args: []string{"EXEC"}, want: "*2\r\n+OK\r\n$1\r\nv\r\n"},
+				{args: []string{"GET", "k"}, want: "$1\r\nv\r\n"},
+			},
+		},
+		{
+			name: "empty exec",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"EXEC"}, want: "*0\r\n"},
+			},
+		},
+		{
+			name: "queued commands run on exec only",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "v"}, want: "+QUEUED\r\n"},
+				{client: 1, args: []string{"GET", "k"}, want: "$-1\r\n"},
+				{args: []string{"EXEC"}, want: "*1\r\n+OK\r\n"},
+				{client: 1, args: []string{"GET", "k"}, want: "$1\r\nv\r\n"},
+			},
+		},
+		{
+			name: "parse error aborts exec",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "v"}, want: "+QUEUED\r\n"},
+				{args: []string{"GET"}, want: "-ERR invalid format for GET command: invalid GET command format: expected 1 argument, got 0\r\n"},
+				{args: []string{"EXEC"}, want: "-EXECABORT Transaction discarded because of previous errors.\r\n"},
+				{args: []string{"GET", "k"}, want: "$-1\r\n"},
+			},
+		},
+		{
+			name: "unknown command aborts exec",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"NOSUCHCOMMAND"}, want: "-ERR unknown command 'NOSUCHCOMMAND'\r\n"},
+				{args: []string{"EXEC"}, want: "-EXECABORT Transaction discarded because of previous errors.\r\n"},
+			},
+		},
+		{
+			name: "runtime error does not stop the other commands",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "a", "1"}, want: "+QUEUED\r\n"},
+				{args: []string{"CONFIG", "SET", "nosuchparameter", "1"}, want: "+QUEUED\r\n"},
+				{args: []string{"SET", "b", "2"}, want: "+QUEUED\r\n"},
+				{args: []string{"EXEC"}, want: "*3\r\n+OK\r\n-ERR Unknown option or number of arguments for CONFIG SET - 'nosuchparameter'\r\n+OK\r\n"},
+				{args: []string{"GET", "b"}, want: "$1\r\n2\r\n"},
+			},
+		},
+		{
+			name: "discard",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "v"}, want: "+QUEUED\r\n"},
+				{args: []string{"DISCARD"}, want: "+OK\r\n"},
+				{args: []string{"GET", "k"}, want: "$-1\r\n"},
+				{args: []string{"EXEC"}, want: "-ERR EXEC without MULTI\r\n"},
+			},
+		},
+		{
+			name: "exec without multi",
+			commands: []command{
+				{args: []string{"EXEC"}, want: "-ERR EXEC without MULTI\r\n"},
+			},
+		},
+		{
+			name: "discard without multi",
+			commands: []command{
+				{args: []string{"DISCARD"}, want: "-ERR DISCARD without MULTI\r\n"},
+			},
+		},
+		{
+			name: "nested multi",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "-ERR MULTI calls can not be nested\r\n"},
+				{args: []string{"SET", "k", "v"}, want: "+QUEUED\r\n"},
+				{args: []string{"EXEC"}, want: "*1\r\n+OK\r\n"},
+			},
+		},
+		{
+			name: "transactions are per client",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{client: 1, args: []string{"EXEC"}, want: "-ERR EXEC without MULTI\r\n"},
+				{client: 1, args: []string{"SET", "k", "v"}, want: "+OK\r\n"},
+				{args: []string{"EXEC"}, want: "*0\r\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestServer(t).run(2, tt.commands)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []command
+	}{
+		{
+			name: "untouched key",
+			commands: []command{
+				{args: []string{"SET", "k", "1"}, want: "+OK\r\n"},
+				{args: []string{"WATCH", "k"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "2"}, want: "+QUEUED\r\n"},
+				{args: []string{"EXEC"}, want: "*1\r\n+OK\r\n"},
+			},
+		},
+		{
+			name: "write from another client",
+			commands: []command{
+				{args: []string{"WATCH", "k"}, want: "+OK\r\n"},
+				{client: 1, args: []string{"SET", "k", "other"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "mine"}, want: "+QUEUED\r\n"},
+				{args: []string{"EXEC"}, want: "*-1\r\n"},
+				{args: []string{"GET", "k"}, want: "$5\r\nother\r\n"},
+			},
+		},
+		{
+			name: "write while queueing",
+			commands: []command{
+				{args: []string{"WATCH", "k"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "mine"}, want: "+QUEUED\r\n"},
+				{client: 1, args: []string{"SET", "k", "other"}, want: "+OK\r\n"},
+				{args: []string{"EXEC"}, want: "*-1\r\n"},
+			},
+		},
+		{
+			name: "write of another key",
+			commands: []command{
+				{args: []string{"WATCH", "k"}, want: "+OK\r\n"},
+				{client: 1, args: []string{"SET", "other", "v"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"EXEC"}, want: "*0\r\n"},
+			},
+		},
+		{
+			name: "flushdb",
+			commands: []command{
+				{args: []string{"SET", "k", "v"}, want: "+OK\r\n"},
+				{args: []string{"WATCH", "k"}, want: "+OK\r\n"},
+				{client: 1, args: []string{"FLUSHDB"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"EXEC"}, want: "*-1\r\n"},
+			},
+		},
+		{
+			name: "unwatch",
+			commands: []command{
+				{args: []string{"WATCH", "k"}, want: "+OK\r\n"},
+				{client: 1, args: []string{"SET", "k", "other"}, want: "+OK\r\n"},
+				{args: []string{"UNWATCH"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"SET", "k", "mine"}, want: "+QUEUED\r\n"},
+				{args: []string{"EXEC"}, want: "*1\r\n+OK\r\n"},
+			},
+		},
+		{
+			name: "exec clears the watches",
+			commands: []command{
+				{args: []string{"WATCH", "k"}, want: "+OK\r\n"},
+				{client: 1, args: []string{"SET", "k", "other"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"EXEC"}, want: "*-1\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"EXEC"}, want: "*0\r\n"},
+			},
+		},
+		{
+			name: "discard clears the watches",
+			commands: []command{
+				{args: []string{"WATCH", "k"}, want: "+OK\r\n"},
+				{client: 1, args: []string{"SET", "k", "other"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"DISCARD"}, want: "+OK\r\n"},
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"EXEC"}, want: "*0\r\n"},
+			},
+		},
+		{
+			name: "watch inside multi",
+			commands: []command{
+				{args: []string{"MULTI"}, want: "+OK\r\n"},
+				{args: []string{"WATCH", "k"}, want: "-ERR WATCH inside MULTI is not allowed\r\n"},
+				{args: []string{"EXEC"}, want: "*0\r\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestServer(t).run(2, tt.commands)
+		})
+	}
+}
+
+func TestWatchExpiredKey(t *testing.T) {
+	s := newTestServer(t)
+	ids := s.run(1, []command{
+		{args: []string{"SET", "k", "v", "PX", "1"}, want: "+OK\r\n"},
+		{args: []string{"WATCH", "k"}, want: "+OK\r\n"},
+		{args: []string{"MULTI"}, want: "+OK\r\n"},
+		{args: []string{"SET", "k", "mine"}, want: "+QUEUED\r\n"},
+	})
+
+	// the key expires without being removed, which EXEC must notice by itself
+	time.Sleep(5 * time.Millisecond)
+	if got, want := s.do(ids[0], "EXEC"), "*-1\r\n"; got != want {
+		t.Errorf("EXEC = %q, want %q", got, want)
+	}
+}
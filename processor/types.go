@@ -6,4 +6,16 @@ const (
 	BulkStringPrefix   = '$'
 	ArrayPrefix        = '*'
 	SimpleErrorPrefix  = '-'
+
+	// RESP3
+	NullPrefix           = '_'
+	BooleanPrefix        = '#'
+	DoublePrefix         = ','
+	BigNumberPrefix      = '('
+	BulkErrorPrefix      = '!'
+	VerbatimStringPrefix = '='
+	MapPrefix            = '%'
+	SetPrefix            = '~'
+	AttributePrefix      = '|'
+	PushPrefix           = '>'
 )
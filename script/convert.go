@@ -0,0 +1,136 @@
+package script
+
+import (
+	"strconv"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ToLua converts a reply into a Lua value:
+//   - integer -> number
+//   - bulk string -> string, null bulk string -> false
+//   - array -> table, null array -> false
+//   - simple string -> table with a single ok field
+//   - error -> table with a single err field
+//
+// RESP3 replies are converted the same way as their RESP2 downgrades, so maps
+// become flat tables, null becomes false and doubles become strings.
+func ToLua(L *lua.LState, data spec.Data) lua.LValue {
+	switch data.(type) {
+	case *spec.IntegerData:
+		i, _ := spec.Value[int64](data)
+		return lua.LNumber(i)
+
+	case *spec.BulkStringData:
+		bulkStringData := data.(*spec.BulkStringData)
+		if bulkStringData.IsNull() {
+			return lua.LFalse
+		}
+
+		return lua.LString(bulkStringData.S)
+
+	case *spec.SimpleStringData:
+		str, _ := spec.Value[string](data)
+		return singleFieldTable(L, "ok", str)
+
+	case *spec.SimpleErrorData:
+		err, _ := spec.Value[error](data)
+		return singleFieldTable(L, "err", replyErrorOf(err).Error())
+
+	case *spec.ArrayData:
+		arrayData := data.(*spec.ArrayData)
+		if arrayData.IsNull() {
+			return lua.LFalse
+		}
+
+		t := L.CreateTable(len(arrayData.A), 0)
+		for _, elem := range arrayData.A {
+			t.Append(ToLua(L, elem))
+		}
+		return t
+
+	case *spec.NullData:
+		return lua.LFalse
+
+	case *spec.BooleanData:
+		b, _ := spec.Value[bool](data)
+		if b {
+			return lua.LNumber(1)
+		}
+		return lua.LNumber(0)
+
+	case *spec.DoubleData:
+		f, _ := spec.Value[float64](data)
+		return lua.LString(strconv.FormatFloat(f, 'g', -1, 64))
+
+	case *spec.BigNumberData:
+		bigNumberData := data.(*spec.BigNumberData)
+		return lua.LString(bigNumberData.N.String())
+
+	case *spec.BulkErrorData:
+		msg, _ := spec.Value[string](data)
+		return singleFieldTable(L, "err", msg)
+
+	case *spec.VerbatimStringData:
+		str, _ := spec.Value[string](data)
+		return lua.LString(str)
+
+	case *spec.MultiData, *spec.MapData, *spec.SetData, *spec.PushData:
+		multi, _ := spec.Value[[]spec.Data](data)
+
+		t := L.CreateTable(len(multi), 0)
+		for _, elem := range multi {
+			t.Append(ToLua(L, elem))
+		}
+		return t
+
+	default:
+		return lua.LNil
+	}
+}
+
+// FromLua converts a Lua value into a reply:
+//   - number -> integer (truncated)
+//   - string -> bulk string
+//   - true -> integer 1, false and nil -> null bulk string
+//   - table with a single ok field -> simple string
+//   - table with a single err field -> error
+//   - other tables -> array, up to the first nil
+func FromLua(lv lua.LValue) spec.Data {
+	switch v := lv.(type) {
+	case lua.LNumber:
+		return spec.IntegerOf(int64(v))
+
+	case lua.LString:
+		return spec.BulkStringOf(string(v))
+
+	case lua.LBool:
+		if v {
+			return spec.IntegerOf(1)
+		}
+		return spec.NullBulkString()
+
+	case *lua.LTable:
+		if msg, isString := v.RawGetString("err").(lua.LString); isString {
+			return spec.SimpleErrorOf(replyErrorFromString(string(msg)))
+		}
+
+		if status, isString := v.RawGetString("ok").(lua.LString); isString {
+			return spec.SimpleStringOf(string(status))
+		}
+
+		elems := make([]spec.Data, 0, v.Len())
+		for i := 1; ; i++ {
+			elem := v.RawGetInt(i)
+			if elem == lua.LNil {
+				break
+			}
+			elems = append(elems, FromLua(elem))
+		}
+		return spec.ArrayOf(elems...)
+
+	default:
+		return spec.NullBulkString()
+	}
+}
@@ -0,0 +1,75 @@
+package script
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc64"
+	"io"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// payload layout of FUNCTION DUMP:
+//
+//	magic | version | count(uvarint) | { len(uvarint) | code }... | crc64(8 bytes, little endian)
+const (
+	dumpMagic   = "RFN"
+	dumpVersion = 1
+)
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// Dump serializes the code of the libraries, to be restored with Restore.
+func Dump(libs []*Library) []byte {
+	var bb bytes.Buffer
+
+	bb.WriteString(dumpMagic)
+	bb.WriteByte(dumpVersion)
+	bb.Write(binary.AppendUvarint(nil, uint64(len(libs))))
+	for _, lib := range libs {
+		bb.Write(binary.AppendUvarint(nil, uint64(len(lib.Code))))
+		bb.WriteString(lib.Code)
+	}
+
+	return binary.LittleEndian.AppendUint64(bb.Bytes(), crc64.Checksum(bb.Bytes(), crc64Table))
+}
+
+// Restore deserializes the code of the libraries dumped with Dump.
+func Restore(payload []byte) ([]string, error) {
+	errInvalid := spec.ErrorOf("ERR", "payload version or checksum are wrong")
+
+	if len(payload) < len(dumpMagic)+1+8 {
+		return nil, errInvalid
+	}
+
+	body, checksum := payload[:len(payload)-8], payload[len(payload)-8:]
+	if crc64.Checksum(body, crc64Table) != binary.LittleEndian.Uint64(checksum) {
+		return nil, errInvalid
+	}
+
+	if string(body[:len(dumpMagic)]) != dumpMagic || body[len(dumpMagic)] != dumpVersion {
+		return nil, errInvalid
+	}
+
+	r := bytes.NewReader(body[len(dumpMagic)+1:])
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errInvalid
+	}
+
+	codes := make([]string, 0, count)
+	for range count {
+		l, err := binary.ReadUvarint(r)
+		if err != nil || l > uint64(r.Len()) {
+			return nil, errInvalid
+		}
+
+		code := make([]byte, l)
+		if _, err := io.ReadFull(r, code); err != nil {
+			return nil, errInvalid
+		}
+		codes = append(codes, string(code))
+	}
+
+	return codes, nil
+}
@@ -0,0 +1,322 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	Engine = "LUA"
+
+	// loading a library only registers functions, so it must not take long
+	libraryLoadTimeout = 500 * time.Millisecond
+)
+
+const (
+	FlagNoWrites           = "no-writes"
+	FlagAllowOOM           = "allow-oom"
+	FlagAllowStale         = "allow-stale"
+	FlagNoCluster          = "no-cluster"
+	FlagAllowCrossSlotKeys = "allow-cross-slot-keys"
+)
+
+var functionFlags = []string{FlagNoWrites, FlagAllowOOM, FlagAllowStale, FlagNoCluster, FlagAllowCrossSlotKeys}
+
+// Library is a set of functions loaded from code starting with a "#!lua name=<library>" header.
+// The Lua state of the library lives as long as the library, as functions are closures in it.
+type Library struct {
+	Name      string
+	Code      string
+	Functions []*Function
+
+	L       *lua.LState
+	call    Caller // caller of the function being run
+	loading bool
+}
+
+type Function struct {
+	Name        string
+	Description string
+	Flags       []string
+	Library     *Library
+
+	fn *lua.LFunction
+}
+
+// ReadOnly reports whether the function is declared with the no-writes flag.
+func (f *Function) ReadOnly() bool {
+	return slices.Contains(f.Flags, FlagNoWrites)
+}
+
+// AllowOOM reports whether the function is declared with the allow-oom flag, so it runs
+// even when the memory is over maxmemory.
+func (f *Function) AllowOOM() bool {
+	return slices.Contains(f.Flags, FlagAllowOOM)
+}
+
+// LoadLibrary runs the library code, which registers its functions with redis.register_function.
+func LoadLibrary(code string) (*Library, error) {
+	name, body, err := parseLibraryHeader(code)
+	if err != nil {
+		return nil, err
+	}
+
+	proto, err := compile(body, "@user_function")
+	if err != nil {
+		return nil, spec.ErrorOf("ERR", "Error compiling function: %s", err)
+	}
+
+	lib := &Library{
+		Name:    name,
+		Code:    code,
+		loading: true,
+	}
+	lib.L = newState(func(args []string) (spec.Data, error) {
+		if lib.call == nil {
+			return nil, spec.ErrorOf("ERR", "redis.call is not allowed while loading a library")
+		}
+		return lib.call(args)
+	})
+	lib.registerFunctionAPI()
+
+	ctx, cancel := context.WithTimeout(context.Background(), libraryLoadTimeout)
+	defer cancel()
+
+	lib.L.SetContext(ctx)
+	lib.L.Push(lib.L.NewFunctionFromProto(proto))
+	err = lib.L.PCall(0, 0, nil)
+	lib.L.RemoveContext()
+	lib.loading = false
+
+	if err != nil {
+		lib.Close()
+		if errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return nil, spec.ErrorOf("ERR", "FUNCTION LOAD timeout")
+		}
+		return nil, spec.ErrorOf("ERR", "Error registering functions: %s", err)
+	}
+
+	if len(lib.Functions) == 0 {
+		lib.Close()
+		return nil, spec.ErrorOf("ERR", "No functions registered")
+	}
+
+	return lib, nil
+}
+
+func (l *Library) Close() {
+	l.L.Close()
+}
+
+// Call calls the function with the keys and arguments, converting its return value into a reply.
+// The function is interrupted with an error once ctx is done.
+func (f *Function) Call(ctx context.Context, keys []string, args []string, call Caller) (spec.Data, error) {
+	L := f.Library.L
+
+	f.Library.call = call
+	L.SetContext(ctx)
+	defer func() {
+		L.RemoveContext()
+		f.Library.call = nil
+	}()
+
+	L.Push(f.fn)
+	L.Push(stringTable(L, keys))
+	L.Push(stringTable(L, args))
+	if err := L.PCall(2, 1, nil); err != nil {
+		return nil, runError(ctx, f.Name, err)
+	}
+
+	ret := L.Get(-1)
+	L.Pop(1)
+	return FromLua(ret), nil
+}
+
+func (l *Library) registerFunctionAPI() {
+	redis := l.L.GetGlobal("redis").(*lua.LTable)
+
+	redis.RawSetString("register_function", l.L.NewFunction(func(L *lua.LState) int {
+		if !l.loading {
+			L.RaiseError("redis.register_function can only be called on FUNCTION LOAD command")
+			return 0
+		}
+
+		f, err := l.functionOf(L)
+		if err != nil {
+			L.RaiseError("%s", err.Error())
+			return 0
+		}
+
+		for _, registered := range l.Functions {
+			if registered.Name == f.Name {
+				L.RaiseError("Function already exists in the library")
+				return 0
+			}
+		}
+
+		l.Functions = append(l.Functions, f)
+		return 0
+	}))
+}
+
+// functionOf reads the arguments of redis.register_function, either (name, callback)
+// or a table with function_name, callback, flags and description fields.
+func (l *Library) functionOf(L *lua.LState) (*Function, error) {
+	f := &Function{Library: l}
+
+	switch L.GetTop() {
+	case 1:
+		t, isTable := L.Get(1).(*lua.LTable)
+		if !isTable {
+			return nil, errors.New("calling redis.register_function with a single argument is only applicable to Lua table (representing named arguments).")
+		}
+
+		var err error
+		t.ForEach(func(k lua.LValue, v lua.LValue) {
+			if err != nil {
+				return
+			}
+
+			switch k.String() {
+			case "function_name":
+				name, isString := v.(lua.LString)
+				if !isString {
+					err = errors.New("function_name argument given to redis.register_function must be a string")
+					return
+				}
+				f.Name = string(name)
+
+			case "callback":
+				fn, isFunction := v.(*lua.LFunction)
+				if !isFunction {
+					err = errors.New("callback argument given to redis.register_function must be a function")
+					return
+				}
+				f.fn = fn
+
+			case "description":
+				description, isString := v.(lua.LString)
+				if !isString {
+					err = errors.New("description argument given to redis.register_function must be a string")
+					return
+				}
+				f.Description = string(description)
+
+			case "flags":
+				flags, isTable := v.(*lua.LTable)
+				if !isTable {
+					err = errors.New("flags argument to redis.register_function must be a table representing function flags")
+					return
+				}
+
+				f.Flags, err = parseFunctionFlags(flags)
+
+			default:
+				err = errors.New("unknown argument given to redis.register_function")
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+
+	case 2:
+		name, isString := L.Get(1).(lua.LString)
+		if !isString {
+			return nil, errors.New("first argument to redis.register_function must be a string")
+		}
+		f.Name = string(name)
+
+		fn, isFunction := L.Get(2).(*lua.LFunction)
+		if !isFunction {
+			return nil, errors.New("second argument to redis.register_function must be a function")
+		}
+		f.fn = fn
+
+	default:
+		return nil, errors.New("wrong number of arguments to redis.register_function")
+	}
+
+	if f.Name == "" {
+		return nil, errors.New("redis.register_function must get a function name argument")
+	}
+
+	if !validName(f.Name) {
+		return nil, errors.New("Function names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+
+	if f.fn == nil {
+		return nil, errors.New("redis.register_function must get a callback argument")
+	}
+
+	return f, nil
+}
+
+func parseFunctionFlags(t *lua.LTable) ([]string, error) {
+	flags := make([]string, 0, t.Len())
+	for i := 1; i <= t.Len(); i++ {
+		flag, isString := t.RawGetInt(i).(lua.LString)
+		if !isString || !slices.Contains(functionFlags, string(flag)) {
+			return nil, errors.New("unknown flag given")
+		}
+		flags = append(flags, string(flag))
+	}
+
+	return flags, nil
+}
+
+// parseLibraryHeader parses the "#!<engine> name=<library>" header of the library code,
+// returning the library name and the code with the header line blanked out.
+func parseLibraryHeader(code string) (string, string, error) {
+	header, body, _ := strings.Cut(code, "\n")
+	if !strings.HasPrefix(header, "#!") {
+		return "", "", spec.ErrorOf("ERR", "Missing library metadata")
+	}
+
+	fields := strings.Fields(strings.TrimSuffix(header[2:], "\r"))
+	if len(fields) == 0 || strings.ToUpper(fields[0]) != Engine {
+		engine := ""
+		if len(fields) > 0 {
+			engine = fields[0]
+		}
+		return "", "", spec.ErrorOf("ERR", "Engine '%s' not found", engine)
+	}
+
+	name := ""
+	for _, field := range fields[1:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found || key != "name" {
+			return "", "", spec.ErrorOf("ERR", "Invalid metadata value given: %s", field)
+		}
+		name = value
+	}
+
+	if name == "" {
+		return "", "", spec.ErrorOf("ERR", "Library name was not given")
+	}
+
+	if !validName(name) {
+		return "", "", spec.ErrorOf("ERR", "Library names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+
+	// keep the line numbers of the body for error messages
+	return name, "\n" + body, nil
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
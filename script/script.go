@@ -0,0 +1,243 @@
+package script
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Caller executes a command called from a script with redis.call or redis.pcall.
+// It is invoked from the goroutine running the script.
+type Caller func(args []string) (spec.Data, error)
+
+// Script is a compiled Lua script, identified by the SHA1 digest of its body.
+type Script struct {
+	SHA  string
+	Body string
+
+	proto *lua.FunctionProto
+}
+
+func SHA1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func Compile(body string) (*Script, error) {
+	sha := SHA1Hex(body)
+
+	proto, err := compile(body, "@user_script")
+	if err != nil {
+		return nil, spec.ErrorOf("ERR", "Error compiling script (new function): user_script: %s", err)
+	}
+
+	return &Script{
+		SHA:   sha,
+		Body:  body,
+		proto: proto,
+	}, nil
+}
+
+func compile(body string, name string) (*lua.FunctionProto, error) {
+	chunk, err := parse.Parse(strings.NewReader(body), name)
+	if err != nil {
+		return nil, err
+	}
+
+	return lua.Compile(chunk, name)
+}
+
+// Run runs the script with the KEYS and ARGV globals set, converting its return value into a reply.
+// The script is interrupted with an error once ctx is done.
+func Run(ctx context.Context, s *Script, keys []string, args []string, call Caller) (spec.Data, error) {
+	L := newState(call)
+	defer L.Close()
+
+	L.SetGlobal("KEYS", stringTable(L, keys))
+	L.SetGlobal("ARGV", stringTable(L, args))
+
+	L.SetContext(ctx)
+	L.Push(L.NewFunctionFromProto(s.proto))
+	if err := L.PCall(0, 1, nil); err != nil {
+		return nil, runError(ctx, "f_"+s.SHA, err)
+	}
+
+	return FromLua(L.Get(-1)), nil
+}
+
+// newState creates a sandboxed Lua state exposing the redis library.
+func newState(call Caller) *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+
+	// scripts must not access the filesystem
+	for _, name := range []string{"dofile", "loadfile", "module", "require"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	L.SetGlobal("redis", redisTable(L, call))
+	return L
+}
+
+func redisTable(L *lua.LState, call Caller) *lua.LTable {
+	redis := L.NewTable()
+
+	redis.RawSetString("call", L.NewFunction(func(L *lua.LState) int {
+		reply := callFromLua(L, call)
+		if t, isTable := reply.(*lua.LTable); isTable && t.RawGetString("err") != lua.LNil {
+			L.Error(t, 1)
+			return 0
+		}
+
+		L.Push(reply)
+		return 1
+	}))
+
+	redis.RawSetString("pcall", L.NewFunction(func(L *lua.LState) int {
+		L.Push(callFromLua(L, call))
+		return 1
+	}))
+
+	redis.RawSetString("error_reply", L.NewFunction(func(L *lua.LState) int {
+		L.Push(singleFieldTable(L, "err", L.CheckString(1)))
+		return 1
+	}))
+
+	redis.RawSetString("status_reply", L.NewFunction(func(L *lua.LState) int {
+		L.Push(singleFieldTable(L, "ok", L.CheckString(1)))
+		return 1
+	}))
+
+	redis.RawSetString("sha1hex", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString(SHA1Hex(L.CheckString(1))))
+		return 1
+	}))
+
+	for i, name := range []string{"LOG_DEBUG", "LOG_VERBOSE", "LOG_NOTICE", "LOG_WARNING"} {
+		redis.RawSetString(name, lua.LNumber(i))
+	}
+
+	redis.RawSetString("log", L.NewFunction(func(L *lua.LState) int {
+		level := L.CheckInt(1)
+
+		parts := make([]string, 0, L.GetTop()-1)
+		for i := 2; i <= L.GetTop(); i++ {
+			parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+		}
+		msg := strings.Join(parts, " ")
+
+		switch level {
+		case 0:
+			slog.Debug("script log", slog.String("message", msg))
+		case 1, 2:
+			slog.Info("script log", slog.String("message", msg))
+		default:
+			slog.Warn("script log", slog.String("message", msg))
+		}
+		return 0
+	}))
+
+	return redis
+}
+
+// callFromLua calls the command with the arguments on the Lua stack, returning the reply
+// converted into a Lua value. Errors are returned as error tables.
+func callFromLua(L *lua.LState, call Caller) lua.LValue {
+	if L.GetTop() == 0 {
+		return singleFieldTable(L, "err", "ERR Please specify at least one argument for this redis lib call")
+	}
+
+	args := make([]string, 0, L.GetTop())
+	for i := 1; i <= L.GetTop(); i++ {
+		switch arg := L.Get(i).(type) {
+		case lua.LString:
+			args = append(args, string(arg))
+		case lua.LNumber:
+			args = append(args, arg.String())
+		default:
+			return singleFieldTable(L, "err", "ERR Lua redis lib command arguments must be strings or integers")
+		}
+	}
+
+	reply, err := call(args)
+	if err != nil {
+		return singleFieldTable(L, "err", replyErrorOf(err).Error())
+	}
+
+	return ToLua(L, reply)
+}
+
+// runError converts an error raised from the script into the error replied to the client.
+func runError(ctx context.Context, fn string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) {
+		if t, isTable := apiErr.Object.(*lua.LTable); isTable {
+			if msg, isString := t.RawGetString("err").(lua.LString); isString {
+				return replyErrorFromString(string(msg))
+			}
+		}
+
+		return spec.ErrorOf("ERR", "Error running script (call to %s): %s", fn, apiErr.Object.String())
+	}
+
+	return spec.ErrorOf("ERR", "Error running script (call to %s): %s", fn, err)
+}
+
+func replyErrorOf(err error) *spec.ReplyError {
+	var replyErr *spec.ReplyError
+	if errors.As(err, &replyErr) {
+		return replyErr
+	}
+
+	return &spec.ReplyError{Code: "ERR", Msg: err.Error()}
+}
+
+// replyErrorFromString splits the leading error code from an error string, defaulting to "ERR".
+func replyErrorFromString(s string) *spec.ReplyError {
+	s = strings.TrimPrefix(s, "-")
+
+	code, msg, found := strings.Cut(s, " ")
+	if found && code != "" && strings.ToUpper(code) == code {
+		return &spec.ReplyError{Code: code, Msg: msg}
+	}
+
+	return &spec.ReplyError{Code: "ERR", Msg: s}
+}
+
+func stringTable(L *lua.LState, ss []string) *lua.LTable {
+	t := L.CreateTable(len(ss), 0)
+	for _, s := range ss {
+		t.Append(lua.LString(s))
+	}
+	return t
+}
+
+func singleFieldTable(L *lua.LState, field string, value string) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString(field, lua.LString(value))
+	return t
+}
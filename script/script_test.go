@@ -0,0 +1,147 @@
+package script
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/codecrafters-io/redis-starter-go/spec"
+)
+
+// run runs the script body with the caller replying reply, or err, to every command.
+func run(t *testing.T, body string, reply spec.Data, err error) (spec.Data, error) {
+	t.Helper()
+
+	s, compileErr := Compile(body)
+	if compileErr != nil {
+		t.Fatalf("Compile(%q) failed: %v", body, compileErr)
+	}
+
+	return Run(context.Background(), s, nil, nil, func(args []string) (spec.Data, error) {
+		return reply, err
+	})
+}
+
+func TestConvertReply(t *testing.T) {
+	tests := []struct {
+		reply   spec.Data
+		luaType string
+		want    spec.Data
+	}{
+		{reply: spec.IntegerOf(42), luaType: "number", want: spec.IntegerOf(42)},
+		{reply: spec.BulkStringOf("v"), luaType: "string", want: spec.BulkStringOf("v")},
+		{reply: spec.NullBulkString(), luaType: "boolean", want: spec.NullBulkString()},
+		{reply: spec.NullArray(), luaType: "boolean", want: spec.NullBulkString()},
+		{reply: spec.SimpleStringOf("OK"), luaType: "table", want: spec.SimpleStringOf("OK")},
+		{
+			reply:   spec.SimpleErrorOf(spec.ErrorOf("WRONGTYPE", "Operation against a key holding the wrong kind of value")),
+			luaType: "table",
+			want:    spec.SimpleErrorOf(spec.ErrorOf("WRONGTYPE", "Operation against a key holding the wrong kind of value")),
+		},
+		{
+			reply:   spec.ArrayOf(spec.IntegerOf(1), spec.BulkStringOf("a"), spec.ArrayOf(spec.SimpleStringOf("OK"))),
+			luaType: "table",
+			want:    spec.ArrayOf(spec.IntegerOf(1), spec.BulkStringOf("a"), spec.ArrayOf(spec.SimpleStringOf("OK"))),
+		},
+		// the array stops at the first nil, which a null bulk string is not
+		{
+			reply:   spec.ArrayOf(spec.IntegerOf(1), spec.NullBulkString(), spec.IntegerOf(2)),
+			luaType: "table",
+			want:    spec.ArrayOf(spec.IntegerOf(1), spec.NullBulkString(), spec.IntegerOf(2)),
+		},
+
+		// RESP3 replies are converted as their RESP2 downgrades
+		{reply: spec.Null(), luaType: "boolean", want: spec.NullBulkString()},
+		{reply: spec.BooleanOf(true), luaType: "number", want: spec.IntegerOf(1)},
+		{reply: spec.BooleanOf(false), luaType: "number", want: spec.IntegerOf(0)},
+		{reply: spec.DoubleOf(1.5), luaType: "string", want: spec.BulkStringOf("1.5")},
+		{reply: &spec.BigNumberData{N: big.NewInt(7)}, luaType: "string", want: spec.BulkStringOf("7")},
+		{reply: spec.BulkErrorOf("ERR bulk"), luaType: "table", want: spec.SimpleErrorOf(spec.ErrorOf("ERR", "bulk"))},
+		{reply: spec.VerbatimStringOf("txt", "text"), luaType: "string", want: spec.BulkStringOf("text")},
+		{
+			reply:   spec.MapOf(spec.BulkStringOf("k"), spec.IntegerOf(1)),
+			luaType: "table",
+			want:    spec.ArrayOf(spec.BulkStringOf("k"), spec.IntegerOf(1)),
+		},
+		{reply: spec.SetOf(spec.BulkStringOf("m")), luaType: "table", want: spec.ArrayOf(spec.BulkStringOf("m"))},
+	}
+
+	for _, tt := range tests {
+		got, err := run(t, "return type(redis.pcall('x'))", tt.reply, nil)
+		if err != nil || !reflect.DeepEqual(got, spec.BulkStringOf(tt.luaType)) {
+			t.Errorf("type of %#v = %v, %v, want %q", tt.reply, got, err, tt.luaType)
+		}
+
+		got, err = run(t, "return redis.pcall('x')", tt.reply, nil)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%#v converted back = %#v, %v, want %#v", tt.reply, got, err, tt.want)
+		}
+	}
+}
+
+func TestConvertLuaValue(t *testing.T) {
+	tests := []struct {
+		body string
+		want spec.Data
+	}{
+		{body: "return 3.7", want: spec.IntegerOf(3)},
+		{body: "return -3.7", want: spec.IntegerOf(-3)},
+		{body: "return 'v'", want: spec.BulkStringOf("v")},
+		{body: "return true", want: spec.IntegerOf(1)},
+		{body: "return false", want: spec.NullBulkString()},
+		{body: "return nil", want: spec.NullBulkString()},
+		{body: "return {ok = 'FINE'}", want: spec.SimpleStringOf("FINE")},
+		{body: "return {err = 'MYERR custom'}", want: spec.SimpleErrorOf(spec.ErrorOf("MYERR", "custom"))},
+		{body: "return {err = 'no code'}", want: spec.SimpleErrorOf(spec.ErrorOf("ERR", "no code"))},
+		{body: "return {1, 'a', {2}}", want: spec.ArrayOf(spec.IntegerOf(1), spec.BulkStringOf("a"), spec.ArrayOf(spec.IntegerOf(2)))},
+		{body: "return {1, nil, 2}", want: spec.ArrayOf(spec.IntegerOf(1))},
+		{body: "return {k = 'v'}", want: spec.ArrayOf([]spec.Data{}...)},
+	}
+
+	for _, tt := range tests {
+		got, err := run(t, tt.body, nil, nil)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Run(%q) = %#v, %v, want %#v", tt.body, got, err, tt.want)
+		}
+	}
+}
+
+func TestRedisLibErrors(t *testing.T) {
+	wrongType := spec.ErrorOf("WRONGTYPE", "Operation against a key holding the wrong kind of value")
+
+	tests := []struct {
+		body    string
+		reply   spec.Data
+		callErr error
+		want    spec.Data
+		wantErr string
+	}{
+		// pcall returns the error as a table, call raises it
+		{body: "return redis.pcall('x').err", callErr: wrongType, want: spec.BulkStringOf(wrongType.Error())},
+		{body: "return redis.pcall('x').err", reply: spec.SimpleErrorOf(wrongType), want: spec.BulkStringOf(wrongType.Error())},
+		{body: "return redis.pcall('x')", callErr: wrongType, want: spec.SimpleErrorOf(wrongType)},
+		{body: "redis.call('x') return 1", callErr: wrongType, wantErr: wrongType.Error()},
+		{body: "local ok, e = pcall(redis.call, 'x') return e.err", callErr: wrongType, want: spec.BulkStringOf(wrongType.Error())},
+		{body: "return redis.pcall().err", want: spec.BulkStringOf("ERR Please specify at least one argument for this redis lib call")},
+		{body: "return redis.pcall('x', {}).err", want: spec.BulkStringOf("ERR Lua redis lib command arguments must be strings or integers")},
+
+		{body: "return redis.error_reply('MYERR custom')", want: spec.SimpleErrorOf(spec.ErrorOf("MYERR", "custom"))},
+		{body: "return redis.error_reply('lower case')", want: spec.SimpleErrorOf(spec.ErrorOf("ERR", "lower case"))},
+		{body: "error(redis.error_reply('MYERR raised'))", wantErr: "MYERR raised"},
+		{body: "return redis.status_reply('FINE')", want: spec.SimpleStringOf("FINE")},
+		{body: "return redis.status_reply('FINE').ok", want: spec.BulkStringOf("FINE")},
+	}
+
+	for _, tt := range tests {
+		got, err := run(t, tt.body, tt.reply, tt.callErr)
+		switch {
+		case tt.wantErr != "":
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Run(%q) error = %v, want %q", tt.body, err, tt.wantErr)
+			}
+		case err != nil || !reflect.DeepEqual(got, tt.want):
+			t.Errorf("Run(%q) = %#v, %v, want %#v", tt.body, got, err, tt.want)
+		}
+	}
+}
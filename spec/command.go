@@ -29,3 +29,458 @@ type SetCommand struct {
 }
 
 func (e *SetCommand) command() {}
+
+type SubscribeCommand struct {
+	Channels []string
+}
+
+func (e *SubscribeCommand) command() {}
+
+type UnsubscribeCommand struct {
+	Channels []string // unsubscribe from all channels when empty
+}
+
+func (e *UnsubscribeCommand) command() {}
+
+type PSubscribeCommand struct {
+	Patterns []string
+}
+
+func (e *PSubscribeCommand) command() {}
+
+type PUnsubscribeCommand struct {
+	Patterns []string // unsubscribe from all patterns when empty
+}
+
+func (e *PUnsubscribeCommand) command() {}
+
+type PublishCommand struct {
+	Channel string
+	Message string
+}
+
+func (e *PublishCommand) command() {}
+
+type InfoCommand struct {
+	Sections []string // lower cased, empty for the default sections
+}
+
+func (e *InfoCommand) command() {}
+
+type ConfigGetCommand struct {
+	Patterns []string
+}
+
+func (e *ConfigGetCommand) command() {}
+
+type ConfigSetCommand struct {
+	Params [][2]string // parameter, value pairs
+}
+
+func (e *ConfigSetCommand) command() {}
+
+type ConfigRewriteCommand struct{}
+
+func (e *ConfigRewriteCommand) command() {}
+
+type ConfigResetStatCommand struct{}
+
+func (e *ConfigResetStatCommand) command() {}
+
+type ObjectIdleTimeCommand struct {
+	Key string
+}
+
+func (e *ObjectIdleTimeCommand) command() {}
+
+type ObjectFreqCommand struct {
+	Key string
+}
+
+func (e *ObjectFreqCommand) command() {}
+
+type ObjectEncodingCommand struct {
+	Key string
+}
+
+func (e *ObjectEncodingCommand) command() {}
+
+type ObjectRefCountCommand struct {
+	Key string
+}
+
+func (e *ObjectRefCountCommand) command() {}
+
+type MemoryUsageCommand struct {
+	Key     string
+	Samples int // nested values sampled to estimate the size, 0 for all of them
+}
+
+func (e *MemoryUsageCommand) command() {}
+
+type MemoryStatsCommand struct{}
+
+func (e *MemoryStatsCommand) command() {}
+
+type MemoryDoctorCommand struct{}
+
+func (e *MemoryDoctorCommand) command() {}
+
+type MemoryPurgeCommand struct{}
+
+func (e *MemoryPurgeCommand) command() {}
+
+type SlowlogGetCommand struct {
+	Count int // -1 for every entry
+}
+
+func (e *SlowlogGetCommand) command() {}
+
+type SlowlogLenCommand struct{}
+
+func (e *SlowlogLenCommand) command() {}
+
+type SlowlogResetCommand struct{}
+
+func (e *SlowlogResetCommand) command() {}
+
+type LatencyLatestCommand struct{}
+
+func (e *LatencyLatestCommand) command() {}
+
+type LatencyHistoryCommand struct {
+	Event string
+}
+
+func (e *LatencyHistoryCommand) command() {}
+
+type LatencyResetCommand struct {
+	Events []string // every event when empty
+}
+
+func (e *LatencyResetCommand) command() {}
+
+type LatencyGraphCommand struct {
+	Event string
+}
+
+func (e *LatencyGraphCommand) command() {}
+
+type LatencyDoctorCommand struct{}
+
+func (e *LatencyDoctorCommand) command() {}
+
+type LatencyHistogramCommand struct {
+	Commands []string // every command when empty
+}
+
+func (e *LatencyHistogramCommand) command() {}
+
+type AclSetUserCommand struct {
+	Username string
+	Rules    []string
+}
+
+func (e *AclSetUserCommand) command() {}
+
+type AclGetUserCommand struct {
+	Username string
+}
+
+func (e *AclGetUserCommand) command() {}
+
+type AclDelUserCommand struct {
+	Usernames []string
+}
+
+func (e *AclDelUserCommand) command() {}
+
+type AclListCommand struct{}
+
+func (e *AclListCommand) command() {}
+
+type AclUsersCommand struct{}
+
+func (e *AclUsersCommand) command() {}
+
+type AclWhoAmICommand struct{}
+
+func (e *AclWhoAmICommand) command() {}
+
+type AclCatCommand struct {
+	Category *string // the categories when nil, or the commands of the category
+}
+
+func (e *AclCatCommand) command() {}
+
+type AclLogCommand struct {
+	Count int
+	Reset bool // drops the entries instead of returning them
+}
+
+func (e *AclLogCommand) command() {}
+
+type AclDryRunCommand struct {
+	Username string
+	Args     []string // the command and its arguments
+}
+
+func (e *AclDryRunCommand) command() {}
+
+type AclSaveCommand struct{}
+
+func (e *AclSaveCommand) command() {}
+
+type AclLoadCommand struct{}
+
+func (e *AclLoadCommand) command() {}
+
+type MultiCommand struct{}
+
+func (e *MultiCommand) command() {}
+
+type MonitorCommand struct{}
+
+func (e *MonitorCommand) command() {}
+
+type ExecCommand struct{}
+
+func (e *ExecCommand) command() {}
+
+type DiscardCommand struct{}
+
+func (e *DiscardCommand) command() {}
+
+type WatchCommand struct {
+	Keys []string
+}
+
+func (e *WatchCommand) command() {}
+
+type UnwatchCommand struct{}
+
+func (e *UnwatchCommand) command() {}
+
+type FlushDBCommand struct{}
+
+func (e *FlushDBCommand) command() {}
+
+type EvalCommand struct {
+	Script string
+	Keys   []string
+	Args   []string
+}
+
+func (e *EvalCommand) command() {}
+
+type EvalShaCommand struct {
+	SHA  string
+	Keys []string
+	Args []string
+}
+
+func (e *EvalShaCommand) command() {}
+
+type ScriptLoadCommand struct {
+	Script string
+}
+
+func (e *ScriptLoadCommand) command() {}
+
+type ScriptExistsCommand struct {
+	SHAs []string
+}
+
+func (e *ScriptExistsCommand) command() {}
+
+type ScriptFlushCommand struct{}
+
+func (e *ScriptFlushCommand) command() {}
+
+type ScriptKillCommand struct{}
+
+func (e *ScriptKillCommand) command() {}
+
+type FunctionLoadCommand struct {
+	Code    string
+	Replace bool
+}
+
+func (e *FunctionLoadCommand) command() {}
+
+type FunctionListCommand struct {
+	LibraryPattern string // every library when empty
+	WithCode       bool
+}
+
+func (e *FunctionListCommand) command() {}
+
+type FunctionDeleteCommand struct {
+	Library string
+}
+
+func (e *FunctionDeleteCommand) command() {}
+
+type FunctionDumpCommand struct{}
+
+func (e *FunctionDumpCommand) command() {}
+
+type FunctionRestorePolicy string
+
+const (
+	FunctionRestoreAppend  FunctionRestorePolicy = "APPEND"
+	FunctionRestoreReplace FunctionRestorePolicy = "REPLACE"
+	FunctionRestoreFlush   FunctionRestorePolicy = "FLUSH"
+)
+
+type FunctionRestoreCommand struct {
+	Payload string
+	Policy  FunctionRestorePolicy
+}
+
+func (e *FunctionRestoreCommand) command() {}
+
+type FunctionFlushCommand struct{}
+
+func (e *FunctionFlushCommand) command() {}
+
+type FunctionKillCommand struct{}
+
+func (e *FunctionKillCommand) command() {}
+
+type FunctionStatsCommand struct{}
+
+func (e *FunctionStatsCommand) command() {}
+
+type FCallCommand struct {
+	Function string
+	Keys     []string
+	Args     []string
+	ReadOnly bool // FCALL_RO
+}
+
+func (e *FCallCommand) command() {}
+
+type AuthCommand struct {
+	Username *string // the default user when nil
+	Password string
+}
+
+func (e *AuthCommand) command() {}
+
+type HelloCommand struct {
+	Protocol *int // keep the current protocol when nil
+	Auth     *HelloAuth
+	SetName  *string
+}
+
+type HelloAuth struct {
+	Username string
+	Password string
+}
+
+func (e *HelloCommand) command() {}
+
+type ClientIDCommand struct{}
+
+func (c *ClientIDCommand) command() {}
+
+type ClientInfoCommand struct{}
+
+func (c *ClientInfoCommand) command() {}
+
+type ClientListCommand struct {
+	Type string   // empty for all types
+	IDs  []uint64 // empty for all clients
+}
+
+func (c *ClientListCommand) command() {}
+
+type ClientSetNameCommand struct {
+	Name string
+}
+
+func (c *ClientSetNameCommand) command() {}
+
+type ClientGetNameCommand struct{}
+
+func (c *ClientGetNameCommand) command() {}
+
+// ClientKillCommand kills the clients matching all the given filters. The old form
+// with a single address is distinguished by Legacy, which replies OK instead of the count.
+type ClientKillCommand struct {
+	ID     *uint64
+	Addr   string
+	LAddr  string
+	User   string
+	Type   string
+	MaxAge int64 // seconds, 0 for no filter
+	SkipMe bool
+	Legacy bool
+}
+
+func (c *ClientKillCommand) command() {}
+
+type ClientPauseCommand struct {
+	Timeout time.Duration
+	Write   bool // pause only the commands that may write, instead of all commands
+}
+
+func (c *ClientPauseCommand) command() {}
+
+type ClientUnpauseCommand struct{}
+
+func (c *ClientUnpauseCommand) command() {}
+
+type ClientNoEvictCommand struct {
+	On bool
+}
+
+func (c *ClientNoEvictCommand) command() {}
+
+type ClientNoTouchCommand struct {
+	On bool
+}
+
+func (c *ClientNoTouchCommand) command() {}
+
+type ClientReplyMode string
+
+const (
+	ClientReplyOn   ClientReplyMode = "ON"
+	ClientReplyOff  ClientReplyMode = "OFF"
+	ClientReplySkip ClientReplyMode = "SKIP"
+)
+
+type ClientReplyCommand struct {
+	Mode ClientReplyMode
+}
+
+func (c *ClientReplyCommand) command() {}
+
+// ClientTrackingCommand enables or disables server assisted client side caching.
+type ClientTrackingCommand struct {
+	On       bool
+	Redirect uint64 // 0 for no redirection
+	Prefixes []string
+	BCast    bool // invalidate keys by prefix instead of by the keys read
+	OptIn    bool // track only the keys read right after CLIENT CACHING yes
+	OptOut   bool // track the keys read unless CLIENT CACHING no is given before
+	NoLoop   bool // do not invalidate the keys modified by the client itself
+}
+
+func (c *ClientTrackingCommand) command() {}
+
+type ClientCachingCommand struct {
+	Yes bool
+}
+
+func (c *ClientCachingCommand) command() {}
+
+type ClientGetRedirCommand struct{}
+
+func (c *ClientGetRedirCommand) command() {}
+
+type ClientTrackingInfoCommand struct{}
+
+func (c *ClientTrackingInfoCommand) command() {}
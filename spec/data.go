@@ -5,8 +5,6 @@ import (
 	"reflect"
 )
 
-// TODO: add RESP3 specs when needed
-
 type Data interface {
 	data()
 
@@ -18,7 +16,7 @@ type Data interface {
 func Value[T any](data Data) (T, error) {
 	var zero T
 
-	assignType := reflect.TypeOf(zero)
+	assignType := reflect.TypeFor[T]()
 	if !data.Type().AssignableTo(assignType) {
 		return zero, fmt.Errorf("type %v cannot be assigned to %v", data, assignType)
 	}
@@ -43,6 +41,10 @@ type SimpleErrorData struct {
 	Err error
 }
 
+func SimpleErrorOf(err error) *SimpleErrorData {
+	return &SimpleErrorData{Err: err}
+}
+
 func (e *SimpleErrorData) data()              {}
 func (e *SimpleErrorData) Value() any         { return e.Err }
 func (e *SimpleErrorData) Type() reflect.Type { return reflect.TypeFor[error]() }
@@ -52,6 +54,10 @@ type IntegerData struct {
 	I int64
 }
 
+func IntegerOf(i int64) *IntegerData {
+	return &IntegerData{I: i}
+}
+
 func (i *IntegerData) data()              {}
 func (i *IntegerData) Value() any         { return i.I }
 func (i *IntegerData) Type() reflect.Type { return reflect.TypeFor[int64]() }
@@ -86,9 +92,44 @@ type ArrayData struct {
 	A   []Data
 }
 
+func ArrayOf(a ...Data) *ArrayData {
+	return &ArrayData{Len: len(a), A: a}
+}
+
+func NullArray() *ArrayData {
+	return &ArrayData{Len: -1}
+}
+
+func BulkStringArrayOf(ss ...string) *ArrayData {
+	a := make([]Data, 0, len(ss))
+	for _, s := range ss {
+		a = append(a, BulkStringOf(s))
+	}
+	return ArrayOf(a...)
+}
+
 func (a *ArrayData) data()              {}
 func (a *ArrayData) Value() any         { return a.A }
 func (a *ArrayData) Type() reflect.Type { return reflect.TypeFor[[]Data]() }
 func (a *ArrayData) Incomplete() bool {
 	return len(a.A) < a.Len
 }
+
+func (a *ArrayData) IsNull() bool {
+	return a.Len == -1
+}
+
+// MultiData is a sequence of replies written back to back for a single command,
+// e.g. SUBSCRIBE answers with one confirmation per channel.
+type MultiData struct {
+	D []Data
+}
+
+func MultiOf(d ...Data) *MultiData {
+	return &MultiData{D: d}
+}
+
+func (m *MultiData) data()              {}
+func (m *MultiData) Value() any         { return m.D }
+func (m *MultiData) Type() reflect.Type { return reflect.TypeFor[[]Data]() }
+func (m *MultiData) Incomplete() bool   { return false }
@@ -0,0 +1,18 @@
+package spec
+
+import "fmt"
+
+// ReplyError is an error replied to the client as is, prefixed with its error code
+// (e.g. "ERR", "WRONGTYPE", "EXECABORT").
+type ReplyError struct {
+	Code string
+	Msg  string
+}
+
+func (e *ReplyError) Error() string {
+	return e.Code + " " + e.Msg
+}
+
+func ErrorOf(code string, format string, args ...any) *ReplyError {
+	return &ReplyError{Code: code, Msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,222 @@
+package spec
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Categories are the ACL categories of commands, in the order Redis lists them.
+var Categories = []string{
+	"keyspace", "read", "write", "set", "sortedset", "list", "hash", "string", "bitmap",
+	"hyperloglog", "geo", "stream", "pubsub", "admin", "fast", "slow", "blocking", "dangerous",
+	"connection", "transaction", "scripting",
+}
+
+// KeyFlags are the accesses of a command to its keys.
+type KeyFlags int
+
+const (
+	KeyRead KeyFlags = 1 << iota
+	KeyWrite
+)
+
+// KeySpec locates keys in the arguments of a command, which include its name, as the key
+// specs of Redis.
+type KeySpec struct {
+	Flags   KeyFlags
+	Begin   int  // index of the first key, or of the number of keys with NumKeys
+	Last    int  // index of the last key, negative from the end; unused with NumKeys
+	NumKeys bool // the number of keys is at Begin, followed by the keys
+}
+
+// ChannelSpec locates the pub/sub channels in the arguments of a command.
+type ChannelSpec struct {
+	Begin   int  // index of the first channel
+	Last    int  // index of the last channel, negative from the end
+	Pattern bool // the channels are patterns, as with PSUBSCRIBE
+}
+
+// CommandSpec describes a command, or a subcommand of a container command, for the
+// access control of users.
+type CommandSpec struct {
+	Name       string   // e.g. "get" or "client|list"
+	Categories []string // ACL categories, e.g. "read" or "dangerous"
+	Keys       []KeySpec
+	Channels   *ChannelSpec
+}
+
+// Key is a key found in the arguments of a command.
+type Key struct {
+	Name  string
+	Flags KeyFlags
+}
+
+var (
+	readKey      = KeySpec{Flags: KeyRead, Begin: 1, Last: 1}
+	writeKey     = KeySpec{Flags: KeyWrite, Begin: 1, Last: 1}
+	scriptKeys   = KeySpec{Flags: KeyRead | KeyWrite, Begin: 2, NumKeys: true}
+	subcmdKey    = KeySpec{Flags: KeyRead, Begin: 2, Last: 2}
+	adminDanger  = []string{"admin", "slow", "dangerous"}
+	clientAdmin  = []string{"admin", "slow", "dangerous", "connection"}
+	clientConn   = []string{"slow", "connection"}
+	scriptWrite  = []string{"write", "slow", "scripting"}
+	scriptSlow   = []string{"slow", "scripting"}
+	keyspaceRead = []string{"keyspace", "read", "slow"}
+)
+
+// commandSpecs are the commands of the server, ordered by name.
+var commandSpecs = []*CommandSpec{
+	{Name: "acl|cat", Categories: []string{"slow"}},
+	{Name: "acl|deluser", Categories: adminDanger},
+	{Name: "acl|dryrun", Categories: adminDanger},
+	{Name: "acl|getuser", Categories: adminDanger},
+	{Name: "acl|list", Categories: adminDanger},
+	{Name: "acl|load", Categories: adminDanger},
+	{Name: "acl|log", Categories: adminDanger},
+	{Name: "acl|save", Categories: adminDanger},
+	{Name: "acl|setuser", Categories: adminDanger},
+	{Name: "acl|users", Categories: adminDanger},
+	{Name: "acl|whoami", Categories: []string{"slow"}},
+	{Name: "auth", Categories: []string{"fast", "connection"}},
+	{Name: "client|caching", Categories: clientConn},
+	{Name: "client|getname", Categories: clientConn},
+	{Name: "client|getredir", Categories: clientConn},
+	{Name: "client|id", Categories: clientConn},
+	{Name: "client|info", Categories: clientConn},
+	{Name: "client|kill", Categories: clientAdmin},
+	{Name: "client|list", Categories: clientAdmin},
+	{Name: "client|no-evict", Categories: clientAdmin},
+	{Name: "client|no-touch", Categories: clientConn},
+	{Name: "client|pause", Categories: clientAdmin},
+	{Name: "client|reply", Categories: clientConn},
+	{Name: "client|setname", Categories: clientConn},
+	{Name: "client|tracking", Categories: clientConn},
+	{Name: "client|trackinginfo", Categories: clientConn},
+	{Name: "client|unpause", Categories: clientAdmin},
+	{Name: "config|get", Categories: adminDanger},
+	{Name: "config|resetstat", Categories: adminDanger},
+	{Name: "config|rewrite", Categories: adminDanger},
+	{Name: "config|set", Categories: adminDanger},
+	{Name: "discard", Categories: []string{"fast", "transaction"}},
+	{Name: "echo", Categories: []string{"fast", "connection"}},
+	{Name: "eval", Categories: scriptSlow, Keys: []KeySpec{scriptKeys}},
+	{Name: "evalsha", Categories: scriptSlow, Keys: []KeySpec{scriptKeys}},
+	{Name: "exec", Categories: []string{"slow", "transaction"}},
+	{Name: "fcall", Categories: scriptSlow, Keys: []KeySpec{scriptKeys}},
+	{Name: "fcall_ro", Categories: scriptSlow, Keys: []KeySpec{{Flags: KeyRead, Begin: 2, NumKeys: true}}},
+	{Name: "flushdb", Categories: []string{"keyspace", "write", "slow", "dangerous"}},
+	{Name: "function|delete", Categories: scriptWrite},
+	{Name: "function|dump", Categories: scriptSlow},
+	{Name: "function|flush", Categories: scriptWrite},
+	{Name: "function|kill", Categories: scriptSlow},
+	{Name: "function|list", Categories: scriptSlow},
+	{Name: "function|load", Categories: scriptWrite},
+	{Name: "function|restore", Categories: scriptWrite},
+	{Name: "function|stats", Categories: scriptSlow},
+	{Name: "get", Categories: []string{"read", "string", "fast"}, Keys: []KeySpec{readKey}},
+	{Name: "hello", Categories: []string{"fast", "connection"}},
+	{Name: "info", Categories: []string{"slow", "dangerous"}},
+	{Name: "latency|doctor", Categories: adminDanger},
+	{Name: "latency|graph", Categories: adminDanger},
+	{Name: "latency|histogram", Categories: adminDanger},
+	{Name: "latency|history", Categories: adminDanger},
+	{Name: "latency|latest", Categories: adminDanger},
+	{Name: "latency|reset", Categories: adminDanger},
+	{Name: "memory|doctor", Categories: []string{"slow"}},
+	{Name: "memory|purge", Categories: []string{"slow"}},
+	{Name: "memory|stats", Categories: []string{"slow"}},
+	{Name: "memory|usage", Categories: []string{"read", "slow"}, Keys: []KeySpec{subcmdKey}},
+	{Name: "monitor", Categories: adminDanger},
+	{Name: "multi", Categories: []string{"fast", "transaction"}},
+	{Name: "object|encoding", Categories: keyspaceRead, Keys: []KeySpec{subcmdKey}},
+	{Name: "object|freq", Categories: keyspaceRead, Keys: []KeySpec{subcmdKey}},
+	{Name: "object|idletime", Categories: keyspaceRead, Keys: []KeySpec{subcmdKey}},
+	{Name: "object|refcount", Categories: keyspaceRead, Keys: []KeySpec{subcmdKey}},
+	{Name: "ping", Categories: []string{"fast", "connection"}},
+	{Name: "psubscribe", Categories: []string{"pubsub", "slow"}, Channels: &ChannelSpec{Begin: 1, Last: -1, Pattern: true}},
+	{Name: "publish", Categories: []string{"pubsub", "fast"}, Channels: &ChannelSpec{Begin: 1, Last: 1}},
+	{Name: "punsubscribe", Categories: []string{"pubsub", "slow"}},
+	{Name: "script|exists", Categories: scriptSlow},
+	{Name: "script|flush", Categories: scriptSlow},
+	{Name: "script|kill", Categories: scriptSlow},
+	{Name: "script|load", Categories: scriptSlow},
+	{Name: "set", Categories: []string{"write", "string", "slow"}, Keys: []KeySpec{writeKey}},
+	{Name: "slowlog|get", Categories: adminDanger},
+	{Name: "slowlog|len", Categories: adminDanger},
+	{Name: "slowlog|reset", Categories: adminDanger},
+	{Name: "subscribe", Categories: []string{"pubsub", "slow"}, Channels: &ChannelSpec{Begin: 1, Last: -1}},
+	{Name: "unsubscribe", Categories: []string{"pubsub", "slow"}},
+	{Name: "unwatch", Categories: []string{"fast", "transaction"}},
+	{Name: "watch", Categories: []string{"fast", "transaction"}, Keys: []KeySpec{{Flags: KeyRead, Begin: 1, Last: -1}}},
+}
+
+// CommandSpecs returns the specs of every command, ordered by name.
+func CommandSpecs() []*CommandSpec {
+	return commandSpecs
+}
+
+// LookupCommand returns the spec of the command by its full name, e.g. "get" or
+// "client|list", case-insensitively.
+func LookupCommand(name string) (*CommandSpec, bool) {
+	name = strings.ToLower(name)
+	i, found := slices.BinarySearchFunc(commandSpecs, name, func(c *CommandSpec, name string) int {
+		return strings.Compare(c.Name, name)
+	})
+	if !found {
+		return nil, false
+	}
+
+	return commandSpecs[i], true
+}
+
+// HasCategory reports whether the command belongs to the ACL category.
+func (c *CommandSpec) HasCategory(category string) bool {
+	return category == "all" || slices.Contains(c.Categories, category)
+}
+
+// KeysOf returns the keys in the arguments of the command.
+func (c *CommandSpec) KeysOf(args []string) []Key {
+	var keys []Key
+	for _, ks := range c.Keys {
+		first, last := ks.Begin, ks.Last
+		if ks.NumKeys {
+			if ks.Begin >= len(args) {
+				continue
+			}
+			n, err := strconv.Atoi(args[ks.Begin])
+			if err != nil || n <= 0 {
+				continue
+			}
+			first, last = ks.Begin+1, ks.Begin+n
+		} else if last < 0 {
+			last += len(args)
+		}
+
+		for i := first; i <= last && i < len(args); i++ {
+			keys = append(keys, Key{Name: args[i], Flags: ks.Flags})
+		}
+	}
+
+	return keys
+}
+
+// ChannelsOf returns the channels in the arguments of the command, or the patterns of
+// channels when they are patterns.
+func (c *CommandSpec) ChannelsOf(args []string) []string {
+	if c.Channels == nil {
+		return nil
+	}
+
+	last := c.Channels.Last
+	if last < 0 {
+		last += len(args)
+	}
+
+	var channels []string
+	for i := c.Channels.Begin; i <= last && i < len(args); i++ {
+		channels = append(channels, args[i])
+	}
+
+	return channels
+}
@@ -0,0 +1,158 @@
+package spec
+
+import (
+	"math/big"
+	"reflect"
+)
+
+// Protocol versions negotiated by HELLO.
+const (
+	RESP2 = 2
+	RESP3 = 3
+)
+
+type NullData struct{}
+
+func Null() *NullData {
+	return &NullData{}
+}
+
+func (n *NullData) data()              {}
+func (n *NullData) Value() any         { return nil }
+func (n *NullData) Type() reflect.Type { return reflect.TypeFor[any]() }
+func (n *NullData) Incomplete() bool   { return false }
+
+type BooleanData struct {
+	B bool
+}
+
+func BooleanOf(b bool) *BooleanData {
+	return &BooleanData{B: b}
+}
+
+func (b *BooleanData) data()              {}
+func (b *BooleanData) Value() any         { return b.B }
+func (b *BooleanData) Type() reflect.Type { return reflect.TypeFor[bool]() }
+func (b *BooleanData) Incomplete() bool   { return false }
+
+type DoubleData struct {
+	F float64
+}
+
+func DoubleOf(f float64) *DoubleData {
+	return &DoubleData{F: f}
+}
+
+func (d *DoubleData) data()              {}
+func (d *DoubleData) Value() any         { return d.F }
+func (d *DoubleData) Type() reflect.Type { return reflect.TypeFor[float64]() }
+func (d *DoubleData) Incomplete() bool   { return false }
+
+type BigNumberData struct {
+	N *big.Int
+}
+
+func (b *BigNumberData) data()              {}
+func (b *BigNumberData) Value() any         { return b.N }
+func (b *BigNumberData) Type() reflect.Type { return reflect.TypeFor[*big.Int]() }
+func (b *BigNumberData) Incomplete() bool   { return false }
+
+// BulkErrorData is an error whose message may contain any byte, including CRLF.
+type BulkErrorData struct {
+	Len int
+	S   string
+}
+
+func BulkErrorOf(s string) *BulkErrorData {
+	return &BulkErrorData{Len: len(s), S: s}
+}
+
+func (b *BulkErrorData) data()              {}
+func (b *BulkErrorData) Value() any         { return b.S }
+func (b *BulkErrorData) Type() reflect.Type { return reflect.TypeFor[string]() }
+func (b *BulkErrorData) Incomplete() bool {
+	return len(b.S) < b.Len
+}
+
+// VerbatimStringData is a bulk string with a three characters format (e.g. "txt", "mkd").
+// Len is the length of the whole payload, including the "fmt:" prefix.
+type VerbatimStringData struct {
+	Len    int
+	Format string
+	S      string
+}
+
+func VerbatimStringOf(format string, s string) *VerbatimStringData {
+	return &VerbatimStringData{Len: len(format) + 1 + len(s), Format: format, S: s}
+}
+
+func (v *VerbatimStringData) data()              {}
+func (v *VerbatimStringData) Value() any         { return v.S }
+func (v *VerbatimStringData) Type() reflect.Type { return reflect.TypeFor[string]() }
+func (v *VerbatimStringData) Incomplete() bool {
+	return v.Format == "" && v.Len > 0
+}
+
+// MapData holds Len key value pairs, flattened into A as key, value, key, value...
+type MapData struct {
+	Len int
+	A   []Data
+}
+
+func MapOf(kvs ...Data) *MapData {
+	return &MapData{Len: len(kvs) / 2, A: kvs}
+}
+
+func (m *MapData) data()              {}
+func (m *MapData) Value() any         { return m.A }
+func (m *MapData) Type() reflect.Type { return reflect.TypeFor[[]Data]() }
+func (m *MapData) Incomplete() bool {
+	return len(m.A) < 2*m.Len
+}
+
+type SetData struct {
+	Len int
+	A   []Data
+}
+
+func SetOf(a ...Data) *SetData {
+	return &SetData{Len: len(a), A: a}
+}
+
+func (s *SetData) data()              {}
+func (s *SetData) Value() any         { return s.A }
+func (s *SetData) Type() reflect.Type { return reflect.TypeFor[[]Data]() }
+func (s *SetData) Incomplete() bool {
+	return len(s.A) < s.Len
+}
+
+// AttributeData holds Len key value pairs of auxiliary information, flattened like MapData.
+// It is sent before the reply it describes and is never sent to RESP2 connections.
+type AttributeData struct {
+	Len int
+	A   []Data
+}
+
+func (a *AttributeData) data()              {}
+func (a *AttributeData) Value() any         { return a.A }
+func (a *AttributeData) Type() reflect.Type { return reflect.TypeFor[[]Data]() }
+func (a *AttributeData) Incomplete() bool {
+	return len(a.A) < 2*a.Len
+}
+
+// PushData is out of band data such as pub/sub messages, sent as arrays to RESP2 connections.
+type PushData struct {
+	Len int
+	A   []Data
+}
+
+func PushOf(a ...Data) *PushData {
+	return &PushData{Len: len(a), A: a}
+}
+
+func (p *PushData) data()              {}
+func (p *PushData) Value() any         { return p.A }
+func (p *PushData) Type() reflect.Type { return reflect.TypeFor[[]Data]() }
+func (p *PushData) Incomplete() bool {
+	return len(p.A) < p.Len
+}
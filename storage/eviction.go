@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EvictionPolicy selects the keys evicted when maxmemory is reached, like the
+// maxmemory-policy option of Redis.
+type EvictionPolicy int
+
+const (
+	NoEviction EvictionPolicy = iota
+	AllKeysLRU
+	VolatileLRU
+	AllKeysLFU
+	VolatileLFU
+	AllKeysRandom
+	VolatileRandom
+	VolatileTTL
+)
+
+var evictionPolicyNames = []string{
+	NoEviction:     "noeviction",
+	AllKeysLRU:     "allkeys-lru",
+	VolatileLRU:    "volatile-lru",
+	AllKeysLFU:     "allkeys-lfu",
+	VolatileLFU:    "volatile-lfu",
+	AllKeysRandom:  "allkeys-random",
+	VolatileRandom: "volatile-random",
+	VolatileTTL:    "volatile-ttl",
+}
+
+// ParseEvictionPolicy parses a policy name such as "allkeys-lru", case-insensitively.
+func ParseEvictionPolicy(s string) (EvictionPolicy, error) {
+	i := slices.Index(evictionPolicyNames, strings.ToLower(s))
+	if i < 0 {
+		return 0, fmt.Errorf("invalid maxmemory policy: %s", s)
+	}
+
+	return EvictionPolicy(i), nil
+}
+
+func (p EvictionPolicy) String() string {
+	return evictionPolicyNames[p]
+}
+
+// LFU reports whether the policy evicts the least frequently used keys.
+func (p EvictionPolicy) LFU() bool {
+	return p == AllKeysLFU || p == VolatileLFU
+}
+
+// volatile reports whether the policy only evicts keys with an expiration.
+func (p EvictionPolicy) volatile() bool {
+	return p == VolatileLRU || p == VolatileLFU || p == VolatileRandom || p == VolatileTTL
+}
+
+// ErrOutOfMemory is returned when the memory can't be freed under maxmemory.
+var ErrOutOfMemory = errors.New("command not allowed when used memory > 'maxmemory'")
+
+// Eviction configures how the memory is bounded.
+type Eviction struct {
+	MaxMemory    int64 // 0 for no limit
+	Policy       EvictionPolicy
+	Samples      int // keys sampled for each eviction
+	LFULogFactor int // the higher, the more accesses are needed to increment a counter
+	LFUDecayTime int // minutes for a counter to be decremented, 0 to never decay
+}
+
+const (
+	// the memory accounted for a key along with its value, and for its expiration
+	entryOverhead      = 64
+	expirationOverhead = 32
+
+	// the LRU clock has a resolution of a second and wraps after 24 bits, as in Redis
+	lruClockMax        = 1<<24 - 1
+	lruClockResolution = time.Second
+
+	lfuInitVal = 5 // the counter of new keys, so they are not evicted right away
+
+	evictionPoolSize = 16
+)
+
+func entrySize(key, value string) int64 {
+	return int64(len(key)+len(value)) + entryOverhead
+}
+
+// SetEviction sets the configuration of maxmemory. Keys are evicted by the following
+// calls of FreeMemory.
+func (s *InMemoryStorage) SetEviction(eviction Eviction) {
+	if eviction.Policy != s.eviction.Policy {
+		s.pool = s.pool[:0] // the idle scores are not comparable across policies
+	}
+
+	s.eviction = eviction
+}
+
+// FreeMemory evicts keys until the used memory is under maxmemory. It returns
+// ErrOutOfMemory when no key can be evicted, e.g. with noeviction or with a volatile
+// policy and no key with an expiration.
+func (s *InMemoryStorage) FreeMemory() error {
+	if s.eviction.MaxMemory == 0 || s.used <= s.eviction.MaxMemory {
+		return nil
+	}
+	if s.eviction.Policy == NoEviction {
+		return ErrOutOfMemory
+	}
+
+	for s.used > s.eviction.MaxMemory {
+		key, found := s.evictionCandidate()
+		if !found {
+			return ErrOutOfMemory
+		}
+
+		s.remove(key)
+		s.evicted++
+		s.notify(NotifyEvicted, "evicted", key)
+	}
+
+	return nil
+}
+
+// evictionCandidate returns the key to evict by the policy.
+func (s *InMemoryStorage) evictionCandidate() (string, bool) {
+	switch s.eviction.Policy {
+	case AllKeysRandom:
+		return s.keys.random(s.rand)
+
+	case VolatileRandom:
+		return s.volatileKeys.random(s.rand)
+
+	default:
+		return s.evictionPoolCandidate()
+	}
+}
+
+// evictionPoolCandidate samples keys into the pool of the best candidates, which is kept
+// across evictions to approximate the real LRU, LFU or TTL order with few samples.
+func (s *InMemoryStorage) evictionPoolCandidate() (string, bool) {
+	for {
+		keys := s.keys
+		if s.eviction.Policy.volatile() {
+			keys = s.volatileKeys
+		}
+		if keys.len() == 0 {
+			return "", false
+		}
+		s.samplePool(keys.sample(s.rand, s.eviction.Samples))
+
+		// the best candidate is the last one, skipping keys removed since they were sampled
+		for len(s.pool) > 0 {
+			candidate := s.pool[len(s.pool)-1]
+			s.pool = s.pool[:len(s.pool)-1]
+			if _, found := s.data[candidate.key]; found {
+				return candidate.key, true
+			}
+		}
+	}
+}
+
+// samplePool adds the sampled keys to the pool, which is sorted by ascending idle score
+// and keeps the keys with the highest scores.
+func (s *InMemoryStorage) samplePool(keys []string) {
+	now := time.Now()
+	for _, key := range keys {
+		if slices.ContainsFunc(s.pool, func(c evictionCandidate) bool { return c.key == key }) {
+			continue
+		}
+
+		idle := s.idleScore(key, now)
+		if len(s.pool) == evictionPoolSize && idle <= s.pool[0].idle {
+			continue
+		}
+
+		i, _ := slices.BinarySearchFunc(s.pool, idle, func(c evictionCandidate, idle uint64) int {
+			switch {
+			case c.idle < idle:
+				return -1
+			case c.idle > idle:
+				return 1
+			default:
+				return 0
+			}
+		})
+		s.pool = slices.Insert(s.pool, i, evictionCandidate{key: key, idle: idle})
+		if len(s.pool) > evictionPoolSize {
+			s.pool = s.pool[1:]
+		}
+	}
+}
+
+// idleScore returns the score of the key for the policy: the higher, the better to evict.
+func (s *InMemoryStorage) idleScore(key string, now time.Time) uint64 {
+	switch s.eviction.Policy {
+	case VolatileTTL:
+		return math.MaxUint64 - uint64(s.expirationMap[key].UnixMilli())
+	case AllKeysLFU, VolatileLFU:
+		return 255 - uint64(s.decayedFreq(s.data[key], now))
+	default:
+		return uint64(idleTime(s.data[key], now))
+	}
+}
+
+type evictionCandidate struct {
+	key  string
+	idle uint64
+}
+
+type evictionPool []evictionCandidate
+
+// keyIndex keeps the keys in a slice, so they can be picked at random as Redis does from
+// its dictionaries, which the iteration order of a map does not allow.
+type keyIndex struct {
+	keys []string
+	pos  map[string]int
+}
+
+func newKeyIndex() *keyIndex {
+	return &keyIndex{pos: make(map[string]int)}
+}
+
+func (ki *keyIndex) len() int {
+	return len(ki.keys)
+}
+
+func (ki *keyIndex) add(key string) {
+	if _, found := ki.pos[key]; found {
+		return
+	}
+
+	ki.pos[key] = len(ki.keys)
+	ki.keys = append(ki.keys, key)
+}
+
+// remove removes the key, moving the last key to its position.
+func (ki *keyIndex) remove(key string) {
+	i, found := ki.pos[key]
+	if !found {
+		return
+	}
+
+	last := ki.keys[len(ki.keys)-1]
+	ki.keys[i] = last
+	ki.pos[last] = i
+	ki.keys = ki.keys[:len(ki.keys)-1]
+	delete(ki.pos, key)
+}
+
+func (ki *keyIndex) random(r *rand.Rand) (string, bool) {
+	if len(ki.keys) == 0 {
+		return "", false
+	}
+
+	return ki.keys[r.IntN(len(ki.keys))], true
+}
+
+// sample returns n keys picked at random, which may be picked more than once, or every
+// key when there are no more than n.
+func (ki *keyIndex) sample(r *rand.Rand, n int) []string {
+	if len(ki.keys) <= n {
+		return slices.Clone(ki.keys)
+	}
+
+	keys := make([]string, 0, n)
+	for range n {
+		keys = append(keys, ki.keys[r.IntN(len(ki.keys))])
+	}
+	return keys
+}
+
+func (s *InMemoryStorage) newEntry(value string) *entry {
+	now := time.Now()
+	return &entry{
+		value: value,
+		lru:   lruClock(now),
+		lfu:   lfuInitVal,
+		ldt:   lfuClock(now),
+	}
+}
+
+// access updates the statistics of an accessed key, unless accesses do not touch keys.
+func (s *InMemoryStorage) access(e *entry) {
+	if s.noTouch {
+		return
+	}
+
+	now := time.Now()
+	e.lru = lruClock(now)
+	e.lfu = s.logIncr(s.decayedFreq(e, now))
+	e.ldt = lfuClock(now)
+}
+
+// logIncr increments the counter with a probability decreasing as it grows, so it
+// reaches 255 after about a million accesses with the default factor of 10.
+func (s *InMemoryStorage) logIncr(counter uint8) uint8 {
+	if counter == math.MaxUint8 {
+		return counter
+	}
+
+	base := max(float64(counter)-lfuInitVal, 0)
+	p := 1 / (base*float64(s.eviction.LFULogFactor) + 1)
+	if s.rand.Float64() < p {
+		counter++
+	}
+
+	return counter
+}
+
+// decayedFreq returns the counter of the entry decremented once for every decay time
+// elapsed since it was last decremented.
+func (s *InMemoryStorage) decayedFreq(e *entry, now time.Time) uint8 {
+	if s.eviction.LFUDecayTime == 0 {
+		return e.lfu
+	}
+
+	elapsed := int(lfuClock(now) - e.ldt) // minutes, wrapping like the clock
+	periods := elapsed / s.eviction.LFUDecayTime
+	if periods >= int(e.lfu) {
+		return 0
+	}
+
+	return e.lfu - uint8(periods)
+}
+
+func lruClock(now time.Time) uint32 {
+	return uint32(now.UnixNano()/int64(lruClockResolution)) & lruClockMax
+}
+
+// lfuClock returns the time in minutes wrapping after 16 bits.
+func lfuClock(now time.Time) uint16 {
+	return uint16(now.Unix() / 60)
+}
+
+// idleTime estimates the time since the entry was last accessed by the LRU clock.
+func idleTime(e *entry, now time.Time) time.Duration {
+	clock := lruClock(now)
+	if clock >= e.lru {
+		return time.Duration(clock-e.lru) * lruClockResolution
+	}
+
+	return time.Duration(clock+(lruClockMax-e.lru)) * lruClockResolution
+}
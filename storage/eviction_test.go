@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+)
+
+// testKey is a key set before eviction, with the statistics eviction relies on.
+type testKey struct {
+	name string
+	idle time.Duration // since the last access
+	freq uint8         // LFU counter
+	ttl  time.Duration // 0 for no expiration
+}
+
+// newTestStorage returns a storage with the keys, whose memory is one byte over maxmemory
+// so a single key is evicted.
+func newTestStorage(t *testing.T, policy EvictionPolicy, keys []testKey) *InMemoryStorage {
+	t.Helper()
+
+	s := NewInMemoryStorage()
+	s.rand = rand.New(rand.NewPCG(1, 2))
+
+	now := time.Now()
+	for _, k := range keys {
+		var expireAt *time.Time
+		if k.ttl != 0 {
+			at := now.Add(k.ttl)
+			expireAt = &at
+		}
+		if err := s.Set(k.name, "v", expireAt); err != nil {
+			t.Fatalf("Set(%q) failed: %v", k.name, err)
+		}
+
+		e := s.data[k.name]
+		e.lru = lruClock(now.Add(-k.idle))
+		e.lfu = k.freq
+	}
+
+	s.SetEviction(Eviction{
+		MaxMemory:    s.used - 1,
+		Policy:       policy,
+		Samples:      len(keys),
+		LFULogFactor: 10,
+	})
+	return s
+}
+
+// evict frees the memory and returns the evicted keys, sorted.
+func evict(s *InMemoryStorage) ([]string, error) {
+	before := slices.Collect(maps.Keys(s.data))
+
+	err := s.FreeMemory()
+
+	var evicted []string
+	for _, key := range before {
+		if _, found := s.data[key]; !found {
+			evicted = append(evicted, key)
+		}
+	}
+	slices.Sort(evicted)
+	return evicted, err
+}
+
+func TestEvictionPolicy(t *testing.T) {
+	keys := []testKey{
+		{name: "old", idle: time.Hour, freq: 50},
+		{name: "rare", idle: time.Minute, freq: 1},
+		{name: "volatile-old", idle: 30 * time.Minute, freq: 50, ttl: 2 * time.Hour},
+		{name: "volatile-rare", idle: time.Second, freq: 2, ttl: 3 * time.Hour},
+		{name: "volatile-soon", idle: time.Second, freq: 50, ttl: time.Hour},
+	}
+	persistent := []testKey{keys[0], keys[1]}
+
+	tests := []struct {
+		policy  EvictionPolicy
+		keys    []testKey
+		want    []string
+		wantErr error
+	}{
+		{policy: NoEviction, keys: keys, wantErr: ErrOutOfMemory},
+		{policy: AllKeysLRU, keys: keys, want: []string{"old"}},
+		{policy: VolatileLRU, keys: keys, want: []string{"volatile-old"}},
+		{policy: VolatileLRU, keys: persistent, wantErr: ErrOutOfMemory},
+		{policy: AllKeysLFU, keys: keys, want: []string{"rare"}},
+		{policy: VolatileLFU, keys: keys, want: []string{"volatile-rare"}},
+		{policy: VolatileLFU, keys: persistent, wantErr: ErrOutOfMemory},
+		{policy: VolatileTTL, keys: keys, want: []string{"volatile-soon"}},
+		{policy: VolatileTTL, keys: persistent, wantErr: ErrOutOfMemory},
+		{policy: VolatileRandom, keys: persistent, wantErr: ErrOutOfMemory},
+	}
+
+	for _, tt := range tests {
+		s := newTestStorage(t, tt.policy, tt.keys)
+		got, err := evict(s)
+		if !errors.Is(err, tt.wantErr) || !slices.Equal(got, tt.want) {
+			t.Errorf("%s evicted %q, %v, want %q, %v", tt.policy, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestEvictionRandom(t *testing.T) {
+	keys := []testKey{
+		{name: "a"},
+		{name: "b"},
+		{name: "c", ttl: time.Hour},
+		{name: "d", ttl: time.Hour},
+		{name: "e", ttl: time.Hour},
+	}
+
+	tests := []struct {
+		policy   EvictionPolicy
+		eligible []string
+	}{
+		{policy: AllKeysRandom, eligible: []string{"a", "b", "c", "d", "e"}},
+		{policy: VolatileRandom, eligible: []string{"c", "d", "e"}},
+	}
+
+	for _, tt := range tests {
+		// every eligible key is evicted at some point, and only them
+		seen := make(map[string]bool)
+		for range 100 {
+			s := newTestStorage(t, tt.policy, keys)
+			s.rand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+
+			got, err := evict(s)
+			if err != nil || len(got) != 1 || !slices.Contains(tt.eligible, got[0]) {
+				t.Fatalf("%s evicted %q, %v, want one of %q", tt.policy, got, err, tt.eligible)
+			}
+			seen[got[0]] = true
+		}
+		if len(seen) != len(tt.eligible) {
+			t.Errorf("%s evicted %v over 100 runs, want each of %q", tt.policy, seen, tt.eligible)
+		}
+
+		// the same seed evicts the same key
+		first, _ := evict(newTestStorage(t, tt.policy, keys))
+		second, _ := evict(newTestStorage(t, tt.policy, keys))
+		if !slices.Equal(first, second) {
+			t.Errorf("%s evicted %q then %q with the same seed", tt.policy, first, second)
+		}
+	}
+}
+
+// TestEvictionSampling checks that the keys sampled into the pool only depend on the
+// random source, so the same seed evicts the keys in the same order.
+func TestEvictionSampling(t *testing.T) {
+	var keys []testKey
+	for i := range 100 {
+		keys = append(keys, testKey{name: fmt.Sprintf("k%d", i), idle: time.Duration(i) * time.Minute})
+	}
+
+	evictAll := func() []string {
+		s := newTestStorage(t, AllKeysLRU, keys)
+		s.eviction.Samples = 3
+
+		var order []string
+		for len(s.data) > 0 {
+			key, found := s.evictionCandidate()
+			if !found {
+				t.Fatalf("no candidate left with %d keys", len(s.data))
+			}
+			order = append(order, key)
+			s.remove(key)
+		}
+		return order
+	}
+
+	first, second := evictAll(), evictAll()
+	if !slices.Equal(first, second) {
+		t.Errorf("evicted %q then %q with the same seed", first, second)
+	}
+	if got := slices.Compact(slices.Sorted(slices.Values(first))); len(got) != len(keys) {
+		t.Errorf("evicted %d distinct keys, want %d", len(got), len(keys))
+	}
+}
+
+func TestKeyIndex(t *testing.T) {
+	ki := newKeyIndex()
+	for _, key := range []string{"a", "b", "c", "a"} {
+		ki.add(key)
+	}
+	ki.remove("a")
+	ki.remove("x")
+	ki.add("d")
+
+	got := slices.Sorted(slices.Values(ki.keys))
+	if want := []string{"b", "c", "d"}; !slices.Equal(got, want) {
+		t.Errorf("keys = %q, want %q", got, want)
+	}
+	for i, key := range ki.keys {
+		if ki.pos[key] != i {
+			t.Errorf("pos[%q] = %d, want %d", key, ki.pos[key], i)
+		}
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	if got := ki.sample(r, 5); len(got) != 3 {
+		t.Errorf("sample(5) of 3 keys = %q, want them all", got)
+	}
+	if got := ki.sample(r, 2); len(got) != 2 {
+		t.Errorf("sample(2) = %q, want 2 keys", got)
+	}
+}
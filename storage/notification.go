@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NotifyFlag is a bit set of keyspace notification classes, configured with
+// the same flag characters as the notify-keyspace-events option of Redis.
+type NotifyFlag uint16
+
+const (
+	NotifyKeyspace NotifyFlag = 1 << iota // K
+	NotifyKeyevent                        // E
+	NotifyGeneric                         // g
+	NotifyString                          // $
+	NotifyList                            // l
+	NotifySet                             // s
+	NotifyHash                            // h
+	NotifyZset                            // z
+	NotifyExpired                         // x
+	NotifyEvicted                         // e
+	NotifyStream                          // t
+	NotifyKeyMiss                         // m
+	NotifyModule                          // d
+	NotifyNew                             // n
+
+	// NotifyAll is the class set selected by the 'A' alias.
+	NotifyAll = NotifyGeneric | NotifyString | NotifyList | NotifySet | NotifyHash |
+		NotifyZset | NotifyExpired | NotifyEvicted | NotifyStream | NotifyModule
+)
+
+var notifyFlagChars = []struct {
+	c    byte
+	flag NotifyFlag
+}{
+	{'g', NotifyGeneric},
+	{'$', NotifyString},
+	{'l', NotifyList},
+	{'s', NotifySet},
+	{'h', NotifyHash},
+	{'z', NotifyZset},
+	{'x', NotifyExpired},
+	{'e', NotifyEvicted},
+	{'t', NotifyStream},
+	{'d', NotifyModule},
+	{'K', NotifyKeyspace},
+	{'E', NotifyKeyevent},
+	{'m', NotifyKeyMiss},
+	{'n', NotifyNew},
+}
+
+// ParseNotifyFlags parses a flag string such as "KEA" or "Ex".
+func ParseNotifyFlags(s string) (NotifyFlag, error) {
+	var flags NotifyFlag
+	for i := 0; i < len(s); i++ {
+		if s[i] == 'A' {
+			flags |= NotifyAll
+			continue
+		}
+
+		found := false
+		for _, fc := range notifyFlagChars {
+			if fc.c == s[i] {
+				flags |= fc.flag
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid notify flag: %c", s[i])
+		}
+	}
+
+	return flags, nil
+}
+
+// String formats the flags back into their canonical flag string.
+func (f NotifyFlag) String() string {
+	var sb strings.Builder
+
+	rest := f
+	if f&NotifyAll == NotifyAll {
+		sb.WriteByte('A')
+		rest &^= NotifyAll
+	}
+
+	for _, fc := range notifyFlagChars {
+		if rest&fc.flag != 0 {
+			sb.WriteByte(fc.c)
+		}
+	}
+
+	return sb.String()
+}
+
+// KeyEvent describes a single mutation of a key in the storage.
+type KeyEvent struct {
+	Class NotifyFlag
+	Event string
+	Key   string
+}
+
+// KeyEventListener is called synchronously for every KeyEvent emitted by the storage.
+type KeyEventListener func(KeyEvent)
+
+// TouchListener is called synchronously with every key whose value is modified,
+// deleted, expired or flushed.
+type TouchListener func(key string)
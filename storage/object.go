@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	// strings up to this length are allocated along with their object in Redis
+	embstrMaxLen = 44
+
+	// integers below this value are shared by every key holding them
+	sharedIntegers = 10000
+)
+
+// Object is the internals of a key reported by OBJECT and MEMORY USAGE.
+type Object struct {
+	Encoding string
+	RefCount int64
+	Idle     time.Duration
+	Freq     uint8
+	Size     int64 // memory used by the key, its value and its expiration
+}
+
+// Object returns the internals of the key without touching it.
+func (s *InMemoryStorage) Object(key string) (Object, bool) {
+	e, found := s.data[key]
+	if !found {
+		return Object{}, false
+	}
+
+	expireAt, expires := s.expirationMap[key]
+	if expires && time.Now().After(expireAt) {
+		return Object{}, false
+	}
+
+	size := entrySize(key, e.value)
+	if expires {
+		size += expirationOverhead
+	}
+
+	now := time.Now()
+	encoding := stringEncoding(e.value)
+	refCount := int64(1)
+	if encoding == "int" && s.sharesIntegers() {
+		if n, _ := strconv.ParseInt(e.value, 10, 64); n >= 0 && n < sharedIntegers {
+			refCount = math.MaxInt32 // shared objects are never freed
+		}
+	}
+
+	return Object{
+		Encoding: encoding,
+		RefCount: refCount,
+		Idle:     idleTime(e, now),
+		Freq:     s.decayedFreq(e, now),
+		Size:     size,
+	}, true
+}
+
+// sharesIntegers reports whether small integers are shared, which they are not when
+// keys are evicted by their access statistics, as each key keeps its own.
+func (s *InMemoryStorage) sharesIntegers() bool {
+	if s.eviction.MaxMemory == 0 {
+		return true
+	}
+
+	switch s.eviction.Policy {
+	case AllKeysLRU, VolatileLRU, AllKeysLFU, VolatileLFU:
+		return false
+	default:
+		return true
+	}
+}
+
+// stringEncoding returns the encoding Redis uses for a string value: "int" for integers
+// which fit in 64 bits, "embstr" for short strings and "raw" for the others.
+func stringEncoding(value string) string {
+	if len(value) <= 20 {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(n, 10) == value {
+			return "int"
+		}
+	}
+
+	if len(value) <= embstrMaxLen {
+		return "embstr"
+	}
+
+	return "raw"
+}
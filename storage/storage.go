@@ -2,6 +2,7 @@ package storage
 
 import (
 	"log/slog"
+	"math/rand/v2"
 	"time"
 
 	"github.com/codecrafters-io/redis-starter-go/pkg"
@@ -11,6 +12,37 @@ type Storage interface {
 	Get(key string) (*string, error)
 	Set(key string, value string, expireAt *time.Time) error
 	ExpireAllUntil(time time.Time)
+	Flush()
+	FreeMemory() error
+	SetNoTouch(noTouch bool)
+	Object(key string) (Object, bool)
+	AddKeyEventListener(listener KeyEventListener)
+	AddTouchListener(listener TouchListener)
+	Stats() Stats
+	ResetStats()
+}
+
+// Stats are the counters of the keyspace reported by INFO.
+type Stats struct {
+	Keys    int
+	Expires int           // keys with an expiration
+	AvgTTL  time.Duration // of the keys with an expiration
+
+	KeyspaceHits   int64
+	KeyspaceMisses int64
+	ExpiredKeys    int64
+	EvictedKeys    int64
+	Dirty          int64 // changes since the server started
+	UsedMemory     int64 // accounted for maxmemory
+	Overhead       int64 // memory of the keyspace itself rather than the keys and values
+}
+
+// entry is a value with the access statistics of its key used for eviction.
+type entry struct {
+	value string
+	lru   uint32 // LRU clock of the last access
+	lfu   uint8  // logarithmic access counter
+	ldt   uint16 // minutes clock of the last decrement of the counter
 }
 
 type expirationEntry struct {
@@ -19,15 +51,37 @@ type expirationEntry struct {
 }
 
 type InMemoryStorage struct {
-	data           map[string]string
+	data           map[string]*entry
 	expirationMap  map[string]time.Time
 	expirationHeap *pkg.Heap[expirationEntry]
+
+	// the keys of data and expirationMap, to sample them for eviction
+	keys         *keyIndex
+	volatileKeys *keyIndex
+
+	eviction Eviction
+	pool     evictionPool
+	used     int64 // memory of the entries, as accounted for maxmemory
+	noTouch  bool  // accesses do not update the statistics of keys
+	rand     *rand.Rand
+
+	listeners      []KeyEventListener
+	touchListeners []TouchListener
+
+	hits    int64
+	misses  int64
+	expired int64
+	evicted int64
+	dirty   int64
 }
 
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
-		data:          make(map[string]string),
+		data:          make(map[string]*entry),
 		expirationMap: make(map[string]time.Time),
+		keys:          newKeyIndex(),
+		volatileKeys:  newKeyIndex(),
+		rand:          rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
 		expirationHeap: pkg.NewHeap(func(e1, e2 expirationEntry) bool {
 			if e1.expireAt.Before(e2.expireAt) {
 				return true
@@ -42,17 +96,74 @@ func NewInMemoryStorage() *InMemoryStorage {
 	}
 }
 
+func (s *InMemoryStorage) AddKeyEventListener(listener KeyEventListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *InMemoryStorage) AddTouchListener(listener TouchListener) {
+	s.touchListeners = append(s.touchListeners, listener)
+}
+
+// Stats returns the counters of the keyspace.
+func (s *InMemoryStorage) Stats() Stats {
+	var avgTTL time.Duration
+	if len(s.expirationMap) > 0 {
+		now := time.Now()
+		var sum time.Duration
+		for _, expireAt := range s.expirationMap {
+			sum += max(expireAt.Sub(now), 0)
+		}
+		avgTTL = sum / time.Duration(len(s.expirationMap))
+	}
+
+	return Stats{
+		Keys:           len(s.data),
+		Expires:        len(s.expirationMap),
+		AvgTTL:         avgTTL,
+		KeyspaceHits:   s.hits,
+		KeyspaceMisses: s.misses,
+		ExpiredKeys:    s.expired,
+		EvictedKeys:    s.evicted,
+		Dirty:          s.dirty,
+		UsedMemory:     s.used,
+		Overhead:       int64(len(s.data))*entryOverhead + int64(len(s.expirationMap))*expirationOverhead,
+	}
+}
+
+// ResetStats resets the counters of CONFIG RESETSTAT. The number of changes is kept, as
+// it is not a statistic but the state of the dataset.
+func (s *InMemoryStorage) ResetStats() {
+	s.hits = 0
+	s.misses = 0
+	s.expired = 0
+	s.evicted = 0
+}
+
+// SetNoTouch sets whether the following accesses leave the statistics of keys as they are,
+// for the commands of clients with CLIENT NO-TOUCH.
+func (s *InMemoryStorage) SetNoTouch(noTouch bool) {
+	s.noTouch = noTouch
+}
+
 func (s *InMemoryStorage) Get(key string) (*string, error) {
-	value, found := s.data[key]
+	e, found := s.data[key]
 	if !found {
+		s.misses++
+		s.notify(NotifyKeyMiss, "keymiss", key)
 		return nil, nil
 	}
 
 	expireAt, found := s.expirationMap[key]
 	if found && time.Now().After(expireAt) {
+		s.expire(key)
+		s.misses++
+		s.notify(NotifyKeyMiss, "keymiss", key)
 		return nil, nil
 	}
 
+	s.hits++
+	s.access(e)
+	value := e.value
 	return &value, nil
 }
 
@@ -63,21 +174,33 @@ func (s *InMemoryStorage) Set(key string, value string, expireAt *time.Time) err
 			return nil
 		}
 
-		pastExpiredAt, found := s.expirationMap[key]
-		if !found || pastExpiredAt.After(*expireAt) {
-			s.expirationMap[key] = *expireAt
-		}
-
-		s.expirationHeap.Push(expirationEntry{
-			key:      key,
-			expireAt: *expireAt,
-		})
+		s.setExpiration(key, *expireAt)
+	} else {
+		s.clearExpiration(key)
+	}
 
+	e, existed := s.data[key]
+	if existed {
+		// the access statistics are kept, as the key is overwritten rather than created
+		s.used -= entrySize(key, e.value)
+		e.value = value
+		s.access(e)
 	} else {
-		delete(s.expirationMap, key)
+		e = s.newEntry(value)
+		s.data[key] = e
+		s.keys.add(key)
 	}
+	s.used += entrySize(key, value)
+	s.dirty++
+	s.touch(key)
 
-	s.data[key] = value
+	if !existed {
+		s.notify(NotifyNew, "new", key)
+	}
+	s.notify(NotifyString, "set", key)
+	if expireAt != nil {
+		s.notify(NotifyGeneric, "expire", key)
+	}
 	return nil
 }
 
@@ -89,18 +212,90 @@ func (s *InMemoryStorage) ExpireAllUntil(time time.Time) {
 		}
 
 		entry, _ = s.expirationHeap.Pop()
-		if latestExpiration, exists := s.expirationMap[entry.key]; exists && latestExpiration.After(time) {
+		// skip stale entries of keys that were overwritten or deleted after being pushed
+		if latestExpiration, exists := s.expirationMap[entry.key]; !exists || latestExpiration.After(time) {
 			continue
 		}
 
-		s.Delete(entry.key)
+		s.expire(entry.key)
 		slog.Info("expired key removed",
 			slog.String("key", entry.key),
 		)
 	}
 }
 
-func (s *InMemoryStorage) Delete(key string) {
+func (s *InMemoryStorage) Delete(key string) bool {
+	if _, found := s.data[key]; !found {
+		return false
+	}
+
+	s.remove(key)
+	s.notify(NotifyGeneric, "del", key)
+	return true
+}
+
+func (s *InMemoryStorage) Flush() {
+	for key := range s.data {
+		s.touch(key)
+	}
+	s.dirty += int64(len(s.data))
+
+	s.data = make(map[string]*entry)
+	s.expirationMap = make(map[string]time.Time)
+	s.keys = newKeyIndex()
+	s.volatileKeys = newKeyIndex()
+	s.expirationHeap.Clear()
+	s.pool = s.pool[:0]
+	s.used = 0
+}
+
+func (s *InMemoryStorage) remove(key string) {
+	if e, found := s.data[key]; found {
+		s.used -= entrySize(key, e.value)
+	}
 	delete(s.data, key)
-	delete(s.expirationMap, key)
+	s.keys.remove(key)
+	s.clearExpiration(key)
+	s.dirty++
+	s.touch(key)
+}
+
+func (s *InMemoryStorage) setExpiration(key string, expireAt time.Time) {
+	if _, found := s.expirationMap[key]; !found {
+		s.used += expirationOverhead
+		s.volatileKeys.add(key)
+	}
+
+	s.expirationMap[key] = expireAt
+	s.expirationHeap.Push(expirationEntry{
+		key:      key,
+		expireAt: expireAt,
+	})
+}
+
+func (s *InMemoryStorage) clearExpiration(key string) {
+	if _, found := s.expirationMap[key]; found {
+		s.used -= expirationOverhead
+		delete(s.expirationMap, key)
+		s.volatileKeys.remove(key)
+	}
+}
+
+// expire removes the key once its expiration time passed.
+func (s *InMemoryStorage) expire(key string) {
+	s.remove(key)
+	s.expired++
+	s.notify(NotifyExpired, "expired", key)
+}
+
+func (s *InMemoryStorage) touch(key string) {
+	for _, l := range s.touchListeners {
+		l(key)
+	}
+}
+
+func (s *InMemoryStorage) notify(class NotifyFlag, event string, key string) {
+	for _, l := range s.listeners {
+		l(KeyEvent{Class: class, Event: event, Key: key})
+	}
 }